@@ -32,14 +32,23 @@ type ModuleCallback struct {
 }
 
 // Register 配置注册，用于注册模块配置，key 为模块名，cfgPtr 为模块配置结构体指针
+// key 已注册时 panic，运行时动态注册请使用 TryRegister
 func Register(key string, cfgPtr interface{}) {
+	if err := TryRegister(key, cfgPtr); err != nil {
+		panic(err.Error())
+	}
+}
+
+// TryRegister 与 Register 相同，key 已注册时返回错误而不是 panic
+func TryRegister(key string, cfgPtr interface{}) error {
 	modulesMutex.Lock()
 	defer modulesMutex.Unlock()
 
 	if _, exists := modules[key]; exists {
-		panic(fmt.Sprintf("config: module '%s' is already registered", key))
+		return fmt.Errorf("config: module '%s' is already registered", key)
 	}
 	modules[key] = cfgPtr
+	return nil
 }
 
 // RegisterCallback 注册模块初始化回调函数
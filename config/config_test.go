@@ -150,3 +150,13 @@ debug:
 	assert.Equal(t, ":9000", cfg.Port)
 	assert.Equal(t, "http://localhost:9000", cfg.Domain)
 }
+
+func TestTryRegister(t *testing.T) {
+	cfg := &struct{}{}
+	assert.NoError(t, TryRegister("try_register_test", cfg))
+	assert.EqualError(t, TryRegister("try_register_test", cfg), "config: module 'try_register_test' is already registered")
+	assert.Panics(t, func() { Register("try_register_test", cfg) })
+	modulesMutex.Lock()
+	delete(modules, "try_register_test")
+	modulesMutex.Unlock()
+}
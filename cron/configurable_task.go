@@ -3,6 +3,7 @@ package cron
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/jessewkun/gocommon/logger"
@@ -10,9 +11,11 @@ import (
 
 // ConfigurableTask 是一个包装器，它使任何 Task 变得可配置。
 // 它嵌入了原始的 Task 接口，并覆盖了与调度相关的方法，使其从配置中读取信息。
+// 配置可以在运行时通过 SetConfig 或 Manager 的热更新替换。
 type ConfigurableTask struct {
-	Task              // 嵌入基础任务接口
-	config TaskConfig // 持有从配置文件解析的调度信息
+	Task                // 嵌入基础任务接口
+	mu     sync.RWMutex // 保护 config 的并发读写
+	config TaskConfig   // 持有从配置文件解析的调度信息
 }
 
 // NewConfigurableTask 创建一个可配置的任务实例
@@ -23,34 +26,122 @@ func NewConfigurableTask(task Task, cfg TaskConfig) *ConfigurableTask {
 	}
 }
 
+// Config 返回当前配置
+func (ct *ConfigurableTask) Config() TaskConfig {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.config
+}
+
+// SetConfig 替换配置，Key 不可修改
+// 已注册到 Manager 的任务应通过 Manager.ApplyConfig 修改，以便重新调度
+func (ct *ConfigurableTask) SetConfig(cfg TaskConfig) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	cfg.Key = ct.config.Key
+	ct.config = cfg
+}
+
 // Key 覆盖原始任务的 Key 方法，返回配置中的任务名称
 func (ct *ConfigurableTask) Key() string {
-	return ct.config.Key
+	return ct.Config().Key
 }
 
 // Desc 覆盖原始任务的 Desc 方法，返回配置中的任务描述
 func (ct *ConfigurableTask) Desc() string {
-	return ct.config.Desc
+	return ct.Config().Desc
 }
 
-// Spec 覆盖原始任务的 Spec 方法，返回配置中的 CRON 表达式
+// Spec 覆盖原始任务的 Spec 方法，返回配置中的 CRON 表达式，配置了 Timezone 时加上 CRON_TZ 前缀
 func (ct *ConfigurableTask) Spec() string {
-	return ct.config.Spec
+	cfg := ct.Config()
+	return withTimezone(cfg.Spec, cfg.Timezone)
 }
 
 // Enabled 覆盖原始任务的 Enabled 方法，返回配置中的启用状态
 func (ct *ConfigurableTask) Enabled() bool {
-	return ct.config.Enabled
+	return ct.Config().Enabled
 }
 
 // Timeout 覆盖原始任务的 Timeout 方法，返回配置中的超时时间
 func (ct *ConfigurableTask) Timeout() time.Duration {
-	if ct.config.Timeout == "" {
-		return 0 // 默认不超时
+	return ct.parseDuration("timeout", ct.Config().Timeout)
+}
+
+// Jitter 返回配置中的随机延迟上限，未配置时使用原始任务的声明
+func (ct *ConfigurableTask) Jitter() time.Duration {
+	cfg := ct.Config()
+	if cfg.Jitter == "" {
+		return jitterOf(ct.Task)
+	}
+	return ct.parseDuration("jitter", cfg.Jitter)
+}
+
+// LockMode 返回配置中的分布式执行方式，未配置时使用原始任务的声明
+func (ct *ConfigurableTask) LockMode() LockMode {
+	if lock := ct.Config().Lock; lock != "" {
+		return LockMode(lock)
+	}
+	return lockModeOf(ct.Task)
+}
+
+// OverlapPolicy 返回配置中的重叠执行策略，未配置时使用原始任务的声明
+func (ct *ConfigurableTask) OverlapPolicy() OverlapPolicy {
+	cfg := ct.Config()
+	if cfg.Overlap == "" {
+		return overlapPolicyOf(ct.Task)
+	}
+	return OverlapPolicy{
+		Mode:          OverlapMode(cfg.Overlap),
+		MaxConcurrent: cfg.MaxConcurrent,
+	}
+}
+
+// RetryPolicy 返回配置中的重试策略，未配置时使用原始任务的声明
+func (ct *ConfigurableTask) RetryPolicy() RetryPolicy {
+	cfg := ct.Config()
+	if cfg.Retries == 0 {
+		return retryPolicyOf(ct.Task)
+	}
+	return RetryPolicy{
+		MaxRetries: cfg.Retries,
+		Backoff:    ct.parseDuration("retry_backoff", cfg.RetryBackoff),
+		MaxBackoff: ct.parseDuration("retry_max_backoff", cfg.RetryMaxBackoff),
+	}
+}
+
+// AlarmPolicy 返回配置中的报警策略，未配置时使用原始任务的声明
+func (ct *ConfigurableTask) AlarmPolicy() AlarmPolicy {
+	cfg := ct.Config()
+	if cfg.AlarmThreshold == 0 && cfg.MissedRunsAlarm == 0 {
+		return alarmPolicyOf(ct.Task)
+	}
+	return AlarmPolicy{
+		FailureThreshold: cfg.AlarmThreshold,
+		MissedRuns:       cfg.MissedRunsAlarm,
+	}
+}
+
+// Dependencies 返回配置中的上游依赖，未配置（nil）时使用原始任务的声明，配置为空列表表示没有依赖
+func (ct *ConfigurableTask) Dependencies() Dependencies {
+	cfg := ct.Config()
+	if cfg.DependsOn == nil {
+		return dependenciesOf(ct.Task)
+	}
+	return Dependencies{
+		Upstreams: cfg.DependsOn,
+		Window:    ct.parseDuration("depends_window", cfg.DependsWindow),
+	}
+}
+
+// parseDuration 解析配置中的时长，为空或无效时返回 0
+func (ct *ConfigurableTask) parseDuration(field, value string) time.Duration {
+	if value == "" {
+		return 0
 	}
-	d, err := time.ParseDuration(ct.config.Timeout)
+	d, err := time.ParseDuration(value)
 	if err != nil {
-		logger.Error(context.Background(), "CRON", fmt.Errorf("invalid timeout duration '%s' for task %s: %v. defaulting to 0", ct.config.Timeout, ct.Key(), err))
+		logger.Error(context.Background(), "CRON", fmt.Errorf("invalid %s duration '%s' for task %s: %v. defaulting to 0", field, value, ct.Key(), err))
 		return 0
 	}
 	return d
@@ -0,0 +1,274 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jessewkun/gocommon/constant"
+	"github.com/jessewkun/gocommon/logger"
+	"github.com/jessewkun/gocommon/safego"
+	"github.com/robfig/cron/v3"
+)
+
+// Dependencies 任务的上游依赖
+type Dependencies struct {
+	Upstreams []string // 上游任务，全部成功后触发本任务，声明了上游的任务不再按 Spec 调度
+	// Window 上游成功的有效期，超过该时长的成功不计入
+	// 0 表示上游需在同一个调度窗口内成功：只计入上游最近一次调度时刻之后的成功，见 upstreamSince
+	Window time.Duration
+}
+
+// DependentTask 可选接口，任务实现后可声明上游依赖
+type DependentTask interface {
+	Dependencies() Dependencies
+}
+
+func dependenciesOf(task Task) Dependencies {
+	if t, ok := task.(DependentTask); ok {
+		return t.Dependencies()
+	}
+	return Dependencies{}
+}
+
+// dependencyGraph 返回任务 -> 上游任务列表，调用方需持有 m.mu
+func (m *Manager) dependencyGraph() map[string][]string {
+	graph := make(map[string][]string, len(m.tasks))
+	for name, task := range m.tasks {
+		graph[name] = dependenciesOf(task).Upstreams
+	}
+	return graph
+}
+
+// checkDependencies 检查上游任务是否都已注册，并通过拓扑排序检测循环依赖
+func checkDependencies(graph map[string][]string) error {
+	downstreams := make(map[string][]string) // task -> [tasks that depend on it]
+	inDegree := make(map[string]int, len(graph))
+	for name := range graph {
+		inDegree[name] = 0
+	}
+	for name, upstreams := range graph {
+		for _, up := range upstreams {
+			if _, ok := graph[up]; !ok {
+				return fmt.Errorf("cron: task %s depends on unregistered task %s", name, up)
+			}
+			if up == name {
+				return fmt.Errorf("cron: task %s depends on itself", name)
+			}
+			downstreams[up] = append(downstreams[up], name)
+			inDegree[name]++
+		}
+	}
+
+	var queue []string
+	for name, degree := range inDegree {
+		if degree == 0 {
+			queue = append(queue, name)
+		}
+	}
+	visited := 0
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, down := range downstreams[name] {
+			inDegree[down]--
+			if inDegree[down] == 0 {
+				queue = append(queue, down)
+			}
+		}
+	}
+
+	if visited != len(graph) {
+		cyclic := make([]string, 0, len(graph)-visited)
+		for name, degree := range inDegree {
+			if degree > 0 {
+				cyclic = append(cyclic, name)
+			}
+		}
+		sort.Strings(cyclic)
+		return fmt.Errorf("cron: circular dependency detected involving tasks: %v", cyclic)
+	}
+	return nil
+}
+
+// downstreamsOf 返回直接依赖 name 的任务，调用方需持有 m.mu
+func (m *Manager) downstreamsOf(name string) []string {
+	var list []string
+	for down, task := range m.tasks {
+		for _, up := range dependenciesOf(task).Upstreams {
+			if up == name {
+				list = append(list, down)
+				break
+			}
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+// checkDistributedDependencies 分布式模式下有上游的任务由完成最后一个上游的节点触发，只执行一次，
+// 因此只支持 LockModeRun，且 Locker 需实现 UpstreamStore 以便在节点间共享上游的成功记录
+func (m *Manager) checkDistributedDependencies(name string, upstreams []string, mode LockMode) error {
+	if m.dist == nil || len(upstreams) == 0 {
+		return nil
+	}
+	if m.upstreams == nil {
+		return fmt.Errorf("cron: task %s depends on %v, but the distributed Locker does not implement UpstreamStore", name, upstreams)
+	}
+	if mode != LockModeRun {
+		return fmt.Errorf("cron: task %s depends on %v and must use lock mode %q in distributed mode, got %q", name, upstreams, LockModeRun, mode)
+	}
+	return nil
+}
+
+// upstreamKey 返回下游任务在 UpstreamStore 中的 key
+func (m *Manager) upstreamKey(name string) string {
+	if m.dist != nil {
+		return m.dist.upstreamKey(name)
+	}
+	return name
+}
+
+// resetUpstreams 清除下游任务记录的上游成功，不指定上游时清除全部
+func (m *Manager) resetUpstreams(ctx context.Context, name string, upstreams ...string) {
+	if m.upstreams == nil {
+		return
+	}
+	if err := m.upstreams.ResetUpstreams(ctx, m.upstreamKey(name), upstreams...); err != nil {
+		logger.Warn(ctx, "CRON", "Task %s failed to reset upstream records: %v", name, err)
+	}
+}
+
+// maxScheduleLookback prevSchedule 向前查找调度时刻的最大范围，覆盖只在闰年 2 月 29 日执行的表达式
+const maxScheduleLookback = 5 * 366 * 24 * time.Hour
+
+// upstreamSince 返回上游 name 的成功计入下游所需的最早时间，调用方需持有 m.mu
+// 按调度表达式执行的上游取 now 之前最近一次的调度时刻，在此之前的成功属于上一个调度窗口；
+// 由其他任务触发的上游取其各上游中最早的时间；无法确定时返回零值，不做限制
+func (m *Manager) upstreamSince(name string, now time.Time, visiting map[string]bool) time.Time {
+	task, ok := m.tasks[name]
+	if !ok || visiting[name] {
+		return time.Time{}
+	}
+	visiting[name] = true
+	if ups := dependenciesOf(task).Upstreams; len(ups) > 0 {
+		var since time.Time
+		for i, up := range ups {
+			if t := m.upstreamSince(up, now, visiting); i == 0 || t.Before(since) {
+				since = t
+			}
+		}
+		return since
+	}
+	sched, err := parseSpec(m.specOf(name, task), m.cfg.Location)
+	if err != nil {
+		return time.Time{}
+	}
+	return prevSchedule(sched, now.In(m.cfg.Location))
+}
+
+// prevSchedule 返回 sched 在 now 之前（含 now）最近一次的调度时刻，找不到时返回零值
+func prevSchedule(sched cron.Schedule, now time.Time) time.Time {
+	if once, ok := sched.(*onceSchedule); ok {
+		// 一次性任务的 Next 有状态，直接使用执行时间
+		if once.at.After(now) {
+			return time.Time{}
+		}
+		return once.at
+	}
+	// 逐步扩大查找范围，找到 now 之前的调度时刻后再向后逐个推进到最近的一次
+	for step := time.Second; step <= maxScheduleLookback; step *= 2 {
+		t := sched.Next(now.Add(-step))
+		if t.IsZero() || t.After(now) {
+			continue
+		}
+		for {
+			next := sched.Next(t)
+			if next.IsZero() || next.After(now) {
+				return t
+			}
+			t = next
+		}
+	}
+	return time.Time{}
+}
+
+// triggerDownstream 任务执行结束后记录上游结果，所有上游都成功的下游任务以相同的 trace ID 异步触发
+// 上游失败时不触发下游，并清除下游已记录的该上游的成功
+// 上游的成功记录保存在 UpstreamStore 中，分布式模式下由所有节点共享，只有完成最后一个上游的节点触发下游
+func (m *Manager) triggerDownstream(ctx context.Context, name string, err error) {
+	type downstream struct {
+		name  string
+		task  Task
+		since map[string]time.Time // 上游 -> 计入的成功需晚于该时间
+	}
+
+	m.stateMu.Lock()
+	stopped := m.stopped
+	m.stateMu.Unlock()
+	if stopped {
+		logger.Info(ctx, "CRON", "Downstream tasks of %s skipped: manager stopped", name)
+		return
+	}
+
+	now := time.Now()
+	var downs []downstream
+	m.mu.RLock()
+	for _, down := range m.downstreamsOf(name) {
+		task := m.tasks[down]
+		if !task.Enabled() || m.paused[down] {
+			logger.Info(ctx, "CRON", "Downstream task %s of %s skipped: disabled or paused", down, name)
+			continue
+		}
+		deps := dependenciesOf(task)
+		since := make(map[string]time.Time, len(deps.Upstreams))
+		for _, up := range deps.Upstreams {
+			if deps.Window > 0 {
+				since[up] = now.Add(-deps.Window)
+			} else {
+				since[up] = m.upstreamSince(up, now, make(map[string]bool))
+			}
+		}
+		downs = append(downs, downstream{name: down, task: task, since: since})
+	}
+	m.mu.RUnlock()
+	if len(downs) > 0 && m.upstreams == nil {
+		logger.ErrorWithMsg(ctx, "CRON", "Downstream tasks of %s skipped: the distributed Locker does not implement UpstreamStore", name)
+		return
+	}
+
+	// 上游可能因租约丢失被取消，记录结果不受其影响；访问共享存储时不持有 m.mu
+	storeCtx := context.WithoutCancel(ctx)
+	var triggers []downstream
+	for _, d := range downs {
+		if err != nil {
+			m.resetUpstreams(storeCtx, d.name, name)
+			logger.Info(ctx, "CRON", "Downstream task %s of %s skipped: upstream failed", d.name, name)
+			continue
+		}
+		ready, markErr := m.upstreams.MarkUpstream(storeCtx, m.upstreamKey(d.name), name, now, d.since)
+		if markErr != nil {
+			logger.ErrorWithMsg(ctx, "CRON", "Downstream task %s of %s skipped: failed to record upstream success: %v", d.name, name, markErr)
+			continue
+		}
+		if ready {
+			triggers = append(triggers, d)
+		}
+	}
+
+	for _, t := range triggers {
+		// 只继承 trace ID，不继承上游的取消信号与 fencing token
+		runCtx := context.Background()
+		if traceID := ctx.Value(constant.CtxTraceID); traceID != nil {
+			runCtx = context.WithValue(runCtx, constant.CtxTraceID, traceID)
+		}
+		logger.Info(runCtx, "CRON", "Triggering downstream task %s after %s succeeded", t.name, name)
+		go safego.SafeGo(runCtx, func() {
+			if runErr := m.dispatch(runCtx, t.name, t.task); runErr != nil {
+				logger.ErrorWithMsg(runCtx, "CRON", "Task %s failed: %v", t.name, runErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jessewkun/gocommon/logger"
+)
+
+// LockMode 分布式模式下任务的执行方式
+type LockMode string
+
+const (
+	LockModeRun    LockMode = "run"    // 每次调度抢占租约，只有抢到的节点执行，默认值
+	LockModeLeader LockMode = "leader" // 只在 leader 节点执行
+	LockModeNone   LockMode = "none"   // 不加锁，每个节点都执行
+)
+
+// DistributedTask 可选接口，任务实现后可声明分布式模式下的执行方式
+type DistributedTask interface {
+	LockMode() LockMode
+}
+
+// DistributedConfig 分布式模式配置
+type DistributedConfig struct {
+	Redis         string        // 存储租约的 redis 实例名，对应 redis 模块的配置 key
+	Locker        Locker        // 自定义租约实现，设置后忽略 Redis
+	KeyPrefix     string        // 租约 key 前缀，默认 cron:lock
+	NodeID        string        // 节点标识，默认 hostname-pid-随机串
+	LeaseTTL      time.Duration // 租约时长，节点宕机后最多经过该时长由其他节点接管，默认 30s
+	RenewInterval time.Duration // 任务执行期间与 leader 的续约间隔，默认 LeaseTTL/3
+	MinHold       time.Duration // 从调度时刻起租约至少保留的时长，需大于节点间的时钟偏差且小于最小调度间隔，默认 500ms
+}
+
+type fencingTokenKey struct{}
+
+// FencingToken 返回本次执行持有的 fencing token，该值随每次获取租约单调递增
+// 任务写入外部存储时可携带该值，由存储拒绝比已见过的 token 更小的写入，避免租约过期后旧节点的迟到写入
+func FencingToken(ctx context.Context) (int64, bool) {
+	token, ok := ctx.Value(fencingTokenKey{}).(int64)
+	return token, ok
+}
+
+// lockModeOf 返回任务的分布式执行方式
+func lockModeOf(task Task) LockMode {
+	if t, ok := task.(DistributedTask); ok && t.LockMode() != "" {
+		return t.LockMode()
+	}
+	return LockModeRun
+}
+
+// distributor 负责任务租约与 leader 选举
+type distributor struct {
+	cfg    DistributedConfig
+	locker Locker
+
+	mu          sync.RWMutex
+	leaderToken int64                        // 0 表示当前节点不是 leader
+	leaderRuns  map[int64]context.CancelFunc // 正在执行的 leader 模式任务，失去 leader 时取消
+	leaderSeq   int64
+	held        map[string]int // 当前节点持有租约、尚未释放的任务执行
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+func newDistributor(cfg DistributedConfig) (*distributor, error) {
+	if cfg.Locker == nil {
+		if cfg.Redis == "" {
+			return nil, fmt.Errorf("distributed mode requires Redis or Locker")
+		}
+		cfg.Locker = NewRedisLocker(cfg.Redis)
+	}
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = "cron:lock"
+	}
+	if cfg.NodeID == "" {
+		host, _ := os.Hostname()
+		cfg.NodeID = fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.New().String()[:8])
+	}
+	if cfg.LeaseTTL <= 0 {
+		cfg.LeaseTTL = 30 * time.Second
+	}
+	if cfg.RenewInterval <= 0 || cfg.RenewInterval >= cfg.LeaseTTL {
+		cfg.RenewInterval = cfg.LeaseTTL / 3
+	}
+	if cfg.MinHold <= 0 {
+		cfg.MinHold = 500 * time.Millisecond
+	}
+	return &distributor{cfg: cfg, locker: cfg.Locker, leaderRuns: make(map[int64]context.CancelFunc), held: make(map[string]int)}, nil
+}
+
+// lease 单次执行持有的任务租约
+type lease struct {
+	key   string
+	token int64
+	tick  time.Time
+}
+
+// acquire 为一次调度获取任务租约
+func (d *distributor) acquire(ctx context.Context, name string, tick time.Time) (*lease, error) {
+	key := lockKey(d.cfg.KeyPrefix, name)
+	token, err := d.locker.Acquire(ctx, key, d.cfg.NodeID, d.cfg.LeaseTTL)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	d.held[name]++
+	d.mu.Unlock()
+	return &lease{key: key, token: token, tick: tick}, nil
+}
+
+// holding 返回当前节点是否有该任务的执行持有租约
+func (d *distributor) holding(name string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.held[name] > 0
+}
+
+// keepAlive 在任务执行期间续约，租约丢失或超过 LeaseTTL 未能续约时调用 cancel 通知任务停止
+func (d *distributor) keepAlive(ctx context.Context, name string, l *lease, cancel context.CancelFunc) (stop func()) {
+	stopCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		ticker := time.NewTicker(d.cfg.RenewInterval)
+		defer ticker.Stop()
+		lastRenew := time.Now()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+			}
+			err := d.locker.Renew(ctx, l.key, d.cfg.NodeID, l.token, d.cfg.LeaseTTL)
+			if err == nil {
+				lastRenew = time.Now()
+				continue
+			}
+			if errors.Is(err, ErrLockLost) || time.Since(lastRenew) >= d.cfg.LeaseTTL {
+				logger.ErrorWithMsg(ctx, "CRON", "Task %s lost lease (token %d), cancelling: %v", name, l.token, err)
+				cancel()
+				return
+			}
+			logger.Warn(ctx, "CRON", "Task %s failed to renew lease: %v", name, err)
+		}
+	}()
+	return func() {
+		close(stopCh)
+		<-doneCh
+	}
+}
+
+// release 释放任务租约，租约保留到调度时刻 + MinHold
+func (d *distributor) release(ctx context.Context, name string, l *lease) {
+	d.mu.Lock()
+	if d.held[name]--; d.held[name] <= 0 {
+		delete(d.held, name)
+	}
+	d.mu.Unlock()
+	hold := time.Until(l.tick.Add(d.cfg.MinHold))
+	if err := d.locker.Release(ctx, l.key, d.cfg.NodeID, l.token, hold); err != nil {
+		logger.Warn(ctx, "CRON", "Task %s failed to release lease: %v", name, err)
+	}
+}
+
+// start 启动 leader 选举
+func (d *distributor) start(ctx context.Context) {
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+	d.campaign(ctx)
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(d.cfg.RenewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.stop:
+				d.resign(ctx)
+				return
+			case <-ticker.C:
+				d.campaign(ctx)
+			}
+		}
+	}()
+}
+
+// shutdown 停止 leader 选举并释放 leader 租约
+func (d *distributor) shutdown() {
+	if d.stop == nil {
+		return
+	}
+	close(d.stop)
+	<-d.done
+	d.stop = nil
+}
+
+// campaign leader 续约，不是 leader 时尝试成为 leader
+func (d *distributor) campaign(ctx context.Context) {
+	key := d.leaderKey()
+	if token := d.leader(); token > 0 {
+		err := d.locker.Renew(ctx, key, d.cfg.NodeID, token, d.cfg.LeaseTTL)
+		if err == nil {
+			return
+		}
+		// 无法确认租约是否仍然有效时主动退出，宁可短暂无 leader 也不出现两个 leader
+		d.setLeader(0)
+		logger.Warn(ctx, "CRON", "Node %s lost leadership: %v", d.cfg.NodeID, err)
+	}
+	token, err := d.locker.Acquire(ctx, key, d.cfg.NodeID, d.cfg.LeaseTTL)
+	if err != nil {
+		if !errors.Is(err, ErrLockHeld) {
+			logger.Warn(ctx, "CRON", "Node %s failed to campaign for leader: %v", d.cfg.NodeID, err)
+		}
+		return
+	}
+	d.setLeader(token)
+	logger.Info(ctx, "CRON", "Node %s became leader (token %d)", d.cfg.NodeID, token)
+}
+
+// resign 主动释放 leader 租约，便于其他节点尽快接管
+func (d *distributor) resign(ctx context.Context) {
+	token := d.leader()
+	if token == 0 {
+		return
+	}
+	d.setLeader(0)
+	if err := d.locker.Release(ctx, d.leaderKey(), d.cfg.NodeID, token, 0); err != nil {
+		logger.Warn(ctx, "CRON", "Node %s failed to release leadership: %v", d.cfg.NodeID, err)
+	}
+}
+
+// leaderKey 带 hash tag，保证 redis cluster 下与 fence key 位于同一 slot
+// 任务租约为 <prefix>:{<task>}，加上 :election 后缀避免与名为 leader 的任务冲突
+func (d *distributor) leaderKey() string {
+	return strings.TrimSuffix(d.cfg.KeyPrefix, ":") + ":{leader}:election"
+}
+
+// upstreamKey 返回下游任务在 UpstreamStore 中的 key，与任务租约使用相同的 hash tag
+func (d *distributor) upstreamKey(name string) string {
+	return lockKey(d.cfg.KeyPrefix, name) + ":upstreams"
+}
+
+// followLeader 登记一次 leader 模式的执行，当前节点不再是 token 对应的 leader 时调用 cancel
+// 返回的 stop 在执行结束后调用
+func (d *distributor) followLeader(token int64, cancel context.CancelFunc) (stop func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.leaderToken != token {
+		cancel()
+		return func() {}
+	}
+	d.leaderSeq++
+	id := d.leaderSeq
+	d.leaderRuns[id] = cancel
+	return func() {
+		d.mu.Lock()
+		delete(d.leaderRuns, id)
+		d.mu.Unlock()
+	}
+}
+
+func (d *distributor) leader() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.leaderToken
+}
+
+// setLeader 更新 leader 租约，失去 leader 时取消正在执行的 leader 模式任务
+func (d *distributor) setLeader(token int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.leaderToken != token {
+		for id, cancel := range d.leaderRuns {
+			cancel()
+			delete(d.leaderRuns, id)
+		}
+	}
+	d.leaderToken = token
+}
@@ -0,0 +1,433 @@
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/jessewkun/gocommon/constant"
+	"github.com/jessewkun/gocommon/db/mysql"
+	"github.com/jessewkun/gocommon/db/redis"
+	"github.com/jessewkun/gocommon/logger"
+)
+
+// RunRecord 任务单次执行记录
+type RunRecord struct {
+	Key       string        `json:"key"`
+	TraceID   string        `json:"trace_id"`
+	Node      string        `json:"node,omitempty"`
+	StartTime time.Time     `json:"start_time"`
+	Duration  time.Duration `json:"duration"`
+	Attempts  int           `json:"attempts"` // 执行次数，包含重试
+	Error     string        `json:"error,omitempty"`
+}
+
+// Success 本次执行是否成功
+func (r *RunRecord) Success() bool {
+	return r.Error == ""
+}
+
+// TaskStatus 任务当前状态
+type TaskStatus struct {
+	Key                 string        `json:"key"`
+	Spec                string        `json:"spec"`
+	Enabled             bool          `json:"enabled"`
+	Paused              bool          `json:"paused"`
+	DependsOn           []string      `json:"depends_on,omitempty"` // 上游任务，全部成功后触发
+	Running             int           `json:"running"`              // 正在执行的实例数
+	LastStart           time.Time     `json:"last_start"`           // 最近一次开始执行的时间
+	LastDuration        time.Duration `json:"last_duration"`        // 最近一次执行耗时
+	LastError           string        `json:"last_error,omitempty"` // 最近一次执行的错误，成功时为空
+	LastSuccess         time.Time     `json:"last_success"`         // 最近一次成功结束的时间
+	NextRun             time.Time     `json:"next_run"`             // 下一次调度时间，未调度时为零值
+	ConsecutiveFailures int           `json:"consecutive_failures"` // 连续失败次数，成功后清零
+	TotalRuns           int64         `json:"total_runs"`           // 自进程启动以来的执行次数
+	TotalFailures       int64         `json:"total_failures"`       // 自进程启动以来的失败次数
+	TotalSkipped        int64         `json:"total_skipped"`        // 因重叠执行策略被跳过的调度次数
+}
+
+// HistoryStore 执行记录的持久化存储，用于跨进程重启保留任务状态
+type HistoryStore interface {
+	// SaveRun 保存一次执行记录
+	SaveRun(ctx context.Context, run *RunRecord) error
+	// ListRuns 返回任务最近的执行记录，按开始时间倒序
+	ListRuns(ctx context.Context, key string, limit int) ([]*RunRecord, error)
+}
+
+// taskState 单个任务的执行状态与最近的执行记录
+type taskState struct {
+	status        TaskStatus
+	runs          []*RunRecord // 按开始时间正序，最多保留 ManagerConfig.HistorySize 条
+	missedAlarmed bool         // 已发送错过执行报警，成功后重置
+
+	slots   chan struct{}                // 重叠执行策略的信号量，容量为同时执行的实例数上限
+	cancels map[int64]context.CancelFunc // 已通过重叠执行策略检查的调度，用于 cancel_previous
+	runSeq  int64
+
+	removed bool // 任务已移除，所有实例结束后删除状态
+}
+
+// state 返回任务的状态，不存在时创建，调用方需持有 m.stateMu
+func (m *Manager) state(name string) *taskState {
+	s, ok := m.states[name]
+	if !ok {
+		s = &taskState{status: TaskStatus{Key: name}}
+		m.states[name] = s
+	}
+	return s
+}
+
+// releaseState 已移除任务的所有实例结束后删除其状态，调用方需持有 m.stateMu
+func (m *Manager) releaseState(name string, s *taskState) {
+	if s.removed && s.status.Running == 0 && len(s.cancels) == 0 && m.states[name] == s {
+		delete(m.states, name)
+	}
+}
+
+// resetState 重新注册已移除但仍有实例在执行的任务时，清除旧的执行记录，保留执行中的计数，调用方需持有 m.stateMu
+func (m *Manager) resetState(name string) {
+	s, ok := m.states[name]
+	if !ok || !s.removed {
+		return
+	}
+	m.states[name] = &taskState{
+		status:  TaskStatus{Key: name, Running: s.status.Running},
+		slots:   s.slots,
+		cancels: s.cancels,
+		runSeq:  s.runSeq,
+	}
+}
+
+// beginRun 记录任务开始执行
+func (m *Manager) beginRun(name string, start time.Time) {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	s := m.state(name)
+	s.status.Running++
+	s.status.LastStart = start
+	TaskRunning.WithLabelValues(name).Set(float64(s.status.Running))
+}
+
+// finishRun 记录任务执行结果并写入 HistoryStore，返回更新前后的连续失败次数
+func (m *Manager) finishRun(ctx context.Context, name string, start time.Time, attempts int, err error) (int, int) {
+	run := &RunRecord{
+		Key:       name,
+		Node:      m.nodeID(),
+		StartTime: start,
+		Duration:  time.Since(start),
+		Attempts:  attempts,
+	}
+	if traceID, ok := ctx.Value(constant.CtxTraceID).(string); ok {
+		run.TraceID = traceID
+	}
+	if err != nil {
+		run.Error = err.Error()
+	}
+
+	m.stateMu.Lock()
+	s := m.state(name)
+	s.status.Running--
+	prevFailures := s.status.ConsecutiveFailures
+	s.apply(run)
+	s.status.TotalRuns++
+	if err != nil {
+		s.status.TotalFailures++
+	}
+	s.runs = append(s.runs, run)
+	if len(s.runs) > m.cfg.HistorySize {
+		s.runs = s.runs[len(s.runs)-m.cfg.HistorySize:]
+	}
+	running, failures := s.status.Running, s.status.ConsecutiveFailures
+	m.releaseState(name, s)
+	m.stateMu.Unlock()
+
+	result := "success"
+	if err != nil {
+		result = "failed"
+	}
+	TaskRunsTotal.WithLabelValues(name, result).Inc()
+	TaskDuration.WithLabelValues(name).Observe(run.Duration.Seconds())
+	TaskRunning.WithLabelValues(name).Set(float64(running))
+	TaskConsecutiveFailures.WithLabelValues(name).Set(float64(failures))
+
+	if m.cfg.History != nil {
+		if saveErr := m.cfg.History.SaveRun(context.WithoutCancel(ctx), run); saveErr != nil {
+			logger.Warn(ctx, "CRON", "Task %s failed to save run history: %v", name, saveErr)
+		}
+	}
+	return prevFailures, failures
+}
+
+// apply 根据一次执行结果更新状态
+func (s *taskState) apply(run *RunRecord) {
+	s.status.LastStart = run.StartTime
+	s.status.LastDuration = run.Duration
+	s.status.LastError = run.Error
+	if run.Success() {
+		s.status.LastSuccess = run.StartTime.Add(run.Duration)
+		s.status.ConsecutiveFailures = 0
+		s.missedAlarmed = false
+	} else {
+		s.status.ConsecutiveFailures++
+	}
+}
+
+// restoreHistory 从 HistoryStore 恢复任务最近的状态，使连续失败次数等信息在重启后保留
+func (m *Manager) restoreHistory(ctx context.Context, names []string) {
+	if m.cfg.History == nil {
+		return
+	}
+	for _, name := range names {
+		runs, err := m.cfg.History.ListRuns(ctx, name, m.cfg.HistorySize)
+		if err != nil {
+			logger.Warn(ctx, "CRON", "Task %s failed to restore run history: %v", name, err)
+			continue
+		}
+		m.stateMu.Lock()
+		s := m.state(name)
+		s.runs = s.runs[:0]
+		for i := len(runs) - 1; i >= 0; i-- {
+			s.apply(runs[i])
+			s.runs = append(s.runs, runs[i])
+		}
+		failures := s.status.ConsecutiveFailures
+		m.stateMu.Unlock()
+		TaskConsecutiveFailures.WithLabelValues(name).Set(float64(failures))
+	}
+}
+
+// Status 返回所有已注册任务的状态，按任务名排序
+func (m *Manager) Status() []TaskStatus {
+	m.mu.RLock()
+	names := make([]string, 0, len(m.tasks))
+	for name := range m.tasks {
+		names = append(names, name)
+	}
+	m.mu.RUnlock()
+	sort.Strings(names)
+
+	list := make([]TaskStatus, 0, len(names))
+	for _, name := range names {
+		if status, ok := m.TaskStatus(name); ok {
+			list = append(list, status)
+		}
+	}
+	return list
+}
+
+// TaskStatus 返回指定任务的状态
+func (m *Manager) TaskStatus(name string) (TaskStatus, bool) {
+	m.mu.RLock()
+	task, ok := m.tasks[name]
+	entryID, scheduled := m.entries[name]
+	var spec string
+	if ok {
+		spec = m.specOf(name, task)
+	}
+	paused := m.paused[name]
+	var deps Dependencies
+	if ok {
+		deps = dependenciesOf(task)
+	}
+	m.mu.RUnlock()
+	if !ok {
+		return TaskStatus{}, false
+	}
+
+	m.stateMu.Lock()
+	status := m.state(name).status
+	m.stateMu.Unlock()
+
+	status.Spec = spec
+	status.Enabled = task.Enabled()
+	status.Paused = paused
+	status.DependsOn = deps.Upstreams
+	if scheduled {
+		status.NextRun = m.cron.Entry(entryID).Next
+	}
+	return status, true
+}
+
+// maxHistoryLimit History 从 HistoryStore 读取时的最大条数
+const maxHistoryLimit = 1000
+
+// History 返回任务最近的执行记录，按开始时间倒序，配置了 HistoryStore 时从存储中读取
+// limit <= 0 时为 HistorySize；从内存读取时最多 HistorySize 条，从 HistoryStore 读取时最多 1000 条
+func (m *Manager) History(ctx context.Context, name string, limit int) ([]*RunRecord, error) {
+	if limit <= 0 {
+		limit = m.cfg.HistorySize
+	}
+	if m.cfg.History != nil {
+		return m.cfg.History.ListRuns(ctx, name, min(limit, maxHistoryLimit))
+	}
+	limit = min(limit, m.cfg.HistorySize)
+
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	s, ok := m.states[name]
+	if !ok {
+		return nil, nil
+	}
+	runs := make([]*RunRecord, 0, limit)
+	for i := len(s.runs) - 1; i >= 0 && len(runs) < limit; i-- {
+		runs = append(runs, s.runs[i])
+	}
+	return runs, nil
+}
+
+// RedisHistoryStore 基于 db/redis 的执行记录存储，每个任务一个 list，只保留最近 MaxRuns 条
+type RedisHistoryStore struct {
+	dbIns     string
+	keyPrefix string
+	maxRuns   int64
+}
+
+var _ HistoryStore = (*RedisHistoryStore)(nil)
+
+// NewRedisHistoryStore 创建基于 redis 的执行记录存储，dbIns 为 redis 模块中配置的实例名，maxRuns <= 0 时默认保留 100 条
+func NewRedisHistoryStore(dbIns string, maxRuns int) *RedisHistoryStore {
+	if maxRuns <= 0 {
+		maxRuns = 100
+	}
+	return &RedisHistoryStore{dbIns: dbIns, keyPrefix: "cron:history", maxRuns: int64(maxRuns)}
+}
+
+// SaveRun 保存一次执行记录
+func (s *RedisHistoryStore) SaveRun(ctx context.Context, run *RunRecord) error {
+	conn, err := redis.GetConn(s.dbIns)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	key := s.key(run.Key)
+	pipe := conn.TxPipeline()
+	pipe.LPush(ctx, key, data)
+	pipe.LTrim(ctx, key, 0, s.maxRuns-1)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// ListRuns 返回任务最近的执行记录
+func (s *RedisHistoryStore) ListRuns(ctx context.Context, key string, limit int) ([]*RunRecord, error) {
+	conn, err := redis.GetConn(s.dbIns)
+	if err != nil {
+		return nil, err
+	}
+	items, err := conn.LRange(ctx, s.key(key), 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]*RunRecord, 0, len(items))
+	for _, item := range items {
+		var run RunRecord
+		if err := json.Unmarshal([]byte(item), &run); err != nil {
+			return nil, fmt.Errorf("invalid run record of task %s: %w", key, err)
+		}
+		runs = append(runs, &run)
+	}
+	return runs, nil
+}
+
+func (s *RedisHistoryStore) key(name string) string {
+	return s.keyPrefix + ":" + name
+}
+
+// runRecordModel MysqlHistoryStore 的表结构
+type runRecordModel struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement"`
+	TaskKey    string    `gorm:"column:task_key;type:varchar(128);not null;index:idx_task_start,priority:1"`
+	TraceID    string    `gorm:"column:trace_id;type:varchar(64);not null;default:''"`
+	Node       string    `gorm:"column:node;type:varchar(128);not null;default:''"`
+	StartTime  time.Time `gorm:"column:start_time;type:datetime(3);not null;index:idx_task_start,priority:2"`
+	DurationMs int64     `gorm:"column:duration_ms;not null;default:0"`
+	Attempts   int       `gorm:"column:attempts;not null;default:1"`
+	Error      string    `gorm:"column:error;type:text"`
+}
+
+// MysqlHistoryStore 基于 db/mysql 的执行记录存储
+type MysqlHistoryStore struct {
+	dbIns string
+	table string
+}
+
+var _ HistoryStore = (*MysqlHistoryStore)(nil)
+
+// NewMysqlHistoryStore 创建基于 mysql 的执行记录存储，dbIns 为 mysql 模块中配置的实例名，table 为空时默认 cron_run_history
+func NewMysqlHistoryStore(dbIns, table string) *MysqlHistoryStore {
+	if table == "" {
+		table = "cron_run_history"
+	}
+	return &MysqlHistoryStore{dbIns: dbIns, table: table}
+}
+
+// Migrate 创建或更新执行记录表
+func (s *MysqlHistoryStore) Migrate(ctx context.Context) error {
+	db, err := mysql.GetConn(s.dbIns)
+	if err != nil {
+		return err
+	}
+	return db.WithContext(ctx).Table(s.table).AutoMigrate(&runRecordModel{})
+}
+
+// SaveRun 保存一次执行记录
+func (s *MysqlHistoryStore) SaveRun(ctx context.Context, run *RunRecord) error {
+	db, err := mysql.GetConn(s.dbIns)
+	if err != nil {
+		return err
+	}
+	return db.WithContext(ctx).Table(s.table).Create(&runRecordModel{
+		TaskKey:    run.Key,
+		TraceID:    run.TraceID,
+		Node:       run.Node,
+		StartTime:  run.StartTime,
+		DurationMs: run.Duration.Milliseconds(),
+		Attempts:   run.Attempts,
+		Error:      run.Error,
+	}).Error
+}
+
+// ListRuns 返回任务最近的执行记录
+func (s *MysqlHistoryStore) ListRuns(ctx context.Context, key string, limit int) ([]*RunRecord, error) {
+	db, err := mysql.GetConn(s.dbIns)
+	if err != nil {
+		return nil, err
+	}
+	var rows []runRecordModel
+	err = db.WithContext(ctx).Table(s.table).
+		Where("task_key = ?", key).
+		Order("start_time DESC, id DESC").
+		Limit(limit).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]*RunRecord, 0, len(rows))
+	for _, row := range rows {
+		runs = append(runs, &RunRecord{
+			Key:       row.TaskKey,
+			TraceID:   row.TraceID,
+			Node:      row.Node,
+			StartTime: row.StartTime,
+			Duration:  time.Duration(row.DurationMs) * time.Millisecond,
+			Attempts:  row.Attempts,
+			Error:     row.Error,
+		})
+	}
+	return runs, nil
+}
+
+// nodeID 返回当前节点标识
+func (m *Manager) nodeID() string {
+	if m.dist != nil {
+		return m.dist.cfg.NodeID
+	}
+	return hostname
+}
+
+var hostname, _ = os.Hostname()
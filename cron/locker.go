@@ -0,0 +1,292 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/jessewkun/gocommon/db/redis"
+)
+
+var (
+	// ErrLockHeld 租约已被其他节点持有
+	ErrLockHeld = errors.New("cron: lock is held by another node")
+	// ErrLockLost 租约已过期或被其他节点抢占
+	ErrLockLost = errors.New("cron: lock lost")
+)
+
+// Locker 分布式租约，用于多副本部署时保证同一任务只在一个节点执行
+type Locker interface {
+	// Acquire 获取 key 的租约，成功时返回单调递增的 fencing token，已被持有时返回 ErrLockHeld
+	Acquire(ctx context.Context, key, owner string, ttl time.Duration) (int64, error)
+	// Renew 续约，租约已不属于 owner/token 时返回 ErrLockLost
+	Renew(ctx context.Context, key, owner string, token int64, ttl time.Duration) error
+	// Release 释放租约，hold > 0 时不立即删除而是保留 hold 后过期，用于阻止时钟稍慢的节点重复执行同一次调度
+	Release(ctx context.Context, key, owner string, token int64, hold time.Duration) error
+}
+
+// UpstreamStore 任务依赖中上游成功记录的存储，key 对应一个下游任务
+// 分布式模式下上游可能在不同节点执行，需要所有节点共享同一份记录；Locker 实现该接口后分布式模式才支持任务依赖
+type UpstreamStore interface {
+	// MarkUpstream 记录上游 upstream 在 at 成功，并检查 since 中的每个上游是否都在对应时间之后成功过，
+	// 是时清除 key 的全部记录并返回 true，多个节点并发调用时只有一个返回 true
+	MarkUpstream(ctx context.Context, key, upstream string, at time.Time, since map[string]time.Time) (bool, error)
+	// ResetUpstreams 清除 key 中指定上游的成功记录，不指定上游时清除全部
+	ResetUpstreams(ctx context.Context, key string, upstreams ...string) error
+}
+
+// fence key 与租约 key 使用相同的 hash tag，保证 redis cluster 下位于同一 slot
+var (
+	acquireScript = goredis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+local token = redis.call('INCR', KEYS[2])
+redis.call('SET', KEYS[1], ARGV[1] .. ':' .. token, 'PX', ARGV[2])
+return token
+`)
+	renewScript = goredis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+	releaseScript = goredis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[2]) > 0 then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return redis.call('DEL', KEYS[1])
+`)
+	markUpstreamScript = goredis.NewScript(`
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+for i = 3, #ARGV, 2 do
+	local done = redis.call('HGET', KEYS[1], ARGV[i])
+	if not done or tonumber(done) < tonumber(ARGV[i + 1]) then
+		return 0
+	end
+end
+redis.call('DEL', KEYS[1])
+return 1
+`)
+)
+
+// RedisLocker 基于 db/redis 的租约实现，fencing token 保存在 "<key>:fence" 中且不会过期
+// 同时实现了 UpstreamStore，上游成功记录保存在 hash 中，时间为毫秒时间戳
+type RedisLocker struct {
+	dbIns string
+}
+
+var (
+	_ Locker        = (*RedisLocker)(nil)
+	_ UpstreamStore = (*RedisLocker)(nil)
+)
+
+// NewRedisLocker 创建基于 redis 的租约，dbIns 为 redis 模块中配置的实例名
+func NewRedisLocker(dbIns string) *RedisLocker {
+	return &RedisLocker{dbIns: dbIns}
+}
+
+// Acquire 获取租约
+func (l *RedisLocker) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (int64, error) {
+	conn, err := redis.GetConn(l.dbIns)
+	if err != nil {
+		return 0, err
+	}
+	token, err := acquireScript.Run(ctx, conn, []string{key, key + ":fence"}, owner, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, err
+	}
+	if token == 0 {
+		return 0, ErrLockHeld
+	}
+	return token, nil
+}
+
+// Renew 续约
+func (l *RedisLocker) Renew(ctx context.Context, key, owner string, token int64, ttl time.Duration) error {
+	conn, err := redis.GetConn(l.dbIns)
+	if err != nil {
+		return err
+	}
+	ok, err := renewScript.Run(ctx, conn, []string{key}, leaseValue(owner, token), ttl.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Release 释放租约
+func (l *RedisLocker) Release(ctx context.Context, key, owner string, token int64, hold time.Duration) error {
+	conn, err := redis.GetConn(l.dbIns)
+	if err != nil {
+		return err
+	}
+	return releaseScript.Run(ctx, conn, []string{key}, leaseValue(owner, token), hold.Milliseconds()).Err()
+}
+
+// MarkUpstream 记录上游成功，所有上游都已成功时清除记录并返回 true
+func (l *RedisLocker) MarkUpstream(ctx context.Context, key, upstream string, at time.Time, since map[string]time.Time) (bool, error) {
+	conn, err := redis.GetConn(l.dbIns)
+	if err != nil {
+		return false, err
+	}
+	args := make([]interface{}, 0, 2+2*len(since))
+	args = append(args, upstream, at.UnixMilli())
+	for up, t := range since {
+		args = append(args, up, t.UnixMilli())
+	}
+	ready, err := markUpstreamScript.Run(ctx, conn, []string{key}, args...).Int64()
+	if err != nil {
+		return false, err
+	}
+	return ready == 1, nil
+}
+
+// ResetUpstreams 清除上游成功记录
+func (l *RedisLocker) ResetUpstreams(ctx context.Context, key string, upstreams ...string) error {
+	conn, err := redis.GetConn(l.dbIns)
+	if err != nil {
+		return err
+	}
+	if len(upstreams) == 0 {
+		return conn.Del(ctx, key).Err()
+	}
+	return conn.HDel(ctx, key, upstreams...).Err()
+}
+
+// MemoryLocker 进程内租约，适用于单实例部署与测试，同时实现了 UpstreamStore
+type MemoryLocker struct {
+	mu        sync.Mutex
+	leases    map[string]memoryLease
+	fences    map[string]int64
+	upstreams memoryUpstreams
+}
+
+type memoryLease struct {
+	value    string
+	expireAt time.Time
+}
+
+var (
+	_ Locker        = (*MemoryLocker)(nil)
+	_ UpstreamStore = (*MemoryLocker)(nil)
+)
+
+// NewMemoryLocker 创建进程内租约
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{leases: make(map[string]memoryLease), fences: make(map[string]int64)}
+}
+
+// Acquire 获取租约
+func (l *MemoryLocker) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if lease, ok := l.leases[key]; ok && time.Now().Before(lease.expireAt) {
+		return 0, ErrLockHeld
+	}
+	l.fences[key]++
+	token := l.fences[key]
+	l.leases[key] = memoryLease{value: leaseValue(owner, token), expireAt: time.Now().Add(ttl)}
+	return token, nil
+}
+
+// Renew 续约
+func (l *MemoryLocker) Renew(ctx context.Context, key, owner string, token int64, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lease, ok := l.leases[key]
+	if !ok || !time.Now().Before(lease.expireAt) || lease.value != leaseValue(owner, token) {
+		return ErrLockLost
+	}
+	lease.expireAt = time.Now().Add(ttl)
+	l.leases[key] = lease
+	return nil
+}
+
+// Release 释放租约
+func (l *MemoryLocker) Release(ctx context.Context, key, owner string, token int64, hold time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lease, ok := l.leases[key]
+	if !ok || lease.value != leaseValue(owner, token) {
+		return nil
+	}
+	if hold > 0 {
+		lease.expireAt = time.Now().Add(hold)
+		l.leases[key] = lease
+		return nil
+	}
+	delete(l.leases, key)
+	return nil
+}
+
+// MarkUpstream 记录上游成功，所有上游都已成功时清除记录并返回 true
+func (l *MemoryLocker) MarkUpstream(ctx context.Context, key, upstream string, at time.Time, since map[string]time.Time) (bool, error) {
+	return l.upstreams.MarkUpstream(ctx, key, upstream, at, since)
+}
+
+// ResetUpstreams 清除上游成功记录
+func (l *MemoryLocker) ResetUpstreams(ctx context.Context, key string, upstreams ...string) error {
+	return l.upstreams.ResetUpstreams(ctx, key, upstreams...)
+}
+
+// memoryUpstreams 进程内的上游成功记录，单机模式与 MemoryLocker 使用，零值可用
+type memoryUpstreams struct {
+	mu      sync.Mutex
+	records map[string]map[string]time.Time // 下游 -> 上游 -> 成功时间
+}
+
+var _ UpstreamStore = (*memoryUpstreams)(nil)
+
+func (u *memoryUpstreams) MarkUpstream(ctx context.Context, key, upstream string, at time.Time, since map[string]time.Time) (bool, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.records == nil {
+		u.records = make(map[string]map[string]time.Time)
+	}
+	done, ok := u.records[key]
+	if !ok {
+		done = make(map[string]time.Time)
+		u.records[key] = done
+	}
+	done[upstream] = at
+	for up, t := range since {
+		if succeeded, ok := done[up]; !ok || succeeded.Before(t) {
+			return false, nil
+		}
+	}
+	delete(u.records, key)
+	return true, nil
+}
+
+func (u *memoryUpstreams) ResetUpstreams(ctx context.Context, key string, upstreams ...string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(upstreams) == 0 {
+		delete(u.records, key)
+		return nil
+	}
+	for _, up := range upstreams {
+		delete(u.records[key], up)
+	}
+	return nil
+}
+
+func leaseValue(owner string, token int64) string {
+	return fmt.Sprintf("%s:%d", owner, token)
+}
+
+// lockKey 返回任务租约的 key，任务名放在 hash tag 中
+func lockKey(prefix, name string) string {
+	return fmt.Sprintf("%s:{%s}", strings.TrimSuffix(prefix, ":"), name)
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jessewkun/gocommon/config"
 	"github.com/jessewkun/gocommon/constant"
 	"github.com/jessewkun/gocommon/logger"
 	"github.com/jessewkun/gocommon/safego"
@@ -33,35 +34,113 @@ type Task interface {
 	Enabled() bool
 }
 
+// ManagerConfig 定时任务管理器配置
+type ManagerConfig struct {
+	ConfigKey   string             // 任务配置在配置文件中的 key，设置后管理器注册到 config 模块，修改 <key>.tasks 时热更新任务
+	Distributed *DistributedConfig // 分布式模式配置，为空表示单机模式
+	History     HistoryStore       // 执行记录持久化存储，为空时只保存在内存中
+	HistorySize int                // 每个任务在内存中保留的执行记录条数，默认 20
+
+	Location *time.Location // 调度使用的默认时区，默认本地时区，单个任务可通过 CRON_TZ 或 TaskConfig.Timezone 指定
+
+	// MaxConcurrent 所有任务同时执行的实例数上限，达到上限时新的调度排队等待，0 表示不限制
+	// 单个任务的重叠执行由 OverlapPolicy 控制
+	MaxConcurrent int
+
+	MissedRunCheckInterval time.Duration // 错过执行检测的间隔，默认 1m
+}
+
 // Manager 定时任务管理器
 type Manager struct {
 	cron    *cron.Cron
 	tasks   map[string]Task
 	mu      sync.RWMutex
 	running bool
+	cfg     ManagerConfig
+	entries map[string]cron.EntryID // 已加入调度的任务
+	paused  map[string]bool         // 已暂停的任务
+	specs   map[string]string       // RescheduleTask 设置的调度表达式，优先于 Task.Spec
+	once    map[string]bool         // RunAt 注册的一次性任务，执行后自动移除
+	dist    *distributor
+	slots   chan struct{} // ManagerConfig.MaxConcurrent 的信号量
+
+	upstreams UpstreamStore // 任务依赖的上游成功记录，分布式模式下为 Locker 实现的共享存储，未实现时为 nil
+
+	startedAt time.Time
+	watchStop chan struct{}
+	watchDone chan struct{}
+
+	stateMu     sync.Mutex
+	states      map[string]*taskState
+	inflight    map[int64]*inflight // 正在进行的执行，Stop 时取消
+	inflightSeq int64
+	stopped     bool // Stop 之后、下一次 Start 之前为 true，此时不再执行任务
 }
 
 // NewManager 创建定时任务管理器
 func NewManager() *Manager {
+	return newManager(ManagerConfig{})
+}
+
+// NewManagerWithConfig 使用指定配置创建定时任务管理器，ConfigKey 已被其他模块注册时返回错误
+func NewManagerWithConfig(cfg ManagerConfig) (*Manager, error) {
+	manager := newManager(cfg)
+	if cfg.Distributed != nil {
+		dist, err := newDistributor(*cfg.Distributed)
+		if err != nil {
+			return nil, err
+		}
+		manager.dist = dist
+		// 上游可能在其他节点执行，成功记录需要保存在所有节点共享的存储中
+		manager.upstreams, _ = dist.locker.(UpstreamStore)
+	}
+	if cfg.ConfigKey != "" {
+		if err := config.TryRegister(cfg.ConfigKey, manager); err != nil {
+			return nil, fmt.Errorf("cron: %w", err)
+		}
+	}
+	return manager, nil
+}
+
+// newManager 填充默认配置并创建管理器，不涉及可能失败的初始化
+func newManager(cfg ManagerConfig) *Manager {
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
 	manager := &Manager{
-		cron: cron.New(
-			cron.WithSeconds(),
-			cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)),
-		),
-		tasks: make(map[string]Task),
+		cron:     cron.New(cron.WithSeconds(), cron.WithLocation(cfg.Location)),
+		tasks:    make(map[string]Task),
+		entries:  make(map[string]cron.EntryID),
+		paused:   make(map[string]bool),
+		specs:    make(map[string]string),
+		once:     make(map[string]bool),
+		states:   make(map[string]*taskState),
+		inflight: make(map[int64]*inflight),
+
+		upstreams: &memoryUpstreams{},
+	}
+	if cfg.HistorySize <= 0 {
+		cfg.HistorySize = 20
+	}
+	if cfg.MissedRunCheckInterval <= 0 {
+		cfg.MissedRunCheckInterval = time.Minute
+	}
+	if cfg.MaxConcurrent > 0 {
+		manager.slots = make(chan struct{}, cfg.MaxConcurrent)
 	}
+	manager.cfg = cfg
 	return manager
 }
 
-// RegisterTask 注册定时任务
+// RegisterTask 注册定时任务，管理器运行中注册的任务会立即加入调度
 func (m *Manager) RegisterTask(task Task) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	return m.register(task, "")
+}
 
-	if m.running {
-		return fmt.Errorf("cannot register task after manager started")
-	}
-
+// register 注册任务，spec 不为空时覆盖 Task.Spec，调用方需持有 m.mu
+func (m *Manager) register(task Task, spec string) error {
 	if task == nil {
 		return fmt.Errorf("task cannot be nil")
 	}
@@ -74,7 +153,32 @@ func (m *Manager) RegisterTask(task Task) error {
 	if _, exists := m.tasks[key]; exists {
 		return fmt.Errorf("task %s already registered", key)
 	}
+	if err := m.checkDistributedDependencies(key, dependenciesOf(task).Upstreams, lockModeOf(task)); err != nil {
+		return err
+	}
+	if err := m.checkDistributedOverlap(key, overlapPolicyOf(task), lockModeOf(task)); err != nil {
+		return err
+	}
 
+	if m.running {
+		graph := m.dependencyGraph()
+		graph[key] = dependenciesOf(task).Upstreams
+		if err := checkDependencies(graph); err != nil {
+			return err
+		}
+	}
+	if spec != "" {
+		m.specs[key] = spec
+	}
+	if m.running {
+		if err := m.schedule(context.Background(), key, task); err != nil {
+			delete(m.specs, key)
+			return err
+		}
+	}
+	m.stateMu.Lock()
+	m.resetState(key)
+	m.stateMu.Unlock()
 	m.tasks[key] = task
 	return nil
 }
@@ -88,24 +192,39 @@ func (m *Manager) Start(ctx context.Context) error {
 		return fmt.Errorf("manager already started")
 	}
 
+	if err := checkDependencies(m.dependencyGraph()); err != nil {
+		return err
+	}
 	for name, task := range m.tasks {
-		if !task.Enabled() {
-			logger.Info(ctx, "CRON", "Skipping disabled task: %s", name)
-			continue
+		if err := m.checkDistributedDependencies(name, dependenciesOf(task).Upstreams, lockModeOf(task)); err != nil {
+			return err
+		}
+		if err := m.checkDistributedOverlap(name, overlapPolicyOf(task), lockModeOf(task)); err != nil {
+			return err
 		}
+	}
 
-		_, err := m.cron.AddFunc(task.Spec(), func() {
-			runErr := m.runTask(name, task)
-			if runErr != nil {
-				logger.ErrorWithMsg(ctx, "CRON", "Task %s failed: %v", name, runErr)
-			}
-		})
-		if err != nil {
-			return fmt.Errorf("failed to add task %s: %w", name, err)
+	names := make([]string, 0, len(m.tasks))
+	for name := range m.tasks {
+		names = append(names, name)
+	}
+	m.restoreHistory(ctx, names)
+
+	for name, task := range m.tasks {
+		if err := m.schedule(ctx, name, task); err != nil {
+			m.unscheduleAll()
+			return err
 		}
-		logger.Info(ctx, "CRON", "Registered task: %s", name)
 	}
 
+	if m.dist != nil {
+		m.dist.start(context.WithoutCancel(ctx))
+	}
+	m.stateMu.Lock()
+	m.stopped = false
+	m.stateMu.Unlock()
+	m.startedAt = time.Now()
+	m.startWatchdog(context.WithoutCancel(ctx))
 	m.cron.Start()
 	m.running = true
 
@@ -113,32 +232,188 @@ func (m *Manager) Start(ctx context.Context) error {
 	return nil
 }
 
-// Stop 停止定时任务管理器
-func (m *Manager) Stop(ctx context.Context) {
+// Stop 停止定时任务管理器，不再触发新的调度，并取消所有正在执行的任务（包括 RunTask 手动执行）的上下文，
+// 等待其退出、AfterRun 执行完毕，最长等待到 ctx 截止
+// 有任务被中断时返回 *StopError，截止时仍未退出的任务记录在 Unfinished 中
+func (m *Manager) Stop(ctx context.Context) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	if !m.running {
-		return
+		m.mu.Unlock()
+		return nil
 	}
-
 	c := m.cron.Stop()
-	<-c.Done()
+	m.unscheduleAll()
+	m.stopWatchdog()
 	m.running = false
+	// 释放锁后再等待，正在执行的任务触发下游、移除一次性任务时需要获取 m.mu
+	m.mu.Unlock()
 
+	err := m.interrupt(ctx)
+	select {
+	case <-c.Done():
+	case <-ctx.Done():
+	}
+	if m.dist != nil {
+		m.dist.shutdown()
+	}
+
+	var stopErr *StopError
+	if errors.As(err, &stopErr) {
+		if len(stopErr.Unfinished) > 0 {
+			logger.ErrorWithMsg(ctx, "CRON", "Stopped cron manager, tasks %v not finished before deadline", stopErr.Unfinished)
+		} else {
+			logger.Warn(ctx, "CRON", "Stopped cron manager, interrupted tasks: %v", stopErr.Interrupted)
+		}
+		return err
+	}
 	logger.Info(ctx, "CRON", "Stopped cron manager")
+	return nil
 }
 
-// runTask 执行单个任务
-func (m *Manager) runTask(name string, task Task) error {
-	startTime := time.Now()
+// dispatch 执行一次调度，先按任务的 OverlapPolicy 与全局并发上限判断能否执行，
+// 分布式模式下再根据任务的 LockMode 获取租约，未获取到时跳过本次调度
+// ctx 携带本次执行的 trace ID，由下游任务继承
+func (m *Manager) dispatch(ctx context.Context, name string, task Task) error {
+	tick := time.Now().Round(time.Second)
+	trackedCtx, untrack := m.track(ctx, name)
+	defer untrack()
+	if errors.Is(context.Cause(trackedCtx), ErrManagerStopped) {
+		logger.Info(ctx, "CRON", "Task %s skipped: manager stopped", name)
+		return nil
+	}
+	baseCtx, cancel := context.WithCancel(trackedCtx)
+	defer cancel()
 
-	// Create a base context with trace ID
-	baseCtx := context.Background()
-	baseCtx = context.WithValue(baseCtx, constant.CtxTraceID, uuid.New().String())
+	leave, ok := m.enter(baseCtx, name, task, cancel)
+	if !ok {
+		return nil
+	}
+	defer leave()
+
+	// 随机延迟在获取租约前进行，租约至少保留到 tick + jitter + MinHold，延迟较长的节点不会重复执行
+	jitter := jitterOf(task)
+	if delay := randomDelay(jitter); delay > 0 {
+		logger.Debug(baseCtx, "CRON", "Task %s delayed %v by jitter", name, delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-baseCtx.Done():
+			timer.Stop()
+			logger.Info(baseCtx, "CRON", "Task %s cancelled during jitter delay", name)
+			return nil
+		}
+	}
+
+	if m.dist != nil {
+		switch lockModeOf(task) {
+		case LockModeNone:
+		case LockModeLeader:
+			token := m.dist.leader()
+			if token == 0 {
+				logger.Debug(baseCtx, "CRON", "Task %s skipped: node %s is not leader", name, m.dist.cfg.NodeID)
+				return nil
+			}
+			baseCtx = context.WithValue(baseCtx, fencingTokenKey{}, token)
+			// 执行期间失去 leader 时取消任务，与租约丢失的处理一致
+			defer m.dist.followLeader(token, func() {
+				logger.ErrorWithMsg(baseCtx, "CRON", "Task %s cancelled: node %s lost leadership (token %d)", name, m.dist.cfg.NodeID, token)
+				cancel()
+			})()
+		default:
+			l, err := m.dist.acquire(baseCtx, name, tick.Add(jitter))
+			if errors.Is(err, ErrLockHeld) {
+				// 租约被本节点仍在执行的实例占用时计为跳过，否则为其他节点已执行本次调度
+				if m.dist.holding(name) {
+					m.skipRun(baseCtx, name, "lease held by a previous run on this node")
+					return nil
+				}
+				logger.Debug(baseCtx, "CRON", "Task %s skipped: lease held by another node", name)
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("acquire lease failed: %w", err)
+			}
+			baseCtx = context.WithValue(baseCtx, fencingTokenKey{}, l.token)
+			defer m.dist.release(context.WithoutCancel(baseCtx), name, l)
+			defer m.dist.keepAlive(baseCtx, name, l, cancel)()
+		}
+	}
+	return m.runTask(baseCtx, name, task)
+}
+
+// newRunContext 创建带有 trace ID 的任务上下文
+func newRunContext() context.Context {
+	return context.WithValue(context.Background(), constant.CtxTraceID, uuid.New().String())
+}
+
+// runTask 执行单个任务，失败时按任务的 RetryPolicy 重试
+func (m *Manager) runTask(baseCtx context.Context, name string, task Task) error {
+	startTime := time.Now()
 
 	logger.Info(baseCtx, "CRON", "Task %s started", name)
+	m.beginRun(name, startTime)
+
+	policy := retryPolicyOf(task)
+	attempts := 1
+	worker, err := m.runOnce(baseCtx, name, task)
+	for err != nil && attempts <= policy.MaxRetries {
+		delay := policy.backoff(attempts)
+		logger.Warn(baseCtx, "CRON", "Task %s attempt %d failed: %v, retrying in %v", name, attempts, err, delay)
+		// baseCtx 被取消（租约丢失、管理器停止）时不再重试，只统计实际执行的次数
+		if !m.waitRetry(baseCtx, name, worker, delay) {
+			break
+		}
+		attempts++
+		worker, err = m.runOnce(baseCtx, name, task)
+	}
 
+	duration := time.Since(startTime)
+	prevFailures, failures := m.finishRun(baseCtx, name, startTime, attempts, err)
+	// 管理器停止导致的中断不报警
+	if !errors.Is(err, ErrManagerStopped) {
+		m.checkFailureAlarm(baseCtx, name, task, prevFailures, failures, err)
+	}
+	m.triggerDownstream(baseCtx, name, err)
+
+	if err != nil {
+		logger.ErrorWithMsg(baseCtx, "CRON", "Task %s failed after %v (%d attempts): %v", name, duration, attempts, err)
+	} else {
+		logger.Info(baseCtx, "CRON", "Task %s completed successfully in %v", name, duration)
+	}
+	return err
+}
+
+// waitRetry 等待重试的退避时间，并等待上一次超时后仍在执行的 worker 退出，避免同一任务的多次尝试并行执行
+// baseCtx 被取消时返回 false
+func (m *Manager) waitRetry(baseCtx context.Context, name string, worker <-chan struct{}, delay time.Duration) bool {
+	if baseCtx.Err() != nil {
+		return false
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-baseCtx.Done():
+		return false
+	case <-timer.C:
+	}
+
+	select {
+	case <-worker:
+		return true
+	default:
+	}
+	logger.Warn(baseCtx, "CRON", "Task %s waiting for the previous attempt to exit before retrying", name)
+	select {
+	case <-worker:
+		return true
+	case <-baseCtx.Done():
+		return false
+	}
+}
+
+// runOnce 执行一次 BeforeRun、Run、AfterRun，worker 在执行的 goroutine 退出后关闭
+// 超时或被取消时 runOnce 立即返回，此时 worker 可能仍在执行
+func (m *Manager) runOnce(baseCtx context.Context, name string, task Task) (worker <-chan struct{}, err error) {
 	// Prepare the final context for the task
 	var (
 		taskCtx context.Context
@@ -158,18 +433,30 @@ func (m *Manager) runTask(name string, task Task) error {
 	}
 	resultChan := make(chan result, 1)
 
+	workerDone := trackWorker(baseCtx)
+	done := make(chan struct{})
 	go func() {
+		defer close(done)
+		defer workerDone()
 		var err error
 		safego.SafeGo(taskCtx, func() {
+			// panic 视为执行失败，重新抛出交给 safego 记录堆栈
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("task panicked: %v", r)
+					panic(r)
+				}
+			}()
+
 			// 执行 BeforeRun 钩子
 			if beforeErr := task.BeforeRun(taskCtx); beforeErr != nil {
 				err = fmt.Errorf("before run failed: %w", beforeErr)
 				return
 			}
 
-			// 检查上下文是否在任务开始前就已超时
+			// 检查上下文是否在任务开始前就已超时或被取消
 			if taskCtx.Err() != nil {
-				err = taskCtx.Err()
+				err = context.Cause(taskCtx)
 				return
 			}
 
@@ -177,8 +464,8 @@ func (m *Manager) runTask(name string, task Task) error {
 			err = task.Run(taskCtx)
 
 			// AfterRun 钩子应该总是被执行，即使任务超时。
-			// 我们使用 baseCtx 来运行它，以避免它因为 taskCtx 被取消而无法执行。
-			if afterErr := task.AfterRun(baseCtx); afterErr != nil {
+			// 我们使用不会被取消的 baseCtx 来运行它，以避免它因为 taskCtx 被取消而无法执行。
+			if afterErr := task.AfterRun(context.WithoutCancel(baseCtx)); afterErr != nil {
 				if err != nil {
 					// 如果主任务也失败了，记录两个错误
 					err = fmt.Errorf("main task failed: %w, after run also failed: %w", err, afterErr)
@@ -192,27 +479,19 @@ func (m *Manager) runTask(name string, task Task) error {
 	}()
 
 	// 等待任务执行结果或超时/取消
-	var err error
 	select {
 	case res := <-resultChan:
 		err = res.err
 	case <-taskCtx.Done():
-		err = taskCtx.Err()
+		// 管理器停止时为 ErrManagerStopped
+		err = context.Cause(taskCtx)
 	}
 
 	// 如果错误是由于上下文超时/取消引起的，包装成更明确的超时错误信息
 	if errors.Is(err, context.DeadlineExceeded) {
 		err = fmt.Errorf("task %s timeout after %v", name, timeout)
 	}
-
-	duration := time.Since(startTime)
-
-	if err != nil {
-		logger.ErrorWithMsg(baseCtx, "CRON", "Task %s failed after %v: %v", name, duration, err)
-	} else {
-		logger.Info(baseCtx, "CRON", "Task %s completed successfully in %v", name, duration)
-	}
-	return err
+	return done, err
 }
 
 // GetTaskNames 获取所有已注册的任务名称
@@ -247,5 +526,20 @@ func (m *Manager) RunTask(ctx context.Context, taskName string) error {
 
 	// 直接执行，因为 m.tasks 中存储的已经是被 ConfigurableTask 包装过的任务
 	// 它自带了从配置中读取的超时等信息
-	return m.runTask(taskName, task)
+	// 手动执行不经过分布式租约
+	// 管理器停止后、重新 Start 前不再执行
+	runCtx, untrack := m.track(newRunContext(), taskName)
+	defer untrack()
+	if errors.Is(context.Cause(runCtx), ErrManagerStopped) {
+		return ErrManagerStopped
+	}
+	return m.runTask(runCtx, taskName, task)
+}
+
+// IsLeader 当前节点是否为 leader，未开启分布式模式时总是返回 true
+func (m *Manager) IsLeader() bool {
+	if m.dist == nil {
+		return true
+	}
+	return m.dist.leader() > 0
 }
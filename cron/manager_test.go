@@ -0,0 +1,1307 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jessewkun/gocommon/constant"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type funcTask struct {
+	BaseTask
+	key    string
+	mode   LockMode
+	retry  RetryPolicy
+	alarms AlarmPolicy
+	run    func(ctx context.Context) error
+}
+
+func (t *funcTask) RetryPolicy() RetryPolicy { return t.retry }
+func (t *funcTask) AlarmPolicy() AlarmPolicy { return t.alarms }
+
+func (t *funcTask) Key() string        { return t.key }
+func (t *funcTask) Spec() string       { return "0 0 0 * * *" }
+func (t *funcTask) Enabled() bool      { return true }
+func (t *funcTask) LockMode() LockMode { return t.mode }
+
+func (t *funcTask) Run(ctx context.Context) error {
+	if t.run == nil {
+		return nil
+	}
+	return t.run(ctx)
+}
+
+func newDistributedManager(t *testing.T, locker Locker, node string) *Manager {
+	m, err := NewManagerWithConfig(ManagerConfig{Distributed: &DistributedConfig{
+		Locker:  locker,
+		NodeID:  node,
+		MinHold: time.Minute,
+	}})
+	require.NoError(t, err)
+	return m
+}
+
+func TestManager_DistributedLease(t *testing.T) {
+	locker := NewMemoryLocker()
+	m1 := newDistributedManager(t, locker, "node-1")
+	m2 := newDistributedManager(t, locker, "node-2")
+
+	started := make(chan int64, 1)
+	release := make(chan struct{})
+	var runs int
+	var mu sync.Mutex
+	task := &funcTask{key: "export", run: func(ctx context.Context) error {
+		mu.Lock()
+		runs++
+		mu.Unlock()
+		token, _ := FencingToken(ctx)
+		select {
+		case started <- token:
+		default:
+		}
+		<-release
+		return nil
+	}}
+
+	done := make(chan error, 1)
+	go func() { done <- m1.dispatch(newRunContext(), "export", task) }()
+	assert.Equal(t, int64(1), <-started)
+
+	// 租约被 node-1 持有，node-2 跳过本次调度
+	assert.NoError(t, m2.dispatch(newRunContext(), "export", task))
+	close(release)
+	require.NoError(t, <-done)
+
+	// 执行结束后租约保留到 tick + MinHold，时钟稍慢的节点不会重复执行同一次调度
+	assert.NoError(t, m2.dispatch(newRunContext(), "export", task))
+	mu.Lock()
+	assert.Equal(t, 1, runs)
+	mu.Unlock()
+
+	// 不加锁的任务每个节点都会执行，且没有 fencing token
+	task.mode = LockModeNone
+	require.NoError(t, m2.dispatch(newRunContext(), "export", task))
+	mu.Lock()
+	assert.Equal(t, 2, runs)
+	mu.Unlock()
+	assert.Equal(t, int64(0), <-started)
+}
+
+func TestManager_DistributedLeaseLost(t *testing.T) {
+	locker := NewMemoryLocker()
+	m, err := NewManagerWithConfig(ManagerConfig{Distributed: &DistributedConfig{
+		Locker:        locker,
+		LeaseTTL:      30 * time.Millisecond,
+		RenewInterval: 10 * time.Millisecond,
+	}})
+	require.NoError(t, err)
+
+	task := &funcTask{key: "aggregate", run: func(ctx context.Context) error {
+		// 模拟租约被其他节点抢占
+		locker.mu.Lock()
+		delete(locker.leases, lockKey("cron:lock", "aggregate"))
+		locker.mu.Unlock()
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	assert.ErrorIs(t, m.dispatch(newRunContext(), "aggregate", task), context.Canceled)
+}
+
+func TestManager_LeaderOnly(t *testing.T) {
+	locker := NewMemoryLocker()
+	m1 := newDistributedManager(t, locker, "node-1")
+	m2 := newDistributedManager(t, locker, "node-2")
+	m2.dist.cfg.RenewInterval = 10 * time.Millisecond
+
+	var mu sync.Mutex
+	var nodes []int64
+	task := &funcTask{key: "report", mode: LockModeLeader, run: func(ctx context.Context) error {
+		token, _ := FencingToken(ctx)
+		mu.Lock()
+		nodes = append(nodes, token)
+		mu.Unlock()
+		return nil
+	}}
+
+	ctx := context.Background()
+	require.NoError(t, m1.Start(ctx))
+	require.NoError(t, m2.Start(ctx))
+	defer m2.Stop(ctx)
+	assert.True(t, m1.IsLeader())
+	assert.False(t, m2.IsLeader())
+
+	require.NoError(t, m1.dispatch(newRunContext(), "report", task))
+	require.NoError(t, m2.dispatch(newRunContext(), "report", task))
+	assert.Equal(t, []int64{1}, nodes)
+
+	// leader 停止后主动释放租约，其他节点接管，fencing token 递增
+	m1.Stop(ctx)
+	assert.Eventually(t, m2.IsLeader, time.Second, 10*time.Millisecond)
+	require.NoError(t, m2.dispatch(newRunContext(), "report", task))
+	assert.Equal(t, []int64{1, 2}, nodes)
+}
+
+func TestConfigurableTask_LockMode(t *testing.T) {
+	task := NewConfigurableTask(&funcTask{mode: LockModeNone}, TaskConfig{Key: "k"})
+	assert.Equal(t, LockModeNone, lockModeOf(task))
+	task = NewConfigurableTask(&funcTask{}, TaskConfig{Key: "k", Lock: "leader"})
+	assert.Equal(t, LockModeLeader, lockModeOf(task))
+	assert.Equal(t, LockModeRun, lockModeOf(&BaseTask{}))
+}
+
+type memoryHistory struct {
+	mu        sync.Mutex
+	runs      []*RunRecord
+	lastLimit int // 最近一次 ListRuns 的 limit
+}
+
+func (h *memoryHistory) SaveRun(ctx context.Context, run *RunRecord) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.runs = append(h.runs, run)
+	return nil
+}
+
+func (h *memoryHistory) ListRuns(ctx context.Context, key string, limit int) ([]*RunRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastLimit = limit
+	var runs []*RunRecord
+	for i := len(h.runs) - 1; i >= 0 && len(runs) < limit; i-- {
+		if h.runs[i].Key == key {
+			runs = append(runs, h.runs[i])
+		}
+	}
+	return runs, nil
+}
+
+func TestManager_StatusAndHistory(t *testing.T) {
+	m := NewManager()
+	fail := true
+	task := &funcTask{key: "cleanup", run: func(ctx context.Context) error {
+		if fail {
+			return assert.AnError
+		}
+		return nil
+	}}
+	require.NoError(t, m.RegisterTask(task))
+
+	ctx := context.Background()
+	assert.Error(t, m.RunTask(ctx, "cleanup"))
+	assert.Error(t, m.RunTask(ctx, "cleanup"))
+	status, ok := m.TaskStatus("cleanup")
+	require.True(t, ok)
+	assert.Equal(t, 2, status.ConsecutiveFailures)
+	assert.Equal(t, assert.AnError.Error(), status.LastError)
+	assert.True(t, status.LastSuccess.IsZero())
+
+	fail = false
+	require.NoError(t, m.RunTask(ctx, "cleanup"))
+	status, _ = m.TaskStatus("cleanup")
+	assert.Equal(t, 0, status.ConsecutiveFailures)
+	assert.Empty(t, status.LastError)
+	assert.Equal(t, int64(3), status.TotalRuns)
+	assert.Equal(t, int64(2), status.TotalFailures)
+	assert.False(t, status.LastSuccess.IsZero())
+	assert.True(t, status.NextRun.IsZero(), "not scheduled before Start")
+
+	runs, err := m.History(ctx, "cleanup", 2)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.True(t, runs[0].Success())
+	assert.False(t, runs[1].Success())
+	assert.NotEmpty(t, runs[0].TraceID)
+	// limit 来自外部输入，超过 HistorySize 时截断
+	runs, err = m.History(ctx, "cleanup", math.MaxInt)
+	require.NoError(t, err)
+	assert.Len(t, runs, 3)
+
+	require.NoError(t, m.Start(ctx))
+	defer m.Stop(ctx)
+	list := m.Status()
+	require.Len(t, list, 1)
+	assert.False(t, list[0].NextRun.IsZero())
+	assert.Equal(t, "0 0 0 * * *", list[0].Spec)
+}
+
+func TestManager_RestoreHistory(t *testing.T) {
+	store := &memoryHistory{}
+	now := time.Now()
+	store.runs = []*RunRecord{
+		{Key: "report", StartTime: now.Add(-3 * time.Hour), Duration: time.Second},
+		{Key: "report", StartTime: now.Add(-2 * time.Hour), Error: "boom"},
+		{Key: "report", StartTime: now.Add(-time.Hour), Error: "boom again"},
+	}
+
+	m, err := NewManagerWithConfig(ManagerConfig{History: store})
+	require.NoError(t, err)
+	require.NoError(t, m.RegisterTask(&funcTask{key: "report"}))
+	ctx := context.Background()
+	require.NoError(t, m.Start(ctx))
+	defer m.Stop(ctx)
+
+	status, _ := m.TaskStatus("report")
+	assert.Equal(t, 2, status.ConsecutiveFailures)
+	assert.Equal(t, "boom again", status.LastError)
+	assert.Equal(t, now.Add(-3*time.Hour).Add(time.Second), status.LastSuccess)
+
+	require.NoError(t, m.RunTask(ctx, "report"))
+	assert.Len(t, store.runs, 4)
+	runs, err := m.History(ctx, "report", math.MaxInt)
+	require.NoError(t, err)
+	assert.Len(t, runs, 4)
+	assert.Equal(t, maxHistoryLimit, store.lastLimit)
+	status, _ = m.TaskStatus("report")
+	assert.Equal(t, 0, status.ConsecutiveFailures)
+}
+
+func captureAlarms(t *testing.T) chan string {
+	titles := make(chan string, 10)
+	orig := sendAlarm
+	sendAlarm = func(ctx context.Context, title string, content []string) error {
+		titles <- title
+		return nil
+	}
+	t.Cleanup(func() { sendAlarm = orig })
+	return titles
+}
+
+func TestManager_Retry(t *testing.T) {
+	m := NewManager()
+	attempts := 0
+	task := &funcTask{key: "sync", retry: RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond}, run: func(ctx context.Context) error {
+		attempts++
+		if attempts == 2 {
+			panic("flaky")
+		}
+		if attempts < 3 {
+			return assert.AnError
+		}
+		return nil
+	}}
+	require.NoError(t, m.RegisterTask(task))
+	require.NoError(t, m.RunTask(context.Background(), "sync"))
+	assert.Equal(t, 3, attempts)
+
+	runs, _ := m.History(context.Background(), "sync", 1)
+	require.Len(t, runs, 1)
+	assert.Equal(t, 3, runs[0].Attempts)
+
+	// 重试次数用完仍失败
+	attempts = -10
+	err := m.RunTask(context.Background(), "sync")
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, -6, attempts)
+	status, _ := m.TaskStatus("sync")
+	assert.Equal(t, 1, status.ConsecutiveFailures)
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, p.backoff(1))
+	assert.Equal(t, 2*time.Second, p.backoff(2))
+	assert.Equal(t, 4*time.Second, p.backoff(3))
+	assert.Equal(t, 5*time.Second, p.backoff(4))
+	assert.Equal(t, time.Second, RetryPolicy{}.backoff(1))
+}
+
+func TestManager_FailureAlarm(t *testing.T) {
+	titles := captureAlarms(t)
+	m := NewManager()
+	fail := true
+	task := &funcTask{key: "billing", alarms: AlarmPolicy{FailureThreshold: 2}, run: func(ctx context.Context) error {
+		if fail {
+			return assert.AnError
+		}
+		return nil
+	}}
+	require.NoError(t, m.RegisterTask(task))
+
+	ctx := context.Background()
+	_ = m.RunTask(ctx, "billing")
+	_ = m.RunTask(ctx, "billing")
+	assert.Equal(t, "[CRON] 任务 billing 连续失败 2 次", <-titles)
+	_ = m.RunTask(ctx, "billing")
+	_ = m.RunTask(ctx, "billing")
+	assert.Equal(t, "[CRON] 任务 billing 连续失败 4 次", <-titles)
+
+	fail = false
+	require.NoError(t, m.RunTask(ctx, "billing"))
+	assert.Equal(t, "[CRON] 任务 billing 已恢复，此前连续失败 4 次", <-titles)
+	require.NoError(t, m.RunTask(ctx, "billing"))
+	select {
+	case title := <-titles:
+		t.Fatalf("unexpected alarm: %s", title)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestManager_MissedRuns(t *testing.T) {
+	titles := captureAlarms(t)
+	m := NewManager()
+	task := &funcTask{key: "nightly", alarms: AlarmPolicy{MissedRuns: 2}}
+	require.NoError(t, m.RegisterTask(task))
+	ctx := context.Background()
+	require.NoError(t, m.Start(ctx))
+	defer m.Stop(ctx)
+
+	// 任务每天 0 点执行，启动后第二天之内不报警
+	m.checkMissedRuns(ctx, time.Now().Add(24*time.Hour))
+	m.checkMissedRuns(ctx, time.Now().Add(49*time.Hour))
+	assert.Contains(t, <-titles, "已错过 2 次调度未成功执行")
+
+	// 同一次错过只报警一次，成功后重置
+	m.checkMissedRuns(ctx, time.Now().Add(72*time.Hour))
+	require.NoError(t, m.RunTask(ctx, "nightly"))
+	m.checkMissedRuns(ctx, time.Now().Add(24*time.Hour))
+	select {
+	case title := <-titles:
+		t.Fatalf("unexpected alarm: %s", title)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestManager_DistributedMissedRuns(t *testing.T) {
+	titles := captureAlarms(t)
+	ctx := context.Background()
+	newManager := func(history HistoryStore) *Manager {
+		m, err := NewManagerWithConfig(ManagerConfig{History: history, Distributed: &DistributedConfig{Locker: NewMemoryLocker(), NodeID: "node-1"}})
+		require.NoError(t, err)
+		require.NoError(t, m.RegisterTask(&funcTask{key: "nightly", alarms: AlarmPolicy{MissedRuns: 2}}))
+		require.NoError(t, m.Start(ctx))
+		t.Cleanup(func() { m.Stop(ctx) })
+		require.True(t, m.IsLeader())
+		return m
+	}
+
+	// 未配置 HistoryStore 时 leader 看不到其他节点的执行，不做检测
+	newManager(nil).checkMissedRuns(ctx, time.Now().Add(49*time.Hour))
+	select {
+	case title := <-titles:
+		t.Fatalf("unexpected alarm: %s", title)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	newManager(&memoryHistory{}).checkMissedRuns(ctx, time.Now().Add(49*time.Hour))
+	assert.Contains(t, <-titles, "已错过 2 次调度未成功执行")
+}
+
+func TestManager_RuntimeChanges(t *testing.T) {
+	m := NewManager()
+	ctx := context.Background()
+	require.NoError(t, m.Start(ctx))
+	defer m.Stop(ctx)
+
+	// 运行中注册的任务立即加入调度，spec 无效时注册失败
+	require.NoError(t, m.RegisterTask(&funcTask{key: "late"}))
+	status, _ := m.TaskStatus("late")
+	assert.False(t, status.NextRun.IsZero())
+	assert.Error(t, m.RegisterTask(NewConfigurableTask(&BaseTask{}, TaskConfig{Key: "bad", Spec: "invalid", Enabled: true})))
+	assert.NotContains(t, m.GetTaskNames(), "bad")
+
+	require.NoError(t, m.PauseTask("late"))
+	status, _ = m.TaskStatus("late")
+	assert.True(t, status.Paused)
+	assert.True(t, status.NextRun.IsZero())
+	require.NoError(t, m.RunTask(ctx, "late"), "paused task can still run manually")
+	require.NoError(t, m.ResumeTask("late"))
+	assert.False(t, m.IsPaused("late"))
+
+	require.Error(t, m.RescheduleTask("late", "not a spec"))
+	require.NoError(t, m.RescheduleTask("late", "@every 1h"))
+	status, _ = m.TaskStatus("late")
+	assert.Equal(t, "@every 1h", status.Spec)
+	assert.Equal(t, time.Now().Truncate(time.Hour).Add(time.Hour).Unix(), status.NextRun.Unix(), "@every is aligned to the interval")
+
+	require.NoError(t, m.RemoveTask("late"))
+	_, ok := m.TaskStatus("late")
+	assert.False(t, ok)
+	assert.Error(t, m.RemoveTask("late"))
+}
+
+// uniqueConfigKey 生成唯一的 ConfigKey，config 模块的注册是全局的，go test -count=N 时不能重复注册
+func uniqueConfigKey(prefix string) string {
+	return fmt.Sprintf("%s_%d", prefix, configKeySeq.Add(1))
+}
+
+var configKeySeq atomic.Int64
+
+func TestManager_ReloadConfig(t *testing.T) {
+	key := uniqueConfigKey("cron_reload_test")
+	m, err := NewManagerWithConfig(ManagerConfig{ConfigKey: key})
+	require.NoError(t, err)
+	task := NewConfigurableTask(&BaseTask{}, TaskConfig{Key: "export", Spec: "0 0 2 * * *", Enabled: true, Timeout: "1m"})
+	require.NoError(t, m.RegisterTask(task))
+	require.NoError(t, m.RegisterTask(&funcTask{key: "plain"}))
+	ctx := context.Background()
+	require.NoError(t, m.Start(ctx))
+	defer m.Stop(ctx)
+
+	v := viper.New()
+	v.Set(key, map[string]interface{}{"tasks": []interface{}{
+		map[string]interface{}{"key": "export", "spec": "0 30 3 * * *", "enabled": true, "timeout": "5m"},
+		map[string]interface{}{"key": "plain", "spec": "0 0 * * * *", "enabled": true},
+		map[string]interface{}{"key": "unknown", "spec": "0 0 * * * *", "enabled": true},
+	}})
+	// config.Init 会将配置解析到注册的对象上，Manager 没有导出字段，解析不应报错
+	require.NoError(t, v.UnmarshalKey(key, m))
+	require.NoError(t, m.Reload(v))
+
+	status, _ := m.TaskStatus("export")
+	assert.Equal(t, "0 30 3 * * *", status.Spec)
+	assert.Equal(t, 3, status.NextRun.Hour())
+	assert.Equal(t, 30, status.NextRun.Minute())
+	assert.Equal(t, 5*time.Minute, task.Timeout())
+	status, _ = m.TaskStatus("plain")
+	assert.Equal(t, "0 0 0 * * *", status.Spec, "non-configurable tasks are not changed")
+
+	// 禁用后移出调度，无效的 spec 不生效
+	v.Set(key+".tasks", []interface{}{map[string]interface{}{"key": "export", "spec": "0 30 3 * * *", "enabled": false}})
+	require.NoError(t, m.Reload(v))
+	status, _ = m.TaskStatus("export")
+	assert.False(t, status.Enabled)
+	assert.True(t, status.NextRun.IsZero())
+
+	v.Set(key+".tasks", []interface{}{map[string]interface{}{"key": "export", "spec": "bad", "enabled": true}})
+	assert.Error(t, m.Reload(v))
+	assert.False(t, task.Enabled())
+}
+
+type dependentTask struct {
+	funcTask
+	deps Dependencies
+}
+
+func (t *dependentTask) Dependencies() Dependencies { return t.deps }
+
+func TestManager_Dependencies(t *testing.T) {
+	m := NewManager()
+	traces := make(chan string, 4)
+	record := func(ctx context.Context) error {
+		traces <- ctx.Value(constant.CtxTraceID).(string)
+		return nil
+	}
+	var exportErr error
+	require.NoError(t, m.RegisterTask(&funcTask{key: "export", run: func(ctx context.Context) error {
+		traces <- ctx.Value(constant.CtxTraceID).(string)
+		return exportErr
+	}}))
+	require.NoError(t, m.RegisterTask(&funcTask{key: "sync", run: record}))
+	require.NoError(t, m.RegisterTask(&dependentTask{funcTask: funcTask{key: "aggregate", run: record},
+		deps: Dependencies{Upstreams: []string{"export", "sync"}, Window: time.Hour}}))
+	require.NoError(t, m.RegisterTask(&dependentTask{funcTask: funcTask{key: "report", run: record},
+		deps: Dependencies{Upstreams: []string{"aggregate"}}}))
+	ctx := context.Background()
+	require.NoError(t, m.Start(ctx))
+	defer m.Stop(ctx)
+
+	// 声明了上游的任务不按 spec 调度
+	status, _ := m.TaskStatus("aggregate")
+	assert.True(t, status.NextRun.IsZero())
+	assert.Equal(t, []string{"export", "sync"}, status.DependsOn)
+	assert.Error(t, m.RemoveTask("aggregate"), "task with downstreams cannot be removed")
+
+	// 只有一个上游成功时不触发
+	require.NoError(t, m.RunTask(ctx, "export"))
+	<-traces
+	select {
+	case <-traces:
+		t.Fatal("aggregate must wait for all upstreams")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 所有上游成功后依次触发，整条链路共享最后一个上游的 trace ID
+	require.NoError(t, m.RunTask(ctx, "sync"))
+	trace := <-traces
+	for i := 0; i < 2; i++ {
+		select {
+		case got := <-traces:
+			assert.Equal(t, trace, got)
+		case <-time.After(2 * time.Second):
+			t.Fatal("downstream task not triggered")
+		}
+	}
+	assert.Eventually(t, func() bool {
+		status, _ := m.TaskStatus("report")
+		return status.TotalRuns == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// 上游失败时下游不执行，已记录的该上游的成功被清除
+	require.NoError(t, m.RunTask(ctx, "export"))
+	<-traces
+	exportErr = assert.AnError
+	assert.Error(t, m.RunTask(ctx, "export"))
+	<-traces
+	require.NoError(t, m.RunTask(ctx, "sync"))
+	<-traces
+	select {
+	case <-traces:
+		t.Fatal("aggregate must not run after export failed")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestCheckDependencies(t *testing.T) {
+	assert.NoError(t, checkDependencies(map[string][]string{"a": nil, "b": {"a"}, "c": {"a", "b"}}))
+	assert.EqualError(t, checkDependencies(map[string][]string{"a": {"c"}, "b": {"a"}, "c": {"b"}, "d": nil}),
+		"cron: circular dependency detected involving tasks: [a b c]")
+	assert.EqualError(t, checkDependencies(map[string][]string{"a": {"x"}}), "cron: task a depends on unregistered task x")
+
+	m := NewManager()
+	require.NoError(t, m.RegisterTask(&dependentTask{funcTask: funcTask{key: "a"}, deps: Dependencies{Upstreams: []string{"b"}}}))
+	require.NoError(t, m.RegisterTask(&dependentTask{funcTask: funcTask{key: "b"}, deps: Dependencies{Upstreams: []string{"a"}}}))
+	assert.Error(t, m.Start(context.Background()))
+	assert.False(t, m.IsRunning())
+
+	// 热更新引入循环依赖时拒绝
+	m = NewManager()
+	require.NoError(t, m.RegisterTask(NewConfigurableTask(&BaseTask{}, TaskConfig{Key: "x", Spec: "0 0 1 * * *", Enabled: true})))
+	require.NoError(t, m.RegisterTask(NewConfigurableTask(&BaseTask{}, TaskConfig{Key: "y", Enabled: true, DependsOn: []string{"x"}})))
+	require.NoError(t, m.Start(context.Background()))
+	defer m.Stop(context.Background())
+	assert.Error(t, m.ApplyConfig([]TaskConfig{{Key: "x", Enabled: true, DependsOn: []string{"y"}}}))
+	status, _ := m.TaskStatus("x")
+	assert.Empty(t, status.DependsOn)
+}
+
+type overlapTask struct {
+	funcTask
+	overlap OverlapPolicy
+}
+
+func (t *overlapTask) OverlapPolicy() OverlapPolicy { return t.overlap }
+
+func TestManager_OverlapPolicy(t *testing.T) {
+	m := NewManager()
+	started := make(chan struct{}, 8)
+	release := make(chan struct{})
+	run := func(ctx context.Context) error {
+		started <- struct{}{}
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	dispatch := func(task Task) chan error {
+		done := make(chan error, 1)
+		go func() { done <- m.dispatch(newRunContext(), task.Key(), task) }()
+		return done
+	}
+
+	// skip：上一次未结束时跳过并计数
+	skip := &funcTask{key: "skip", run: run}
+	require.NoError(t, m.RegisterTask(skip))
+	first := dispatch(skip)
+	<-started
+	assert.NoError(t, m.dispatch(newRunContext(), "skip", skip))
+	status, _ := m.TaskStatus("skip")
+	assert.Equal(t, int64(1), status.TotalSkipped)
+	release <- struct{}{}
+	require.NoError(t, <-first)
+
+	// delay：排队等待上一次执行结束
+	delay := &overlapTask{funcTask: funcTask{key: "delay", run: run}, overlap: OverlapPolicy{Mode: OverlapDelay}}
+	require.NoError(t, m.RegisterTask(delay))
+	first, second := dispatch(delay), dispatch(delay)
+	<-started
+	select {
+	case <-started:
+		t.Fatal("delayed run must wait for the previous one")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release <- struct{}{}
+	<-started
+	release <- struct{}{}
+	require.NoError(t, <-first)
+	require.NoError(t, <-second)
+
+	// allow：最多 MaxConcurrent 个实例同时执行
+	allow := &overlapTask{funcTask: funcTask{key: "allow", run: run}, overlap: OverlapPolicy{Mode: OverlapAllow, MaxConcurrent: 2}}
+	require.NoError(t, m.RegisterTask(allow))
+	first, second = dispatch(allow), dispatch(allow)
+	<-started
+	<-started
+	assert.NoError(t, m.dispatch(newRunContext(), "allow", allow))
+	status, _ = m.TaskStatus("allow")
+	assert.Equal(t, 2, status.Running)
+	assert.Equal(t, int64(1), status.TotalSkipped)
+	close(release)
+	require.NoError(t, <-first)
+	require.NoError(t, <-second)
+
+	// cancel_previous：取消正在执行的实例
+	cancelTask := &overlapTask{funcTask: funcTask{key: "cancel", run: func(ctx context.Context) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	}}, overlap: OverlapPolicy{Mode: OverlapCancelPrevious}}
+	require.NoError(t, m.RegisterTask(cancelTask))
+	first = dispatch(cancelTask)
+	<-started
+	second = dispatch(cancelTask)
+	assert.ErrorIs(t, <-first, context.Canceled)
+	<-started
+	status, _ = m.TaskStatus("cancel")
+	assert.Equal(t, 1, status.Running)
+	m.stateMu.Lock()
+	for _, c := range m.states["cancel"].cancels {
+		c()
+	}
+	m.stateMu.Unlock()
+	assert.ErrorIs(t, <-second, context.Canceled)
+}
+
+func TestManager_DistributedOverlap(t *testing.T) {
+	m := newDistributedManager(t, NewMemoryLocker(), "node-1")
+
+	// lock: run 的租约按任务互斥，allow 的并发实例无法执行
+	allow := OverlapPolicy{Mode: OverlapAllow, MaxConcurrent: 2}
+	assert.Error(t, m.RegisterTask(&overlapTask{funcTask: funcTask{key: "allow"}, overlap: allow}))
+	assert.Error(t, m.RegisterTask(&overlapTask{funcTask: funcTask{key: "unlimited"}, overlap: OverlapPolicy{Mode: OverlapAllow}}))
+	assert.NoError(t, m.RegisterTask(&overlapTask{funcTask: funcTask{key: "single"}, overlap: OverlapPolicy{Mode: OverlapAllow, MaxConcurrent: 1}}))
+	assert.NoError(t, m.RegisterTask(&overlapTask{funcTask: funcTask{key: "none", mode: LockModeNone}, overlap: allow}))
+
+	require.NoError(t, m.RegisterTask(NewConfigurableTask(&funcTask{}, TaskConfig{Key: "export", Spec: "0 0 1 * * *", Enabled: true})))
+	assert.Error(t, m.ApplyConfig([]TaskConfig{{Key: "export", Spec: "0 0 1 * * *", Enabled: true, Overlap: "allow", MaxConcurrent: 2}}))
+	assert.NoError(t, m.ApplyConfig([]TaskConfig{{Key: "export", Spec: "0 0 1 * * *", Enabled: true, Lock: "leader", Overlap: "allow", MaxConcurrent: 2}}))
+
+	// 租约被本节点仍在执行的实例占用时计为跳过
+	started := make(chan struct{})
+	release := make(chan struct{})
+	task := &overlapTask{funcTask: funcTask{key: "import", run: func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}}}
+	require.NoError(t, m.RegisterTask(task))
+	task.overlap = OverlapPolicy{Mode: OverlapAllow}
+	done := make(chan error, 1)
+	go func() { done <- m.dispatch(newRunContext(), "import", task) }()
+	<-started
+	assert.NoError(t, m.dispatch(newRunContext(), "import", task))
+	status, _ := m.TaskStatus("import")
+	assert.Equal(t, int64(1), status.TotalSkipped)
+	close(release)
+	require.NoError(t, <-done)
+
+	// 租约被其他节点持有时不计为跳过
+	other := newDistributedManager(t, m.dist.locker, "node-2")
+	require.NoError(t, other.RegisterTask(&funcTask{key: "import"}))
+	assert.NoError(t, other.dispatch(newRunContext(), "import", task))
+	status, _ = other.TaskStatus("import")
+	assert.Equal(t, int64(0), status.TotalSkipped)
+}
+
+func TestManager_MaxConcurrent(t *testing.T) {
+	m, err := NewManagerWithConfig(ManagerConfig{MaxConcurrent: 1})
+	require.NoError(t, err)
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	newTask := func(key string) *funcTask {
+		return &funcTask{key: key, run: func(ctx context.Context) error {
+			started <- key
+			<-release
+			return nil
+		}}
+	}
+	a, b := newTask("a"), newTask("b")
+	require.NoError(t, m.RegisterTask(a))
+	require.NoError(t, m.RegisterTask(b))
+
+	done := make(chan error, 2)
+	go func() { done <- m.dispatch(newRunContext(), "a", a) }()
+	assert.Equal(t, "a", <-started)
+	go func() { done <- m.dispatch(newRunContext(), "b", b) }()
+	select {
+	case <-started:
+		t.Fatal("b must wait for a free slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	assert.Equal(t, "b", <-started)
+	require.NoError(t, <-done)
+	require.NoError(t, <-done)
+	status, _ := m.TaskStatus("b")
+	assert.Equal(t, int64(0), status.TotalSkipped, "waiting for a slot is not a skip")
+}
+
+func TestConfigurableTask_OverlapPolicy(t *testing.T) {
+	task := NewConfigurableTask(&overlapTask{overlap: OverlapPolicy{Mode: OverlapDelay}}, TaskConfig{Key: "a"})
+	assert.Equal(t, OverlapPolicy{Mode: OverlapDelay}, task.OverlapPolicy())
+	task.SetConfig(TaskConfig{Overlap: "allow", MaxConcurrent: 3})
+	assert.Equal(t, OverlapPolicy{Mode: OverlapAllow, MaxConcurrent: 3}, task.OverlapPolicy())
+	assert.Equal(t, OverlapPolicy{Mode: OverlapSkip}, overlapPolicyOf(&funcTask{}))
+
+	m := NewManager()
+	require.NoError(t, m.RegisterTask(task))
+	assert.Error(t, m.ApplyConfig([]TaskConfig{{Key: "a", Overlap: "parallel"}}))
+}
+
+func TestParseSpec(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	now := time.Date(2026, 1, 2, 20, 10, 0, 0, time.UTC) // 上海时间 01-03 04:10
+
+	sched, err := parseSpec("CRON_TZ=Asia/Shanghai 0 0 2 * * *", time.Local)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 4, 2, 0, 0, 0, shanghai).Unix(), sched.Next(now).Unix())
+
+	sched, err = parseSpec("@every 15m", time.Local)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 2, 20, 15, 0, 0, time.UTC), sched.Next(now))
+
+	sched, err = parseSpec("CRON_TZ=Asia/Shanghai @at 2026-01-03 08:00:00", time.Local)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 3, 8, 0, 0, 0, shanghai).Unix(), sched.Next(now).Unix())
+	assert.True(t, sched.Next(now).IsZero(), "one-shot schedule fires only once")
+
+	// 未指定时区时使用管理器的默认时区
+	sched, err = parseSpec("@at 2026-01-03 08:00:00", shanghai)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 3, 8, 0, 0, 0, shanghai).Unix(), sched.Next(now).Unix())
+
+	sched, err = parseSpec("@at 2026-01-01T00:00:00Z", time.Local)
+	require.NoError(t, err)
+	assert.Equal(t, now, sched.Next(now), "past time runs immediately")
+
+	for _, spec := range []string{"CRON_TZ=Mars/Olympus 0 0 2 * * *", "CRON_TZ=UTC", "@at tomorrow", "0 0 2 * *"} {
+		_, err := parseSpec(spec, time.Local)
+		assert.Error(t, err, spec)
+	}
+
+	assert.Equal(t, "CRON_TZ=UTC 0 0 2 * * *", withTimezone("0 0 2 * * *", "UTC"))
+	assert.Equal(t, "TZ=UTC 0 0 2 * * *", withTimezone("TZ=UTC 0 0 2 * * *", "Asia/Shanghai"))
+}
+
+func TestManager_Timezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	m, err := NewManagerWithConfig(ManagerConfig{Location: tokyo})
+	require.NoError(t, err)
+	task := NewConfigurableTask(&BaseTask{}, TaskConfig{Key: "report", Spec: "0 0 2 * * *", Enabled: true, Timezone: "America/New_York", Jitter: "30s"})
+	require.NoError(t, m.RegisterTask(task))
+	require.NoError(t, m.RegisterTask(&funcTask{key: "local"}))
+	assert.Equal(t, "CRON_TZ=America/New_York 0 0 2 * * *", task.Spec())
+	assert.Equal(t, 30*time.Second, task.Jitter())
+
+	ctx := context.Background()
+	require.NoError(t, m.Start(ctx))
+	defer m.Stop(ctx)
+
+	newYork, _ := time.LoadLocation("America/New_York")
+	status, _ := m.TaskStatus("report")
+	assert.Equal(t, 2, status.NextRun.In(newYork).Hour())
+	status, _ = m.TaskStatus("local")
+	assert.Equal(t, 0, status.NextRun.In(tokyo).Hour(), "ManagerConfig.Location is the default zone")
+
+	assert.Error(t, m.ApplyConfig([]TaskConfig{{Key: "report", Spec: "0 0 2 * * *", Enabled: true, Timezone: "Nowhere"}}))
+	require.NoError(t, m.ApplyConfig([]TaskConfig{{Key: "report", Spec: "0 0 2 * * *", Enabled: true, Timezone: "Asia/Tokyo"}}))
+	status, _ = m.TaskStatus("report")
+	assert.Equal(t, 2, status.NextRun.In(tokyo).Hour())
+
+	// 未指定时区的 @at 同样使用 ManagerConfig.Location
+	require.NoError(t, m.RescheduleTask("local", "@at 2099-01-03 08:00:00"))
+	status, _ = m.TaskStatus("local")
+	assert.Equal(t, time.Date(2099, 1, 3, 8, 0, 0, 0, tokyo).Unix(), status.NextRun.Unix())
+}
+
+type jitterTask struct {
+	funcTask
+	jitter time.Duration
+}
+
+func (t *jitterTask) Jitter() time.Duration { return t.jitter }
+
+func TestManager_Jitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		d := randomDelay(time.Second)
+		assert.True(t, d >= 0 && d < time.Second)
+	}
+	assert.Zero(t, randomDelay(0))
+
+	// 随机延迟期间任务被取消时不执行
+	m := NewManager()
+	ran := make(chan struct{}, 1)
+	task := &jitterTask{funcTask: funcTask{key: "jitter", run: func(ctx context.Context) error {
+		ran <- struct{}{}
+		return nil
+	}}, jitter: time.Hour}
+	require.NoError(t, m.RegisterTask(task))
+	ctx, cancel := context.WithCancel(newRunContext())
+	cancel()
+	assert.NoError(t, m.dispatch(ctx, "jitter", task))
+	assert.Empty(t, ran)
+}
+
+func TestManager_RunAt(t *testing.T) {
+	m := NewManager()
+	ran := make(chan string, 2)
+	newTask := func(key string) *funcTask {
+		return &funcTask{key: key, run: func(ctx context.Context) error {
+			ran <- key
+			return nil
+		}}
+	}
+	// 启动前注册，at 已过时在启动后立即执行
+	require.NoError(t, m.RunAt(time.Now().Add(-time.Minute), newTask("past")))
+	assert.Error(t, m.RunAt(time.Now(), newTask("past")))
+	ctx := context.Background()
+	require.NoError(t, m.Start(ctx))
+	defer m.Stop(ctx)
+
+	at := time.Now().Add(time.Second).Truncate(time.Second).Add(time.Second)
+	require.NoError(t, m.RunAt(at, newTask("later")))
+	status, _ := m.TaskStatus("later")
+	assert.Equal(t, at.Unix(), status.NextRun.Unix())
+	assert.Equal(t, "@at "+at.Format(time.RFC3339), status.Spec)
+
+	require.NoError(t, m.RunAt(time.Now().Add(time.Hour), newTask("cancelled")))
+	require.NoError(t, m.RemoveTask("cancelled"))
+
+	assert.Equal(t, "past", <-ran)
+	select {
+	case name := <-ran:
+		assert.Equal(t, "later", name)
+	case <-time.After(5 * time.Second):
+		t.Fatal("one-shot task not executed")
+	}
+	// 执行后自动移除
+	assert.Eventually(t, func() bool {
+		return len(m.GetTaskNames()) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+type afterRunTask struct {
+	funcTask
+	after func(ctx context.Context) error
+}
+
+func (t *afterRunTask) AfterRun(ctx context.Context) error { return t.after(ctx) }
+
+func TestManager_Stop(t *testing.T) {
+	m := NewManager()
+	ctx := context.Background()
+	require.NoError(t, m.Start(ctx))
+	assert.NoError(t, m.Stop(ctx), "nothing to interrupt")
+
+	started := make(chan struct{}, 2)
+	var causes []error
+	var mu sync.Mutex
+	afterDone := make(chan struct{})
+	scheduled := &afterRunTask{funcTask: funcTask{key: "scheduled", run: func(ctx context.Context) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		mu.Lock()
+		causes = append(causes, context.Cause(ctx))
+		mu.Unlock()
+		return context.Cause(ctx)
+	}}, after: func(ctx context.Context) error {
+		// AfterRun 使用不会被取消的上下文，Stop 等待其完成
+		time.Sleep(50 * time.Millisecond)
+		close(afterDone)
+		return nil
+	}}
+	manual := &funcTask{key: "manual", run: func(ctx context.Context) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		return context.Cause(ctx)
+	}}
+	require.NoError(t, m.RegisterTask(scheduled))
+	require.NoError(t, m.RegisterTask(manual))
+	require.NoError(t, m.Start(ctx))
+
+	results := make(chan error, 2)
+	go func() { results <- m.dispatch(newRunContext(), "scheduled", scheduled) }()
+	go func() { results <- m.RunTask(ctx, "manual") }()
+	<-started
+	<-started
+
+	stopCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	err := m.Stop(stopCtx)
+	var stopErr *StopError
+	require.ErrorAs(t, err, &stopErr)
+	assert.Equal(t, []string{"manual", "scheduled"}, stopErr.Interrupted)
+	assert.Empty(t, stopErr.Unfinished)
+	assert.NoError(t, stopErr.Err)
+	select {
+	case <-afterDone:
+	default:
+		t.Fatal("Stop must wait for AfterRun")
+	}
+	assert.Equal(t, []error{ErrManagerStopped}, causes)
+	for i := 0; i < 2; i++ {
+		assert.ErrorIs(t, <-results, ErrManagerStopped)
+	}
+	status, _ := m.TaskStatus("scheduled")
+	assert.Equal(t, 0, status.Running)
+	assert.Equal(t, ErrManagerStopped.Error(), status.LastError)
+	assert.False(t, m.IsRunning())
+}
+
+func TestManager_StopDeadline(t *testing.T) {
+	m := NewManager()
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	// 不响应 ctx 的任务在截止时间前无法退出
+	task := &funcTask{key: "stubborn", run: func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}}
+	require.NoError(t, m.RegisterTask(task))
+	ctx := context.Background()
+	require.NoError(t, m.Start(ctx))
+	go func() { _ = m.dispatch(newRunContext(), "stubborn", task) }()
+	<-started
+
+	stopCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	err := m.Stop(stopCtx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	var stopErr *StopError
+	require.ErrorAs(t, err, &stopErr)
+	assert.Equal(t, []string{"stubborn"}, stopErr.Unfinished)
+	assert.Contains(t, err.Error(), "not finished before stop deadline")
+}
+
+func TestManager_ReloadDependencies(t *testing.T) {
+	key := uniqueConfigKey("cron_reload_deps_test")
+	m, err := NewManagerWithConfig(ManagerConfig{ConfigKey: key})
+	require.NoError(t, err)
+	exportRuns, reportRuns := make(chan struct{}, 4), make(chan struct{}, 4)
+	require.NoError(t, m.RegisterTask(NewConfigurableTask(&funcTask{key: "export", run: func(ctx context.Context) error {
+		exportRuns <- struct{}{}
+		return nil
+	}}, TaskConfig{Key: "export", Spec: "0 0 2 * * *", Enabled: true})))
+	report := NewConfigurableTask(&funcTask{key: "report", run: func(ctx context.Context) error {
+		reportRuns <- struct{}{}
+		return nil
+	}}, TaskConfig{Key: "report", Spec: "0 0 3 * * *", Enabled: true})
+	require.NoError(t, m.RegisterTask(report))
+	// 代码中声明了依赖的任务
+	audit := NewConfigurableTask(&dependentTask{funcTask: funcTask{key: "audit"}, deps: Dependencies{Upstreams: []string{"export"}}},
+		TaskConfig{Key: "audit", Spec: "0 0 4 * * *", Enabled: true})
+	require.NoError(t, m.RegisterTask(audit))
+	ctx := context.Background()
+	require.NoError(t, m.Start(ctx))
+	defer m.Stop(ctx)
+
+	status, _ := m.TaskStatus("report")
+	assert.False(t, status.NextRun.IsZero())
+	status, _ = m.TaskStatus("audit")
+	assert.True(t, status.NextRun.IsZero())
+
+	// 新增依赖：移出调度，只由上游触发一次
+	v := viper.New()
+	v.Set(key+".tasks", []interface{}{
+		map[string]interface{}{"key": "report", "spec": "0 0 3 * * *", "enabled": true, "depends_on": []interface{}{"export"}},
+		map[string]interface{}{"key": "audit", "spec": "0 0 4 * * *", "enabled": true, "depends_on": []interface{}{}},
+	})
+	require.NoError(t, m.Reload(v))
+	status, _ = m.TaskStatus("report")
+	assert.True(t, status.NextRun.IsZero())
+	assert.Equal(t, []string{"export"}, status.DependsOn)
+	// 配置为空列表时清除代码中声明的依赖，恢复按 spec 调度
+	status, _ = m.TaskStatus("audit")
+	assert.False(t, status.NextRun.IsZero())
+	assert.Empty(t, status.DependsOn)
+
+	require.NoError(t, m.RunTask(ctx, "export"))
+	<-exportRuns
+	select {
+	case <-reportRuns:
+	case <-time.After(2 * time.Second):
+		t.Fatal("report not triggered by export")
+	}
+
+	// 删除依赖：恢复按 spec 调度
+	v.Set(key+".tasks", []interface{}{
+		map[string]interface{}{"key": "report", "spec": "0 0 3 * * *", "enabled": true},
+	})
+	require.NoError(t, m.Reload(v))
+	status, _ = m.TaskStatus("report")
+	assert.False(t, status.NextRun.IsZero())
+	assert.Empty(t, status.DependsOn)
+	require.NoError(t, m.RunTask(ctx, "export"))
+	<-exportRuns
+	select {
+	case <-reportRuns:
+		t.Fatal("report must not be triggered after depends_on is removed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// audit 恢复代码中声明的依赖后，引入循环依赖的配置不生效
+	v.Set(key+".tasks", []interface{}{
+		map[string]interface{}{"key": "audit", "spec": "0 0 4 * * *", "enabled": true},
+		map[string]interface{}{"key": "export", "spec": "0 0 2 * * *", "enabled": true, "depends_on": []interface{}{"audit"}},
+	})
+	assert.Error(t, m.Reload(v))
+	status, _ = m.TaskStatus("audit")
+	assert.Equal(t, []string{"export"}, status.DependsOn)
+	status, _ = m.TaskStatus("export")
+	assert.Empty(t, status.DependsOn)
+}
+
+func TestManager_StopBlocksDownstream(t *testing.T) {
+	m := NewManager()
+	ran := make(chan struct{}, 1)
+	upstream := &funcTask{key: "upstream"}
+	downstream := &dependentTask{funcTask: funcTask{key: "downstream", run: func(ctx context.Context) error {
+		ran <- struct{}{}
+		return nil
+	}}, deps: Dependencies{Upstreams: []string{"upstream"}}}
+	require.NoError(t, m.RegisterTask(upstream))
+	require.NoError(t, m.RegisterTask(downstream))
+	ctx := context.Background()
+	require.NoError(t, m.Start(ctx))
+	require.NoError(t, m.Stop(ctx))
+
+	// 上游在 Stop 期间执行成功：停止后不再触发下游，也不再执行新的调度
+	m.triggerDownstream(newRunContext(), "upstream", nil)
+	assert.NoError(t, m.dispatch(newRunContext(), "downstream", downstream))
+	assert.ErrorIs(t, m.RunTask(ctx, "downstream"), ErrManagerStopped)
+	select {
+	case <-ran:
+		t.Fatal("downstream must not run after Stop")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 重新 Start 后恢复
+	require.NoError(t, m.Start(ctx))
+	defer m.Stop(ctx)
+	m.triggerDownstream(newRunContext(), "upstream", nil)
+	select {
+	case <-ran:
+	case <-time.After(2 * time.Second):
+		t.Fatal("downstream not triggered after restart")
+	}
+}
+
+func TestManager_RemoveRunningTask(t *testing.T) {
+	m := NewManager()
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	task := &funcTask{key: "removed", run: func(ctx context.Context) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}}
+	require.NoError(t, m.RegisterTask(task))
+	done := make(chan error, 1)
+	go func() { done <- m.dispatch(newRunContext(), "removed", task) }()
+	<-started
+
+	// 执行中移除：状态保留到实例结束，执行中计数不会变为负数，并发名额正常归还
+	require.NoError(t, m.RemoveTask("removed"))
+	_, ok := m.TaskStatus("removed")
+	assert.False(t, ok)
+	close(release)
+	require.NoError(t, <-done)
+	assert.Equal(t, float64(0), testutil.ToFloat64(TaskRunning.WithLabelValues("removed")))
+	m.stateMu.Lock()
+	_, exists := m.states["removed"]
+	m.stateMu.Unlock()
+	assert.False(t, exists, "state is dropped after the last run finished")
+
+	require.NoError(t, m.RegisterTask(task))
+	require.NoError(t, m.dispatch(newRunContext(), "removed", task))
+	<-started
+	status, _ := m.TaskStatus("removed")
+	assert.Equal(t, 0, status.Running)
+	assert.Equal(t, int64(1), status.TotalRuns)
+	assert.Zero(t, status.TotalSkipped)
+}
+
+func TestNewManagerWithConfig_DuplicateConfigKey(t *testing.T) {
+	key := uniqueConfigKey("cron_duplicate_test")
+	_, err := NewManagerWithConfig(ManagerConfig{ConfigKey: key})
+	require.NoError(t, err)
+	_, err = NewManagerWithConfig(ManagerConfig{ConfigKey: key})
+	assert.ErrorContains(t, err, "already registered")
+}
+
+func TestManager_LeaderLostCancelsRun(t *testing.T) {
+	locker := NewMemoryLocker()
+	m := newDistributedManager(t, locker, "node-1")
+	ctx := context.Background()
+	require.NoError(t, m.Start(ctx))
+	defer m.Stop(ctx)
+	require.True(t, m.IsLeader())
+	assert.Equal(t, "cron:lock:{leader}:election", m.dist.leaderKey())
+
+	started := make(chan struct{})
+	task := &funcTask{key: "report", mode: LockModeLeader, run: func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	done := make(chan error, 1)
+	go func() { done <- m.dispatch(newRunContext(), "report", task) }()
+	<-started
+
+	// leader 租约过期被他人占用后续约失败，正在执行的 leader 模式任务被取消
+	locker.mu.Lock()
+	delete(locker.leases, m.dist.leaderKey())
+	locker.mu.Unlock()
+	_, err := locker.Acquire(ctx, m.dist.leaderKey(), "node-2", time.Minute)
+	require.NoError(t, err)
+	m.dist.campaign(ctx)
+	assert.False(t, m.IsLeader())
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(2 * time.Second):
+		t.Fatal("leader-only run not cancelled after losing leadership")
+	}
+}
+
+type timeoutTask struct {
+	funcTask
+	timeout time.Duration
+}
+
+func (t *timeoutTask) Timeout() time.Duration { return t.timeout }
+
+func TestManager_RetryAfterTimeout(t *testing.T) {
+	m := NewManager()
+	var running, maxRunning, attempts int32
+	task := &timeoutTask{funcTask: funcTask{key: "slow", retry: RetryPolicy{MaxRetries: 1, Backoff: time.Millisecond}, run: func(ctx context.Context) error {
+		atomic.AddInt32(&attempts, 1)
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			old := atomic.LoadInt32(&maxRunning)
+			if n <= old || atomic.CompareAndSwapInt32(&maxRunning, old, n) {
+				break
+			}
+		}
+		// 不响应 ctx，超时后仍继续执行
+		time.Sleep(150 * time.Millisecond)
+		return nil
+	}}, timeout: 20 * time.Millisecond}
+	require.NoError(t, m.RegisterTask(task))
+
+	err := m.RunTask(context.Background(), "slow")
+	assert.ErrorContains(t, err, "timeout")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxRunning), "retry must wait for the timed-out attempt to exit")
+}
+
+func TestManager_RetryCancelledDuringBackoff(t *testing.T) {
+	m := NewManager()
+	task := &funcTask{key: "flaky", retry: RetryPolicy{MaxRetries: 3, Backoff: time.Hour}, run: func(ctx context.Context) error {
+		return assert.AnError
+	}}
+	require.NoError(t, m.RegisterTask(task))
+
+	// 退避等待期间被取消，没有实际执行的重试不计入次数
+	ctx, cancel := context.WithCancel(newRunContext())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	assert.ErrorIs(t, m.runTask(ctx, "flaky", task), assert.AnError)
+	runs, _ := m.History(context.Background(), "flaky", 1)
+	require.Len(t, runs, 1)
+	assert.Equal(t, 1, runs[0].Attempts)
+}
+
+// plainLocker 只实现 Locker，不支持共享上游成功记录
+type plainLocker struct {
+	Locker
+}
+
+func TestManager_DistributedDependencies(t *testing.T) {
+	locker := NewMemoryLocker()
+	m1 := newDistributedManager(t, locker, "node-1")
+	m2 := newDistributedManager(t, locker, "node-2")
+
+	var reportRuns atomic.Int32
+	for _, m := range []*Manager{m1, m2} {
+		require.NoError(t, m.RegisterTask(&funcTask{key: "export"}))
+		require.NoError(t, m.RegisterTask(&funcTask{key: "sync"}))
+		require.NoError(t, m.RegisterTask(&dependentTask{funcTask: funcTask{key: "report", run: func(ctx context.Context) error {
+			reportRuns.Add(1)
+			return nil
+		}}, deps: Dependencies{Upstreams: []string{"export", "sync"}}}))
+	}
+	ctx := context.Background()
+
+	// 两个上游在不同节点执行成功，下游在整个集群中只触发一次
+	require.NoError(t, m1.RunTask(ctx, "export"))
+	assert.Never(t, func() bool { return reportRuns.Load() > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+	require.NoError(t, m2.RunTask(ctx, "sync"))
+	assert.Eventually(t, func() bool { return reportRuns.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	// 触发后重新计数
+	require.NoError(t, m2.RunTask(ctx, "export"))
+	assert.Never(t, func() bool { return reportRuns.Load() > 1 }, 100*time.Millisecond, 10*time.Millisecond)
+
+	// 分布式模式下有上游的任务只支持 LockModeRun，且 Locker 需实现 UpstreamStore
+	assert.ErrorContains(t, m1.RegisterTask(&dependentTask{funcTask: funcTask{key: "leader_report", mode: LockModeLeader},
+		deps: Dependencies{Upstreams: []string{"export"}}}), "must use lock mode")
+	m3 := newDistributedManager(t, plainLocker{Locker: locker}, "node-3")
+	require.NoError(t, m3.RegisterTask(&funcTask{key: "export"}))
+	assert.ErrorContains(t, m3.RegisterTask(&dependentTask{funcTask: funcTask{key: "report"},
+		deps: Dependencies{Upstreams: []string{"export"}}}), "does not implement UpstreamStore")
+	require.NoError(t, m3.RegisterTask(NewConfigurableTask(&funcTask{key: "audit"}, TaskConfig{Key: "audit", Spec: "0 0 1 * * *", Enabled: true})))
+	assert.ErrorContains(t, m3.ApplyConfig([]TaskConfig{{Key: "audit", Enabled: true, DependsOn: []string{"export"}}}), "does not implement UpstreamStore")
+}
+
+func TestPrevSchedule(t *testing.T) {
+	loc := time.UTC
+	now := time.Date(2026, 3, 10, 10, 30, 0, 0, loc)
+	daily, err := parseSpec("0 0 2 * * *", time.Local)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 10, 2, 0, 0, 0, loc), prevSchedule(daily, now))
+	assert.Equal(t, time.Date(2026, 3, 9, 2, 0, 0, 0, loc), prevSchedule(daily, time.Date(2026, 3, 10, 1, 59, 59, 0, loc)))
+	assert.Equal(t, now, prevSchedule(everySchedule{delay: 30 * time.Minute}, now), "a schedule time equal to now counts")
+
+	leap, err := parseSpec("0 0 0 29 2 *", time.Local)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 2, 29, 0, 0, 0, 0, loc), prevSchedule(leap, now))
+
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, loc), prevSchedule(&onceSchedule{at: time.Date(2026, 3, 1, 0, 0, 0, 0, loc)}, now))
+	assert.True(t, prevSchedule(&onceSchedule{at: now.Add(time.Hour)}, now).IsZero())
+}
+
+func TestManager_DependenciesDefaultWindow(t *testing.T) {
+	m := NewManager()
+	reportRuns := make(chan struct{}, 2)
+	// export 每秒调度一次，sync 每天调度一次
+	require.NoError(t, m.RegisterTask(NewConfigurableTask(&funcTask{key: "export"}, TaskConfig{Key: "export", Spec: "@every 1s", Enabled: true})))
+	require.NoError(t, m.RegisterTask(&funcTask{key: "sync"}))
+	require.NoError(t, m.RegisterTask(&dependentTask{funcTask: funcTask{key: "report", run: func(ctx context.Context) error {
+		reportRuns <- struct{}{}
+		return nil
+	}}, deps: Dependencies{Upstreams: []string{"export", "sync"}}}))
+	ctx := context.Background()
+
+	// export 的成功早于其最近一次调度时刻，属于上一个调度窗口，不与 sync 的成功凑成一轮
+	require.NoError(t, m.RunTask(ctx, "export"))
+	time.Sleep(1100 * time.Millisecond)
+	require.NoError(t, m.RunTask(ctx, "sync"))
+	select {
+	case <-reportRuns:
+		t.Fatal("a success from a previous schedule window must not count")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// export 在本窗口内再次成功后触发
+	require.NoError(t, m.RunTask(ctx, "export"))
+	select {
+	case <-reportRuns:
+	case <-time.After(2 * time.Second):
+		t.Fatal("report not triggered")
+	}
+}
@@ -0,0 +1,54 @@
+package cron
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	TaskRunsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cron_task_runs_total",
+			Help: "Total number of cron task runs",
+		},
+		[]string{"task", "status"},
+	)
+
+	TaskDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cron_task_duration_seconds",
+			Help:    "Histogram of cron task run duration",
+			Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 1800, 3600},
+		},
+		[]string{"task"},
+	)
+
+	TaskRunning = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cron_task_running",
+			Help: "Number of cron task runs in progress",
+		},
+		[]string{"task"},
+	)
+
+	TaskSkippedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cron_task_skipped_total",
+			Help: "Total number of skipped cron task runs caused by overlap policy",
+		},
+		[]string{"task"},
+	)
+
+	TaskConsecutiveFailures = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cron_task_consecutive_failures",
+			Help: "Number of consecutive failed runs of a cron task",
+		},
+		[]string{"task"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(TaskRunsTotal)
+	prometheus.MustRegister(TaskDuration)
+	prometheus.MustRegister(TaskRunning)
+	prometheus.MustRegister(TaskSkippedTotal)
+	prometheus.MustRegister(TaskConsecutiveFailures)
+}
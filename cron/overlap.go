@@ -0,0 +1,163 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jessewkun/gocommon/logger"
+)
+
+// OverlapMode 上一次执行尚未结束时，新一次调度的处理方式
+type OverlapMode string
+
+const (
+	OverlapSkip           OverlapMode = "skip"            // 跳过本次调度，默认值
+	OverlapDelay          OverlapMode = "delay"           // 排队等待，上一次执行结束后再执行
+	OverlapAllow          OverlapMode = "allow"           // 允许并发执行，最多 MaxConcurrent 个实例，超出时跳过
+	OverlapCancelPrevious OverlapMode = "cancel_previous" // 取消正在执行的实例，待其退出后执行
+)
+
+// OverlapPolicy 任务的重叠执行策略，只对调度触发（包括上游触发）的执行生效，RunTask 手动执行不受限制
+type OverlapPolicy struct {
+	Mode          OverlapMode // 默认 skip
+	MaxConcurrent int         // allow 模式下最多同时执行的实例数，0 表示不限制
+}
+
+// OverlapTask 可选接口，任务实现后可声明重叠执行策略
+type OverlapTask interface {
+	OverlapPolicy() OverlapPolicy
+}
+
+func overlapPolicyOf(task Task) OverlapPolicy {
+	if t, ok := task.(OverlapTask); ok {
+		p := t.OverlapPolicy()
+		if p.Mode == "" {
+			p.Mode = OverlapSkip
+		}
+		return p
+	}
+	return OverlapPolicy{Mode: OverlapSkip}
+}
+
+// validOverlapMode 检查配置中的重叠执行策略
+func validOverlapMode(mode string) error {
+	switch OverlapMode(mode) {
+	case "", OverlapSkip, OverlapDelay, OverlapAllow, OverlapCancelPrevious:
+		return nil
+	}
+	return fmt.Errorf("invalid overlap mode %q", mode)
+}
+
+// limit 返回同时执行的实例数上限，0 表示不限制
+func (p OverlapPolicy) limit() int {
+	if p.Mode == OverlapAllow {
+		return p.MaxConcurrent
+	}
+	return 1
+}
+
+// checkDistributedOverlap 分布式模式下 lock 为 run 时租约按任务互斥，同一任务任意时刻只有一个实例持有租约，
+// allow 模式的并发实例会因租约被占用而无法执行，因此只允许 max_concurrent 为 1
+func (m *Manager) checkDistributedOverlap(name string, policy OverlapPolicy, mode LockMode) error {
+	if m.dist == nil || mode != LockModeRun || policy.limit() == 1 {
+		return nil
+	}
+	return fmt.Errorf("cron: task %s uses overlap mode %q with max_concurrent %d, which cannot run concurrently under lock mode %q in distributed mode", name, policy.Mode, policy.MaxConcurrent, LockModeRun)
+}
+
+// enter 按任务的 OverlapPolicy 与 ManagerConfig.MaxConcurrent 判断本次调度能否执行
+// cancel 用于 cancel_previous 模式下被后续调度取消；返回 ok 为 true 时需在执行结束后调用 leave
+func (m *Manager) enter(ctx context.Context, name string, task Task, cancel context.CancelFunc) (leave func(), ok bool) {
+	policy := overlapPolicyOf(task)
+	limit := policy.limit()
+
+	m.stateMu.Lock()
+	s := m.state(name)
+	// 策略热更新后重建信号量，已在执行的实例归还到旧的信号量
+	if limit > 0 && cap(s.slots) != limit {
+		s.slots = make(chan struct{}, limit)
+	}
+	slots := s.slots
+	if limit == 0 {
+		slots = nil
+	}
+	if policy.Mode == OverlapCancelPrevious && len(s.cancels) > 0 {
+		logger.Warn(ctx, "CRON", "Task %s cancelling %d previous run(s): overlap mode %s", name, len(s.cancels), policy.Mode)
+		for _, c := range s.cancels {
+			c()
+		}
+	}
+	if s.cancels == nil {
+		s.cancels = make(map[int64]context.CancelFunc)
+	}
+	s.runSeq++
+	id := s.runSeq
+	s.cancels[id] = cancel
+	m.stateMu.Unlock()
+
+	exit := func() {
+		m.stateMu.Lock()
+		delete(s.cancels, id)
+		m.releaseState(name, s)
+		m.stateMu.Unlock()
+	}
+
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+		default:
+			if policy.Mode == OverlapSkip || policy.Mode == OverlapAllow {
+				exit()
+				m.skipRun(ctx, name, fmt.Sprintf("%d run(s) still in progress, overlap mode %s", limit, policy.Mode))
+				return nil, false
+			}
+			logger.Info(ctx, "CRON", "Task %s waiting for previous run to finish: overlap mode %s", name, policy.Mode)
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				exit()
+				m.skipRun(ctx, name, "cancelled while waiting for previous run")
+				return nil, false
+			}
+		}
+	}
+
+	if m.slots != nil {
+		select {
+		case m.slots <- struct{}{}:
+		default:
+			logger.Warn(ctx, "CRON", "Task %s waiting for a free slot: %d tasks running", name, cap(m.slots))
+			select {
+			case m.slots <- struct{}{}:
+			case <-ctx.Done():
+				if slots != nil {
+					<-slots
+				}
+				exit()
+				m.skipRun(ctx, name, "cancelled while waiting for a free slot")
+				return nil, false
+			}
+		}
+	}
+
+	return func() {
+		if m.slots != nil {
+			<-m.slots
+		}
+		if slots != nil {
+			<-slots
+		}
+		exit()
+	}, true
+}
+
+// skipRun 记录一次被跳过的调度
+func (m *Manager) skipRun(ctx context.Context, name, reason string) {
+	m.stateMu.Lock()
+	s := m.state(name)
+	s.status.TotalSkipped++
+	m.stateMu.Unlock()
+
+	TaskSkippedTotal.WithLabelValues(name).Inc()
+	logger.Warn(ctx, "CRON", "Task %s skipped: %s", name, reason)
+}
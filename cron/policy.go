@@ -0,0 +1,229 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jessewkun/gocommon/alarm"
+	"github.com/jessewkun/gocommon/constant"
+	"github.com/jessewkun/gocommon/logger"
+	"github.com/jessewkun/gocommon/safego"
+	"github.com/robfig/cron/v3"
+)
+
+// RetryPolicy 任务失败后的重试策略
+type RetryPolicy struct {
+	MaxRetries int           // 最大重试次数，0 表示不重试
+	Backoff    time.Duration // 首次重试前的等待时间，之后每次翻倍，默认 1s
+	MaxBackoff time.Duration // 等待时间上限，默认 1m
+}
+
+// AlarmPolicy 任务报警策略，报警通过 alarm.SendAlarm 发送
+type AlarmPolicy struct {
+	FailureThreshold int // 连续失败达到该次数（及其整数倍）时报警，恢复成功时发送恢复通知，0 表示不报警
+	MissedRuns       int // 连续 N 个调度周期内没有成功执行时报警，0 表示不检测，分布式模式下需配置 HistoryStore
+}
+
+// RetryableTask 可选接口，任务实现后可声明重试策略
+type RetryableTask interface {
+	RetryPolicy() RetryPolicy
+}
+
+// AlarmTask 可选接口，任务实现后可声明报警策略
+type AlarmTask interface {
+	AlarmPolicy() AlarmPolicy
+}
+
+// sendAlarm 发送报警，测试时可替换
+var sendAlarm = alarm.SendAlarm
+
+func retryPolicyOf(task Task) RetryPolicy {
+	if t, ok := task.(RetryableTask); ok {
+		return t.RetryPolicy()
+	}
+	return RetryPolicy{}
+}
+
+func alarmPolicyOf(task Task) AlarmPolicy {
+	if t, ok := task.(AlarmTask); ok {
+		return t.AlarmPolicy()
+	}
+	return AlarmPolicy{}
+}
+
+// backoff 返回第 attempt 次失败后的等待时间
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay, maxDelay := p.Backoff, p.MaxBackoff
+	if delay <= 0 {
+		delay = time.Second
+	}
+	if maxDelay <= 0 {
+		maxDelay = time.Minute
+	}
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// checkFailureAlarm 连续失败次数达到阈值时报警，从报警状态恢复时发送恢复通知
+func (m *Manager) checkFailureAlarm(ctx context.Context, name string, task Task, prevFailures, failures int, err error) {
+	threshold := alarmPolicyOf(task).FailureThreshold
+	if threshold <= 0 {
+		return
+	}
+	switch {
+	case err != nil && failures%threshold == 0:
+		m.alarm(ctx, fmt.Sprintf("[CRON] 任务 %s 连续失败 %d 次", name, failures), name, err)
+	case err == nil && prevFailures >= threshold:
+		m.alarm(ctx, fmt.Sprintf("[CRON] 任务 %s 已恢复，此前连续失败 %d 次", name, prevFailures), name, nil)
+	}
+}
+
+// alarm 异步发送报警，避免阻塞任务调度
+func (m *Manager) alarm(ctx context.Context, title, name string, err error) {
+	content := []string{
+		fmt.Sprintf("【DATETIME】: %s", time.Now().Format("2006-01-02 15:04:05")),
+		fmt.Sprintf("【TASK】: %s", name),
+		fmt.Sprintf("【NODE】: %s", m.nodeID()),
+	}
+	if err != nil {
+		content = append(content, fmt.Sprintf("【ERROR】: %v", err))
+	}
+	if traceID := ctx.Value(constant.CtxTraceID); traceID != nil {
+		content = append(content, fmt.Sprintf("【TRACE ID】: %v", traceID))
+	}
+	ctx = context.WithoutCancel(ctx)
+	go safego.SafeGo(ctx, func() {
+		if sendErr := sendAlarm(ctx, title, content); sendErr != nil {
+			logger.Warn(ctx, "CRON", "Failed to send alarm for task %s: %v", name, sendErr)
+		}
+	})
+}
+
+// startWatchdog 启动错过执行检测
+func (m *Manager) startWatchdog(ctx context.Context) {
+	if m.dist != nil && m.cfg.History == nil {
+		for name, task := range m.tasks {
+			if alarmPolicyOf(task).MissedRuns > 0 {
+				logger.Warn(ctx, "CRON", "Missed run detection is disabled in distributed mode without a HistoryStore, task %s will not be checked", name)
+			}
+		}
+	}
+	m.watchStop = make(chan struct{})
+	m.watchDone = make(chan struct{})
+	go func() {
+		defer close(m.watchDone)
+		ticker := time.NewTicker(m.cfg.MissedRunCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.watchStop:
+				return
+			case now := <-ticker.C:
+				safego.SafeGo(ctx, func() { m.checkMissedRuns(ctx, now) })
+			}
+		}
+	}()
+}
+
+// stopWatchdog 停止错过执行检测
+func (m *Manager) stopWatchdog() {
+	if m.watchStop == nil {
+		return
+	}
+	close(m.watchStop)
+	<-m.watchDone
+	m.watchStop = nil
+}
+
+// checkMissedRuns 检查声明了 MissedRuns 的任务，从最近一次成功（或管理器启动）起经过 N 个调度时刻仍未成功时报警
+// 分布式模式下任务可能在其他节点执行，只由 leader 检测，并使用 HistoryStore 中所有节点的执行记录；
+// 未配置 HistoryStore 时 leader 只能看到本节点的执行，不做检测
+func (m *Manager) checkMissedRuns(ctx context.Context, now time.Time) {
+	if !m.IsLeader() || (m.dist != nil && m.cfg.History == nil) {
+		return
+	}
+
+	type scheduled struct {
+		name    string
+		task    Task
+		entryID cron.EntryID
+	}
+	m.mu.RLock()
+	startedAt := m.startedAt
+	list := make([]scheduled, 0, len(m.entries))
+	for name, id := range m.entries {
+		list = append(list, scheduled{name: name, task: m.tasks[name], entryID: id})
+	}
+	m.mu.RUnlock()
+
+	for _, item := range list {
+		missedRuns := alarmPolicyOf(item.task).MissedRuns
+		if missedRuns <= 0 {
+			continue
+		}
+		m.stateMu.Lock()
+		s := m.state(item.name)
+		running, lastSuccess, alarmed := s.status.Running, s.status.LastSuccess, s.missedAlarmed
+		m.stateMu.Unlock()
+		if running > 0 || alarmed {
+			continue
+		}
+		if t, ok := m.lastSuccessFromStore(ctx, item.name); ok && t.After(lastSuccess) {
+			lastSuccess = t
+		}
+
+		ref := lastSuccess
+		if ref.Before(startedAt) {
+			ref = startedAt
+		}
+		entry := m.cron.Entry(item.entryID)
+		if entry.Schedule == nil {
+			continue
+		}
+		// 一次性任务的 Schedule.Next 有状态，不参与检测
+		if _, ok := entry.Schedule.(*onceSchedule); ok {
+			continue
+		}
+		deadline := ref.In(m.cfg.Location)
+		for i := 0; i < missedRuns; i++ {
+			deadline = entry.Schedule.Next(deadline)
+		}
+		// 留出一个检查间隔与随机延迟，避免调度时刻刚到、任务尚未开始时误报
+		if now.Before(deadline.Add(m.cfg.MissedRunCheckInterval + jitterOf(item.task))) {
+			continue
+		}
+
+		m.stateMu.Lock()
+		m.state(item.name).missedAlarmed = true
+		m.stateMu.Unlock()
+		since := "管理器启动"
+		if !lastSuccess.IsZero() && !lastSuccess.Before(startedAt) {
+			since = "最近一次成功"
+		}
+		m.alarm(ctx, fmt.Sprintf("[CRON] 任务 %s 自%s（%s）以来已错过 %d 次调度未成功执行", item.name, since, ref.Format("2006-01-02 15:04:05"), missedRuns), item.name, nil)
+	}
+}
+
+// lastSuccessFromStore 从 HistoryStore 中查找最近一次成功的结束时间
+func (m *Manager) lastSuccessFromStore(ctx context.Context, name string) (time.Time, bool) {
+	if m.cfg.History == nil {
+		return time.Time{}, false
+	}
+	runs, err := m.cfg.History.ListRuns(ctx, name, m.cfg.HistorySize)
+	if err != nil {
+		logger.Warn(ctx, "CRON", "Task %s failed to load run history: %v", name, err)
+		return time.Time{}, false
+	}
+	for _, run := range runs {
+		if run.Success() {
+			return run.StartTime.Add(run.Duration), true
+		}
+	}
+	return time.Time{}, false
+}
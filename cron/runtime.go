@@ -0,0 +1,301 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jessewkun/gocommon/logger"
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/viper"
+)
+
+// specParser 与 NewManager 中 cron.WithSeconds 使用的解析规则一致
+var specParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// specOf 返回任务当前生效的调度表达式，调用方需持有 m.mu
+func (m *Manager) specOf(name string, task Task) string {
+	if spec, ok := m.specs[name]; ok {
+		return spec
+	}
+	return task.Spec()
+}
+
+// schedule 将任务加入调度，已禁用或已暂停的任务跳过，调用方需持有 m.mu
+func (m *Manager) schedule(ctx context.Context, name string, task Task) error {
+	if _, ok := m.entries[name]; ok {
+		return nil
+	}
+	if !task.Enabled() {
+		logger.Info(ctx, "CRON", "Skipping disabled task: %s", name)
+		return nil
+	}
+	if m.paused[name] {
+		logger.Info(ctx, "CRON", "Skipping paused task: %s", name)
+		return nil
+	}
+	if deps := dependenciesOf(task); len(deps.Upstreams) > 0 {
+		logger.Info(ctx, "CRON", "Task %s waits for upstream tasks: %v", name, deps.Upstreams)
+		return nil
+	}
+
+	sched, err := parseSpec(m.specOf(name, task), m.cfg.Location)
+	if err != nil {
+		return fmt.Errorf("failed to add task %s: %w", name, err)
+	}
+	once := m.once[name]
+	m.entries[name] = m.cron.Schedule(sched, cron.FuncJob(func() {
+		runErr := m.dispatch(newRunContext(), name, task)
+		if runErr != nil {
+			logger.ErrorWithMsg(ctx, "CRON", "Task %s failed: %v", name, runErr)
+		}
+		if once {
+			m.removeOnce(name)
+		}
+	}))
+	logger.Info(ctx, "CRON", "Registered task: %s", name)
+	return nil
+}
+
+// unschedule 将任务移出调度，正在执行的实例不受影响，调用方需持有 m.mu
+func (m *Manager) unschedule(name string) {
+	if id, ok := m.entries[name]; ok {
+		m.cron.Remove(id)
+		delete(m.entries, name)
+	}
+}
+
+// unscheduleAll 将所有任务移出调度，调用方需持有 m.mu
+func (m *Manager) unscheduleAll() {
+	for name := range m.entries {
+		m.unschedule(name)
+	}
+}
+
+// reschedule 按任务当前的配置重新调度，调用方需持有 m.mu
+func (m *Manager) reschedule(ctx context.Context, name string) error {
+	task, ok := m.tasks[name]
+	if !ok {
+		return fmt.Errorf("task %s not found", name)
+	}
+	m.unschedule(name)
+	if !m.running {
+		return nil
+	}
+	return m.schedule(ctx, name, task)
+}
+
+// RemoveTask 移除任务，正在执行的实例会继续执行完毕
+func (m *Manager) RemoveTask(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.removeTask(name)
+}
+
+// removeTask 移除任务，调用方需持有 m.mu
+func (m *Manager) removeTask(name string) error {
+	if _, ok := m.tasks[name]; !ok {
+		return fmt.Errorf("task %s not found", name)
+	}
+	if downs := m.downstreamsOf(name); len(downs) > 0 {
+		return fmt.Errorf("task %s is depended on by %v", name, downs)
+	}
+	m.unschedule(name)
+	delete(m.tasks, name)
+	delete(m.paused, name)
+	delete(m.specs, name)
+	delete(m.once, name)
+	// 避免重新注册同名任务时沿用旧的上游成功记录
+	m.resetUpstreams(context.Background(), name)
+
+	m.stateMu.Lock()
+	if s, ok := m.states[name]; ok {
+		// 仍有实例在执行时保留状态，待其结束后再删除，避免 Running 计数与并发名额丢失
+		s.removed = true
+		m.releaseState(name, s)
+	}
+	m.stateMu.Unlock()
+	logger.Info(context.Background(), "CRON", "Removed task: %s", name)
+	return nil
+}
+
+// PauseTask 暂停任务调度，正在执行的实例会继续执行完毕，暂停期间仍可通过 RunTask 手动执行
+func (m *Manager) PauseTask(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tasks[name]; !ok {
+		return fmt.Errorf("task %s not found", name)
+	}
+	m.paused[name] = true
+	m.unschedule(name)
+	logger.Info(context.Background(), "CRON", "Paused task: %s", name)
+	return nil
+}
+
+// ResumeTask 恢复已暂停的任务
+func (m *Manager) ResumeTask(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tasks[name]; !ok {
+		return fmt.Errorf("task %s not found", name)
+	}
+	delete(m.paused, name)
+	logger.Info(context.Background(), "CRON", "Resumed task: %s", name)
+	return m.reschedule(context.Background(), name)
+}
+
+// RescheduleTask 修改任务的调度表达式，优先于 Task.Spec，直到任务被移除或通过配置更新
+func (m *Manager) RescheduleTask(name, spec string) error {
+	if _, err := parseSpec(spec, m.cfg.Location); err != nil {
+		return fmt.Errorf("invalid spec %q for task %s: %w", spec, name, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tasks[name]; !ok {
+		return fmt.Errorf("task %s not found", name)
+	}
+	m.specs[name] = spec
+	logger.Info(context.Background(), "CRON", "Rescheduled task %s: %s", name, spec)
+	return m.reschedule(context.Background(), name)
+}
+
+// RunAt 注册一次性任务，在 at 时刻执行一次后自动移除，at 已过时立即执行
+// 任务 Key 需唯一，执行前可通过 RemoveTask 取消；与普通任务一样受 OverlapPolicy、LockMode 等控制，Task.Spec 不生效
+func (m *Manager) RunAt(at time.Time, task Task) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if task == nil {
+		return fmt.Errorf("task cannot be nil")
+	}
+	key := task.Key()
+	if _, exists := m.tasks[key]; exists {
+		return fmt.Errorf("task %s already registered", key)
+	}
+	// schedule 根据 m.once 决定执行后是否移除，需在注册前设置
+	m.once[key] = true
+	if err := m.register(task, "@at "+at.Format(time.RFC3339)); err != nil {
+		delete(m.once, key)
+		return err
+	}
+	logger.Info(context.Background(), "CRON", "Task %s will run once at %s", key, at.Format(time.DateTime))
+	return nil
+}
+
+// removeOnce 一次性任务执行后将其移除
+func (m *Manager) removeOnce(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.once[name] {
+		return
+	}
+	if err := m.removeTask(name); err != nil {
+		logger.Warn(context.Background(), "CRON", "Failed to remove one-shot task %s: %v", name, err)
+	}
+}
+
+// IsPaused 任务是否已暂停
+func (m *Manager) IsPaused(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.paused[name]
+}
+
+// ApplyConfig 使用新的配置更新已注册的 ConfigurableTask，并按新的 spec、enabled 重新调度
+// timeout 等其他配置在下一次执行时生效；未注册或不是 ConfigurableTask 的任务会被忽略，spec 无效的配置不会生效
+func (m *Manager) ApplyConfig(cfgs []TaskConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ctx := context.Background()
+	var errs []error
+	for _, cfg := range cfgs {
+		task, ok := m.tasks[cfg.Key]
+		if !ok {
+			logger.Warn(ctx, "CRON", "Ignoring config of unregistered task: %s", cfg.Key)
+			continue
+		}
+		ct, ok := task.(*ConfigurableTask)
+		if !ok {
+			logger.Warn(ctx, "CRON", "Ignoring config of task %s: not a ConfigurableTask", cfg.Key)
+			continue
+		}
+		if err := validOverlapMode(cfg.Overlap); err != nil {
+			errs = append(errs, fmt.Errorf("task %s: %w", cfg.Key, err))
+			continue
+		}
+		upstreams := cfg.DependsOn
+		if upstreams == nil {
+			upstreams = dependenciesOf(ct.Task).Upstreams
+		}
+		graph := m.dependencyGraph()
+		graph[cfg.Key] = upstreams
+		if err := checkDependencies(graph); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		mode := LockMode(cfg.Lock)
+		if mode == "" {
+			mode = lockModeOf(ct.Task)
+		}
+		if err := m.checkDistributedDependencies(cfg.Key, upstreams, mode); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		policy := OverlapPolicy{Mode: OverlapMode(cfg.Overlap), MaxConcurrent: cfg.MaxConcurrent}
+		if cfg.Overlap == "" {
+			policy = overlapPolicyOf(ct.Task)
+		}
+		if err := m.checkDistributedOverlap(cfg.Key, policy, mode); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(upstreams) == 0 && cfg.Enabled {
+			if _, err := parseSpec(withTimezone(cfg.Spec, cfg.Timezone), m.cfg.Location); err != nil {
+				errs = append(errs, fmt.Errorf("invalid spec %q for task %s: %w", cfg.Spec, cfg.Key, err))
+				continue
+			}
+		}
+
+		old := ct.Config()
+		ct.SetConfig(cfg)
+		_, overridden := m.specs[cfg.Key]
+		delete(m.specs, cfg.Key)
+		depsChanged := !slices.Equal(old.DependsOn, cfg.DependsOn) || (old.DependsOn == nil) != (cfg.DependsOn == nil) ||
+			old.DependsWindow != cfg.DependsWindow
+		if depsChanged {
+			// 依赖变化后重新计数上游的成功
+			m.resetUpstreams(ctx, cfg.Key)
+		}
+		if old.Spec != cfg.Spec || old.Timezone != cfg.Timezone || old.Enabled != cfg.Enabled || overridden || depsChanged {
+			if err := m.reschedule(ctx, cfg.Key); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			logger.Info(ctx, "CRON", "Task %s config updated, spec: %s, enabled: %v", cfg.Key, cfg.Spec, cfg.Enabled)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// tasksConfig 配置文件中 ManagerConfig.ConfigKey 下的结构
+type tasksConfig struct {
+	Tasks []TaskConfig `mapstructure:"tasks"`
+}
+
+// Reload 实现 config.HotReloadable，配置文件变化时读取 <ConfigKey>.tasks 并调用 ApplyConfig
+func (m *Manager) Reload(v *viper.Viper) error {
+	var cfg tasksConfig
+	if err := v.UnmarshalKey(m.cfg.ConfigKey, &cfg); err != nil {
+		logger.ErrorWithMsg(context.Background(), "CRON", "failed to reload cron config: %v", err)
+		return err
+	}
+	return m.ApplyConfig(cfg.Tasks)
+}
@@ -0,0 +1,112 @@
+package cron
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// JitterTask 可选接口，任务实现后每次调度会在 [0, Jitter) 内随机延迟后再执行，避免多个服务同时启动任务
+type JitterTask interface {
+	Jitter() time.Duration
+}
+
+func jitterOf(task Task) time.Duration {
+	if t, ok := task.(JitterTask); ok {
+		return t.Jitter()
+	}
+	return 0
+}
+
+// randomDelay 返回 [0, max) 内的随机时长
+func randomDelay(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// withTimezone 为调度表达式加上 CRON_TZ 前缀，表达式已指定时区时保持不变
+func withTimezone(spec, tz string) string {
+	if tz == "" || strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		return spec
+	}
+	return "CRON_TZ=" + tz + " " + spec
+}
+
+// parseSpec 解析调度表达式，在 specParser 的基础上：
+//   - @every 按间隔对齐（见 everySchedule），多个节点的调度时刻一致
+//   - 支持 "@at <time>" 只执行一次，时间为 RFC3339 格式，或 "2006-01-02 15:04:05" 格式（使用 CRON_TZ 指定的时区，未指定时为 loc）
+//
+// loc 为管理器的默认时区 ManagerConfig.Location，cron 表达式的默认时区由 cron.WithLocation 决定，不受 loc 影响
+func parseSpec(spec string, loc *time.Location) (cron.Schedule, error) {
+	body := spec
+	if strings.HasPrefix(body, "TZ=") || strings.HasPrefix(body, "CRON_TZ=") {
+		i := strings.Index(body, " ")
+		if i == -1 {
+			return nil, fmt.Errorf("missing spec after timezone")
+		}
+		var err error
+		if loc, err = time.LoadLocation(body[strings.Index(body, "=")+1 : i]); err != nil {
+			return nil, fmt.Errorf("invalid timezone: %w", err)
+		}
+		body = strings.TrimSpace(body[i:])
+	}
+
+	if value, ok := strings.CutPrefix(body, "@at "); ok {
+		value = strings.TrimSpace(value)
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			if at, err = time.ParseInLocation(time.DateTime, value, loc); err != nil {
+				return nil, fmt.Errorf("invalid @at time %q", value)
+			}
+		}
+		return &onceSchedule{at: at}, nil
+	}
+
+	sched, err := specParser.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	if every, ok := sched.(cron.ConstantDelaySchedule); ok {
+		return everySchedule{delay: every.Delay}, nil
+	}
+	return sched, nil
+}
+
+// everySchedule 固定间隔调度，调度时刻对齐到间隔的整数倍
+// 对齐基准为 time.Truncate 使用的 Go 零时刻（公元 1 年 1 月 1 日 UTC），与时区无关；
+// 能整除一天的间隔与按 Unix 纪元对齐的结果相同，其他间隔（如 7m）则不同
+type everySchedule struct {
+	delay time.Duration
+}
+
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Truncate(s.delay).Add(s.delay)
+}
+
+// onceSchedule 只调度一次，时间已过时立即执行
+type onceSchedule struct {
+	at    time.Time
+	mu    sync.Mutex
+	fired bool
+}
+
+// Next 只有第一次调用返回执行时间，之后返回零值，cron 不会再次调度
+// cron 在加入调度与每次执行后各调用一次 Next
+func (s *onceSchedule) Next(t time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fired {
+		return time.Time{}
+	}
+	s.fired = true
+	if s.at.Before(t) {
+		return t
+	}
+	return s.at
+}
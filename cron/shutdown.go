@@ -0,0 +1,124 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrManagerStopped 管理器停止时取消正在执行的任务，任务上下文的 context.Cause 返回该错误
+var ErrManagerStopped = errors.New("cron: manager stopped")
+
+// StopError Stop 时有任务被中断
+type StopError struct {
+	Interrupted []string // Stop 时正在执行、被取消的任务
+	Unfinished  []string // 截止时间前仍未退出（包括 AfterRun）的任务，为 Interrupted 的子集
+	Err         error    // 等待超时时为 ctx.Err()
+}
+
+func (e *StopError) Error() string {
+	if len(e.Unfinished) > 0 {
+		return fmt.Sprintf("cron: interrupted tasks %v, tasks %v not finished before stop deadline: %v", e.Interrupted, e.Unfinished, e.Err)
+	}
+	return fmt.Sprintf("cron: interrupted tasks %v", e.Interrupted)
+}
+
+func (e *StopError) Unwrap() error {
+	return e.Err
+}
+
+// inflight 一次正在进行的执行，wg 覆盖 runOnce 中执行 BeforeRun、Run、AfterRun 的 goroutine
+type inflight struct {
+	name   string
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+	done   chan struct{}
+}
+
+type inflightKey struct{}
+
+// track 记录一次执行，Stop 时取消返回的上下文；untrack 在执行结束后调用
+// 管理器已停止时，返回的上下文立即被取消
+func (m *Manager) track(ctx context.Context, name string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	r := &inflight{name: name, cancel: cancel, done: make(chan struct{})}
+	r.wg.Add(1)
+	ctx = context.WithValue(ctx, inflightKey{}, r)
+
+	m.stateMu.Lock()
+	m.inflightSeq++
+	id := m.inflightSeq
+	m.inflight[id] = r
+	stopped := m.stopped
+	m.stateMu.Unlock()
+	if stopped {
+		cancel(ErrManagerStopped)
+	}
+
+	return ctx, func() {
+		m.stateMu.Lock()
+		delete(m.inflight, id)
+		m.stateMu.Unlock()
+		cancel(nil)
+		r.wg.Done()
+	}
+}
+
+// trackWorker 记录 runOnce 中的执行 goroutine，Stop 等待其结束
+func trackWorker(ctx context.Context) (done func()) {
+	r, ok := ctx.Value(inflightKey{}).(*inflight)
+	if !ok {
+		return func() {}
+	}
+	r.wg.Add(1)
+	return r.wg.Done
+}
+
+// interrupt 将管理器标记为已停止，取消所有正在进行的执行，并等待其结束直到 ctx 截止
+// 标记在下一次 Start 时清除，期间上游触发的下游任务与新的执行都会被立即取消
+func (m *Manager) interrupt(ctx context.Context) error {
+	m.stateMu.Lock()
+	m.stopped = true
+	runs := make([]*inflight, 0, len(m.inflight))
+	for _, r := range m.inflight {
+		runs = append(runs, r)
+	}
+	m.stateMu.Unlock()
+
+	if len(runs) == 0 {
+		return nil
+	}
+	interrupted := make([]string, 0, len(runs))
+	for _, r := range runs {
+		interrupted = append(interrupted, r.name)
+		r.cancel(ErrManagerStopped)
+		go func() {
+			r.wg.Wait()
+			close(r.done)
+		}()
+	}
+	for _, r := range runs {
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+		}
+	}
+
+	var unfinished []string
+	for _, r := range runs {
+		select {
+		case <-r.done:
+		default:
+			unfinished = append(unfinished, r.name)
+		}
+	}
+	sort.Strings(interrupted)
+	sort.Strings(unfinished)
+	err := &StopError{Interrupted: interrupted, Unfinished: unfinished}
+	if len(unfinished) > 0 {
+		err.Err = ctx.Err()
+	}
+	return err
+}
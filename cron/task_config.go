@@ -4,7 +4,23 @@ package cron
 type TaskConfig struct {
 	Key     string `mapstructure:"key"`     // 任务标识
 	Desc    string `mapstructure:"desc"`    // 任务描述
-	Spec    string `mapstructure:"spec"`    // CRON表达式
+	Spec    string `mapstructure:"spec"`    // CRON表达式，也支持 "@every 5m" 与 "@at 2026-01-02 15:04:05"
 	Enabled bool   `mapstructure:"enabled"` // 是否启用
 	Timeout string `mapstructure:"timeout"` // 超时时间，例如 "5m", "1h"
+	Lock    string `mapstructure:"lock"`    // 分布式模式下的执行方式：run（默认）、leader、none
+
+	Timezone string `mapstructure:"timezone"` // 调度使用的时区，例如 "Asia/Shanghai"，为空时使用 ManagerConfig.Location
+	Jitter   string `mapstructure:"jitter"`   // 每次调度随机延迟的上限，例如 "30s"，为空表示不延迟
+
+	Overlap       string `mapstructure:"overlap"`        // 上一次执行未结束时的处理方式：skip（默认）、delay、allow、cancel_previous
+	MaxConcurrent int    `mapstructure:"max_concurrent"` // overlap 为 allow 时最多同时执行的实例数，0 表示不限制
+
+	Retries         int    `mapstructure:"retries"`           // 失败后的最大重试次数
+	RetryBackoff    string `mapstructure:"retry_backoff"`     // 首次重试前的等待时间，之后每次翻倍，默认 "1s"
+	RetryMaxBackoff string `mapstructure:"retry_max_backoff"` // 重试等待时间上限，默认 "1m"
+	AlarmThreshold  int    `mapstructure:"alarm_threshold"`   // 连续失败达到该次数时报警，0 表示不报警
+	MissedRunsAlarm int    `mapstructure:"missed_runs_alarm"` // 连续 N 个调度周期没有成功执行时报警，0 表示不检测
+
+	DependsOn     []string `mapstructure:"depends_on"`     // 上游任务，全部成功后触发本任务，此时 Spec 不生效；配置为 [] 时清除任务代码中声明的依赖
+	DependsWindow string   `mapstructure:"depends_window"` // 上游成功的有效期，例如 "12h"，为空时只计入各上游最近一次调度时刻之后的成功
 }
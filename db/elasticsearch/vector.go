@@ -0,0 +1,410 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/jessewkun/gocommon/llm"
+)
+
+// 向量相似度算法，对应 dense_vector 的 similarity 参数
+const (
+	SimilarityCosine     = "cosine"
+	SimilarityDotProduct = "dot_product"
+	SimilarityL2Norm     = "l2_norm"
+)
+
+// DefaultVectorField 默认的向量字段名
+const DefaultVectorField = "embedding"
+
+// VectorIndexMapping 向量索引的 mapping 定义
+type VectorIndexMapping struct {
+	VectorField string                 // 向量字段名，默认 embedding
+	Dims        int                    // 向量维度，必须与 embedding 模型一致
+	Similarity  string                 // 相似度算法，默认 cosine
+	TextFields  []string               // 需要参与 BM25 检索的 text 字段
+	Analyzer    string                 // TextFields 使用的分词器，为空时使用 ES 默认分词器
+	Properties  map[string]interface{} // 其他字段的 mapping，会与上面的字段合并
+	Settings    map[string]interface{} // 索引 settings，可选
+}
+
+// DenseVectorMapping 返回 dense_vector 字段的 mapping，similarity 为空时使用 cosine
+func DenseVectorMapping(dims int, similarity string) map[string]interface{} {
+	if similarity == "" {
+		similarity = SimilarityCosine
+	}
+	return map[string]interface{}{
+		"type":       "dense_vector",
+		"dims":       dims,
+		"index":      true,
+		"similarity": similarity,
+	}
+}
+
+// Body 生成创建索引所需的请求体
+func (m VectorIndexMapping) Body() map[string]interface{} {
+	properties := make(map[string]interface{}, len(m.Properties)+len(m.TextFields)+1)
+	for k, v := range m.Properties {
+		properties[k] = v
+	}
+	for _, field := range m.TextFields {
+		text := map[string]interface{}{"type": "text"}
+		if m.Analyzer != "" {
+			text["analyzer"] = m.Analyzer
+		}
+		properties[field] = text
+	}
+	field := m.VectorField
+	if field == "" {
+		field = DefaultVectorField
+	}
+	properties[field] = DenseVectorMapping(m.Dims, m.Similarity)
+
+	body := map[string]interface{}{
+		"mappings": map[string]interface{}{"properties": properties},
+	}
+	if len(m.Settings) > 0 {
+		body["settings"] = m.Settings
+	}
+	return body
+}
+
+// CreateVectorIndex 按 VectorIndexMapping 创建索引
+func (c *Client) CreateVectorIndex(ctx context.Context, index string, mapping VectorIndexMapping) error {
+	if mapping.Dims <= 0 {
+		return fmt.Errorf("vector index dims must be positive")
+	}
+	return c.CreateIndex(ctx, index, mapping.Body())
+}
+
+// VectorDocument 待写入的文档
+type VectorDocument struct {
+	ID     string      // 文档 ID，为空时由 ES 生成
+	Text   string      // 用于生成向量的文本
+	Source interface{} // 文档内容，可以是 map 或可被 json.Marshal 为对象的结构体，向量会写入其中的向量字段
+}
+
+// BulkEmbedConfig BulkIndexWithEmbeddings 的配置
+type BulkEmbedConfig struct {
+	Model       string          // embedding 模型名
+	VectorField string          // 向量字段名，默认 embedding
+	BulkSize    int             // 单个 bulk 请求的文档数，默认 500
+	Refresh     string          // bulk 请求的 refresh 参数，如 "true"、"wait_for"
+	Batch       llm.BatchConfig // 向量化的批量配置
+}
+
+// BulkItemError bulk 请求中单个文档的错误
+type BulkItemError struct {
+	ID     string `json:"id"`
+	Status int    `json:"status"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// BulkResult bulk 写入结果
+type BulkResult struct {
+	Indexed int             // 写入成功的文档数
+	Failed  []BulkItemError // 写入失败的文档
+}
+
+// BulkIndexWithEmbeddings 使用 embedder 为文档生成向量，并通过 bulk 接口写入索引
+// 向量化按 llm.BatchEmbed 分批并发执行，任一批次失败时不会写入任何文档
+// 单个文档写入失败不会返回错误，需检查 BulkResult.Failed
+func (c *Client) BulkIndexWithEmbeddings(ctx context.Context, embedder llm.Embedder, index string, docs []VectorDocument, cfg BulkEmbedConfig) (*BulkResult, error) {
+	if cfg.VectorField == "" {
+		cfg.VectorField = DefaultVectorField
+	}
+	if cfg.BulkSize <= 0 {
+		cfg.BulkSize = 500
+	}
+	result := &BulkResult{}
+	if len(docs) == 0 {
+		return result, nil
+	}
+
+	input := make([]string, len(docs))
+	for i, doc := range docs {
+		input[i] = doc.Text
+	}
+	embeddings, err := llm.BatchEmbed(ctx, embedder, &llm.EmbeddingRequest{Model: cfg.Model, Input: input}, cfg.Batch)
+	if err != nil {
+		return nil, fmt.Errorf("create embeddings error: %w", err)
+	}
+
+	for start := 0; start < len(docs); start += cfg.BulkSize {
+		end := start + cfg.BulkSize
+		if end > len(docs) {
+			end = len(docs)
+		}
+		var buf bytes.Buffer
+		for i := start; i < end; i++ {
+			if err := writeBulkItem(&buf, docs[i], cfg.VectorField, embeddings.Data[i].Vector); err != nil {
+				return result, fmt.Errorf("document %d: %w", i, err)
+			}
+		}
+		if err := c.bulk(ctx, index, &buf, cfg.Refresh, result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func writeBulkItem(buf *bytes.Buffer, doc VectorDocument, vectorField string, vector []float32) error {
+	source := make(map[string]interface{})
+	if doc.Source != nil {
+		data, err := json.Marshal(doc.Source)
+		if err != nil {
+			return fmt.Errorf("failed to marshal source: %w", err)
+		}
+		if err := json.Unmarshal(data, &source); err != nil {
+			return fmt.Errorf("source must be a json object: %w", err)
+		}
+	}
+	source[vectorField] = vector
+
+	action := map[string]interface{}{}
+	if doc.ID != "" {
+		action["_id"] = doc.ID
+	}
+	meta, err := json.Marshal(map[string]interface{}{"index": action})
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(source)
+	if err != nil {
+		return fmt.Errorf("failed to marshal source: %w", err)
+	}
+	buf.Write(meta)
+	buf.WriteByte('\n')
+	buf.Write(data)
+	buf.WriteByte('\n')
+	return nil
+}
+
+// bulk 发送 bulk 请求，并将结果累加到 result 中
+func (c *Client) bulk(ctx context.Context, index string, body *bytes.Buffer, refresh string, result *BulkResult) error {
+	opts := []func(*esapi.BulkRequest){
+		c.ES.Bulk.WithContext(ctx),
+		c.ES.Bulk.WithIndex(index),
+	}
+	if refresh != "" {
+		opts = append(opts, c.ES.Bulk.WithRefresh(refresh))
+	}
+	res, err := c.ES.Bulk(body, opts...)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("bulk error: %s", res.Status())
+	}
+
+	var resp struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string `json:"_id"`
+			Status int    `json:"status"`
+			Error  *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	for _, item := range resp.Items {
+		for _, r := range item {
+			if r.Error == nil {
+				result.Indexed++
+				continue
+			}
+			result.Failed = append(result.Failed, BulkItemError{ID: r.ID, Status: r.Status, Type: r.Error.Type, Reason: r.Error.Reason})
+		}
+	}
+	return nil
+}
+
+// KNNQuery kNN 向量检索条件
+type KNNQuery struct {
+	Field         string      // 向量字段名，默认 embedding
+	Vector        []float32   // 查询向量
+	K             int         // 返回的最近邻数量，默认 10
+	NumCandidates int         // 每个分片的候选数量，默认 max(K*10, 100)，上限 10000
+	Filter        interface{} // 过滤条件，为 ES query DSL，可选
+	Similarity    float64     // 最低相似度，0 表示不限制
+	Boost         float64     // 混合检索时向量得分的权重，0 表示使用 ES 默认值 1
+}
+
+// HybridQuery BM25 + 向量的混合检索条件，最终得分为 query 得分与 knn 得分的加权和
+type HybridQuery struct {
+	Query      interface{} // BM25 查询，为 ES query DSL，如 {"match": {"content": "..."}}
+	QueryBoost float64     // BM25 得分的权重，0 表示使用 ES 默认值 1
+	KNN        KNNQuery    // 向量检索条件
+	Size       int         // 返回数量，默认 KNN.K
+}
+
+// SearchOptions 检索结果的通用选项
+type SearchOptions struct {
+	From          int      // 分页偏移量
+	Source        []string // 只返回指定字段，为空时返回除向量字段外的所有字段
+	IncludeVector bool     // 是否在 _source 中返回向量字段
+}
+
+// SearchHit 单条命中结果
+type SearchHit[T any] struct {
+	ID     string  `json:"_id"`
+	Index  string  `json:"_index"`
+	Score  float64 `json:"_score"`
+	Source T       `json:"_source"`
+}
+
+// SearchResult 检索结果
+type SearchResult[T any] struct {
+	Total    int64          // 命中总数
+	MaxScore float64        // 最高得分
+	Took     int64          // 耗时，单位毫秒
+	Hits     []SearchHit[T] // 命中结果，按得分从高到低排列
+}
+
+// KNNSearch 执行 kNN 向量检索，并将命中文档解码为 T
+func KNNSearch[T any](ctx context.Context, c *Client, index string, q KNNQuery, opts SearchOptions) (*SearchResult[T], error) {
+	knn, err := q.body()
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{
+		"knn":  knn,
+		"size": knn["k"],
+	}
+	applySearchOptions(body, q.field(), opts)
+	return searchHits[T](ctx, c, index, body)
+}
+
+// HybridSearch 执行 BM25 + 向量的混合检索，并将命中文档解码为 T
+func HybridSearch[T any](ctx context.Context, c *Client, index string, q HybridQuery, opts SearchOptions) (*SearchResult[T], error) {
+	if q.Query == nil {
+		return nil, fmt.Errorf("hybrid search query cannot be nil")
+	}
+	knn, err := q.KNN.body()
+	if err != nil {
+		return nil, err
+	}
+	query := q.Query
+	if q.QueryBoost > 0 {
+		// 用 bool.should 包一层以便设置 BM25 部分的权重
+		query = map[string]interface{}{
+			"bool": map[string]interface{}{"should": []interface{}{q.Query}, "boost": q.QueryBoost},
+		}
+	}
+	size := q.Size
+	if size <= 0 {
+		size = knn["k"].(int)
+	}
+	body := map[string]interface{}{
+		"query": query,
+		"knn":   knn,
+		"size":  size,
+	}
+	applySearchOptions(body, q.KNN.field(), opts)
+	return searchHits[T](ctx, c, index, body)
+}
+
+// EmbedQuery 使用 embedder 将查询文本转换为向量，用于 KNNQuery.Vector
+func EmbedQuery(ctx context.Context, embedder llm.Embedder, model, text string) ([]float32, error) {
+	resp, err := embedder.CreateEmbeddings(ctx, &llm.EmbeddingRequest{Model: model, Input: []string{text}})
+	if err != nil {
+		return nil, fmt.Errorf("create query embedding error: %w", err)
+	}
+	if len(resp.Data) == 0 {
+		return nil, fmt.Errorf("create query embedding error: empty response")
+	}
+	return resp.Data[0].Vector, nil
+}
+
+func (q KNNQuery) field() string {
+	if q.Field == "" {
+		return DefaultVectorField
+	}
+	return q.Field
+}
+
+func (q KNNQuery) body() (map[string]interface{}, error) {
+	if len(q.Vector) == 0 {
+		return nil, fmt.Errorf("knn query vector cannot be empty")
+	}
+	k := q.K
+	if k <= 0 {
+		k = 10
+	}
+	candidates := q.NumCandidates
+	if candidates <= 0 {
+		candidates = k * 10
+		if candidates < 100 {
+			candidates = 100
+		}
+	}
+	if candidates > 10000 {
+		candidates = 10000
+	}
+	if candidates < k {
+		candidates = k
+	}
+	knn := map[string]interface{}{
+		"field":          q.field(),
+		"query_vector":   q.Vector,
+		"k":              k,
+		"num_candidates": candidates,
+	}
+	if q.Filter != nil {
+		knn["filter"] = q.Filter
+	}
+	if q.Similarity != 0 {
+		knn["similarity"] = q.Similarity
+	}
+	if q.Boost > 0 {
+		knn["boost"] = q.Boost
+	}
+	return knn, nil
+}
+
+func applySearchOptions(body map[string]interface{}, vectorField string, opts SearchOptions) {
+	if opts.From > 0 {
+		body["from"] = opts.From
+	}
+	switch {
+	case len(opts.Source) > 0:
+		body["_source"] = opts.Source
+	case !opts.IncludeVector:
+		body["_source"] = map[string]interface{}{"excludes": []string{vectorField}}
+	}
+}
+
+func searchHits[T any](ctx context.Context, c *Client, index string, body map[string]interface{}) (*SearchResult[T], error) {
+	var resp struct {
+		Took int64 `json:"took"`
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			MaxScore *float64       `json:"max_score"`
+			Hits     []SearchHit[T] `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := c.Search(ctx, index, body, &resp); err != nil {
+		return nil, err
+	}
+	result := &SearchResult[T]{
+		Total: resp.Hits.Total.Value,
+		Took:  resp.Took,
+		Hits:  resp.Hits.Hits,
+	}
+	if resp.Hits.MaxScore != nil {
+		result.MaxScore = *resp.Hits.MaxScore
+	}
+	return result, nil
+}
@@ -0,0 +1,166 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEmbedder 将文本向量化为 [len(text)]
+type fakeEmbedder struct{}
+
+func (fakeEmbedder) Name() string { return "fake" }
+
+func (fakeEmbedder) CreateEmbeddings(ctx context.Context, req *llm.EmbeddingRequest) (*llm.EmbeddingResponse, error) {
+	resp := &llm.EmbeddingResponse{Model: req.Model}
+	for i, text := range req.Input {
+		resp.Data = append(resp.Data, llm.Embedding{Index: i, Vector: []float32{float32(len(text))}})
+	}
+	return resp, nil
+}
+
+// newFakeESClient 创建指向 httptest 服务的客户端，handler 返回响应体
+func newFakeESClient(t *testing.T, handler func(r *http.Request, body []byte) string) *Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(handler(r, body)))
+	}))
+	t.Cleanup(srv.Close)
+	es, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{srv.URL}})
+	require.NoError(t, err)
+	return &Client{ES: es}
+}
+
+func TestVectorIndexMapping_Body(t *testing.T) {
+	body := VectorIndexMapping{
+		Dims:       3,
+		TextFields: []string{"content"},
+		Analyzer:   "ik_max_word",
+		Properties: map[string]interface{}{"tag": map[string]interface{}{"type": "keyword"}},
+	}.Body()
+	props := body["mappings"].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "dense_vector", "dims": 3, "index": true, "similarity": "cosine"}, props["embedding"])
+	assert.Equal(t, map[string]interface{}{"type": "text", "analyzer": "ik_max_word"}, props["content"])
+	assert.Equal(t, map[string]interface{}{"type": "keyword"}, props["tag"])
+	assert.NotContains(t, body, "settings")
+}
+
+func TestClient_BulkIndexWithEmbeddings(t *testing.T) {
+	var requests [][]string
+	client := newFakeESClient(t, func(r *http.Request, body []byte) string {
+		assert.Equal(t, "/docs/_bulk", r.URL.Path)
+		assert.Equal(t, "wait_for", r.URL.Query().Get("refresh"))
+		var lines []string
+		scanner := bufio.NewScanner(strings.NewReader(string(body)))
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		requests = append(requests, lines)
+		if len(requests) == 1 {
+			return `{"errors":true,"items":[{"index":{"_id":"1","status":201}},{"index":{"_id":"2","status":400,"error":{"type":"mapper_parsing_exception","reason":"bad"}}}]}`
+		}
+		return `{"errors":false,"items":[{"index":{"_id":"x","status":201}}]}`
+	})
+
+	type article struct {
+		Title string `json:"title"`
+	}
+	docs := []VectorDocument{
+		{ID: "1", Text: "a", Source: article{Title: "A"}},
+		{ID: "2", Text: "bb", Source: map[string]interface{}{"title": "B"}},
+		{Text: "ccc"},
+	}
+	result, err := client.BulkIndexWithEmbeddings(context.Background(), fakeEmbedder{}, "docs", docs, BulkEmbedConfig{BulkSize: 2, Refresh: "wait_for"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Indexed)
+	assert.Equal(t, []BulkItemError{{ID: "2", Status: 400, Type: "mapper_parsing_exception", Reason: "bad"}}, result.Failed)
+
+	require.Len(t, requests, 2)
+	require.Len(t, requests[0], 4)
+	assert.JSONEq(t, `{"index":{"_id":"1"}}`, requests[0][0])
+	assert.JSONEq(t, `{"title":"A","embedding":[1]}`, requests[0][1])
+	assert.JSONEq(t, `{"title":"B","embedding":[2]}`, requests[0][3])
+	assert.JSONEq(t, `{"index":{}}`, requests[1][0])
+	assert.JSONEq(t, `{"embedding":[3]}`, requests[1][1])
+}
+
+func TestKNNSearch(t *testing.T) {
+	var got map[string]interface{}
+	client := newFakeESClient(t, func(r *http.Request, body []byte) string {
+		assert.Equal(t, "/docs/_search", r.URL.Path)
+		require.NoError(t, json.Unmarshal(body, &got))
+		return `{"took":3,"hits":{"total":{"value":2},"max_score":0.9,"hits":[
+			{"_index":"docs","_id":"1","_score":0.9,"_source":{"title":"A"}},
+			{"_index":"docs","_id":"2","_score":0.5,"_source":{"title":"B"}}]}}`
+	})
+
+	type article struct {
+		Title string `json:"title"`
+	}
+	vector, err := EmbedQuery(context.Background(), fakeEmbedder{}, "m", "hello")
+	require.NoError(t, err)
+	result, err := KNNSearch[article](context.Background(), client, "docs", KNNQuery{
+		Vector: vector,
+		K:      5,
+		Filter: map[string]interface{}{"term": map[string]interface{}{"tag": "go"}},
+	}, SearchOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"field": "embedding", "query_vector": []interface{}{5.0}, "k": 5.0, "num_candidates": 100.0,
+		"filter": map[string]interface{}{"term": map[string]interface{}{"tag": "go"}},
+	}, got["knn"])
+	assert.Equal(t, 5.0, got["size"])
+	assert.Equal(t, map[string]interface{}{"excludes": []interface{}{"embedding"}}, got["_source"])
+
+	assert.Equal(t, int64(2), result.Total)
+	assert.Equal(t, 0.9, result.MaxScore)
+	assert.Equal(t, int64(3), result.Took)
+	assert.Equal(t, []SearchHit[article]{
+		{ID: "1", Index: "docs", Score: 0.9, Source: article{Title: "A"}},
+		{ID: "2", Index: "docs", Score: 0.5, Source: article{Title: "B"}},
+	}, result.Hits)
+
+	_, err = KNNSearch[article](context.Background(), client, "docs", KNNQuery{}, SearchOptions{})
+	assert.EqualError(t, err, "knn query vector cannot be empty")
+}
+
+func TestHybridSearch(t *testing.T) {
+	var got map[string]interface{}
+	client := newFakeESClient(t, func(r *http.Request, body []byte) string {
+		require.NoError(t, json.Unmarshal(body, &got))
+		return `{"hits":{"total":{"value":0},"max_score":null,"hits":[]}}`
+	})
+
+	match := map[string]interface{}{"match": map[string]interface{}{"content": "golang"}}
+	result, err := HybridSearch[map[string]interface{}](context.Background(), client, "docs", HybridQuery{
+		Query:      match,
+		QueryBoost: 0.3,
+		KNN:        KNNQuery{Field: "vec", Vector: []float32{1, 2}, K: 20, Boost: 0.7},
+		Size:       10,
+	}, SearchOptions{From: 10, Source: []string{"title"}})
+	require.NoError(t, err)
+	assert.Empty(t, result.Hits)
+	assert.Equal(t, 0.0, result.MaxScore)
+
+	assert.Equal(t, map[string]interface{}{"bool": map[string]interface{}{"should": []interface{}{match}, "boost": 0.3}}, got["query"])
+	knn := got["knn"].(map[string]interface{})
+	assert.Equal(t, "vec", knn["field"])
+	assert.Equal(t, 200.0, knn["num_candidates"])
+	assert.Equal(t, 0.7, knn["boost"])
+	assert.Equal(t, 10.0, got["size"])
+	assert.Equal(t, 10.0, got["from"])
+	assert.Equal(t, []interface{}{"title"}, got["_source"])
+}
@@ -76,6 +76,12 @@ func NewClient(opt Option) *Client {
 				r.SetHeader(parameter, cast.ToString(value))
 			}
 		}
+		// 通过 WithHeaders 附加的请求头，不覆盖请求自身设置的同名请求头
+		for k, v := range HeadersFromContext(ctx) {
+			if r.Header.Get(k) == "" {
+				r.SetHeader(k, v)
+			}
+		}
 		return nil
 	})
 
@@ -0,0 +1,24 @@
+package http
+
+import "context"
+
+type headersKey struct{}
+
+// WithHeaders 在 context 中附加请求头，使用该 context 发出的请求都会带上这些请求头
+// 多次调用会合并，同名请求头以后设置的为准；请求自身已设置的同名请求头优先
+func WithHeaders(ctx context.Context, headers map[string]string) context.Context {
+	merged := make(map[string]string, len(headers))
+	for k, v := range HeadersFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range headers {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, headersKey{}, merged)
+}
+
+// HeadersFromContext 获取 context 中附加的请求头
+func HeadersFromContext(ctx context.Context) map[string]string {
+	headers, _ := ctx.Value(headersKey{}).(map[string]string)
+	return headers
+}
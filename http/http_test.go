@@ -469,6 +469,9 @@ func TestClient_PostStream(t *testing.T) {
 		assert.Contains(t, err.Error(), "500")
 		assert.Contains(t, err.Error(), "服务器内部错误")
 		assert.False(t, called)
+		var statusErr *StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusInternalServerError, statusErr.StatusCode())
 	})
 
 	t.Run("流式请求支持单次超时", func(t *testing.T) {
@@ -792,6 +795,41 @@ func TestClient_TransparentParameter(t *testing.T) {
 	})
 }
 
+// 测试通过 context 附加请求头
+func TestClient_WithHeaders(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(Option{
+		Timeout: 10 * time.Second,
+		IsLog:   ptr(false),
+	})
+
+	ctx := WithHeaders(context.Background(), map[string]string{"X-Trace-ID": "t1", "X-Tenant": "a"})
+	ctx = WithHeaders(ctx, map[string]string{"X-Tenant": "b", "Authorization": "from-ctx"})
+	_, err := client.Post(ctx, RequestPost{
+		URL:     server.URL,
+		Headers: map[string]string{"Authorization": "Bearer key"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "t1", received.Get("X-Trace-ID"))
+	assert.Equal(t, "b", received.Get("X-Tenant"))
+	assert.Equal(t, "Bearer key", received.Get("Authorization"), "请求自身设置的请求头优先")
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, 3*time.Second, ParseRetryAfter(http.Header{"Retry-After": []string{"3"}}))
+	assert.Zero(t, ParseRetryAfter(http.Header{}))
+	assert.Zero(t, ParseRetryAfter(http.Header{"Retry-After": []string{"soon"}}))
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	d := ParseRetryAfter(http.Header{"Retry-After": []string{date}})
+	assert.True(t, d > 50*time.Second && d <= time.Minute, "got %s", d)
+}
+
 // 测试重试功能
 func TestClient_Retry(t *testing.T) {
 	// 创建一个会失败的服务器来测试重试
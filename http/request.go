@@ -198,10 +198,12 @@ func (c *Client) PostStream(ctx context.Context, req RequestPost, callback func(
 	if resp.IsError() {
 		// 尝试读取错误信息
 		bodyBytes, readErr := io.ReadAll(rawBody)
-		if readErr != nil {
-			return fmt.Errorf("API 返回错误状态 %d, 且读取错误响应体失败: %w", resp.StatusCode(), readErr)
+		return &StatusError{
+			Status:  resp.StatusCode(),
+			Header:  resp.Header(),
+			Body:    bodyBytes,
+			ReadErr: readErr,
 		}
-		return fmt.Errorf("API 返回错误状态 %d: %s", resp.StatusCode(), string(bodyBytes))
 	}
 
 	scanner := bufio.NewScanner(rawBody)
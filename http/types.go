@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -114,3 +115,52 @@ type RequestGet struct {
 	Headers map[string]string // 请求头
 	Timeout time.Duration     // 请求超时时间，如果为0则使用客户端默认超时时间
 }
+
+// StatusError 响应状态码非 2xx 时返回的错误，目前由 PostStream 返回
+type StatusError struct {
+	Status  int         // http response status code
+	Header  http.Header // http response header
+	Body    []byte      // http response body
+	ReadErr error       // 读取响应体失败时的错误
+}
+
+func (e *StatusError) Error() string {
+	if e.ReadErr != nil {
+		return fmt.Sprintf("API 返回错误状态 %d, 且读取错误响应体失败: %v", e.Status, e.ReadErr)
+	}
+	return fmt.Sprintf("API 返回错误状态 %d: %s", e.Status, string(e.Body))
+}
+
+// StatusCode 返回 http 状态码
+func (e *StatusError) StatusCode() int {
+	return e.Status
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.ReadErr
+}
+
+// RetryAfter 返回响应头 Retry-After 建议的等待时间，未设置时返回 0
+func (e *StatusError) RetryAfter() time.Duration {
+	return ParseRetryAfter(e.Header)
+}
+
+// ParseRetryAfter 解析 Retry-After 响应头，支持秒数与 HTTP 日期两种格式，无法解析时返回 0
+func ParseRetryAfter(h http.Header) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,295 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jessewkun/gocommon/logger"
+	"github.com/jessewkun/gocommon/safego"
+)
+
+const agentLogTag = "LLM_AGENT"
+
+// ErrMaxStepsExceeded Agent 在达到最大步数后模型仍在请求调用工具
+var ErrMaxStepsExceeded = errors.New("llm: agent exceeded max steps")
+
+// ToolHandler 工具处理函数，arguments 为模型给出的 JSON 参数，返回值作为 tool 消息内容回传给模型
+type ToolHandler func(ctx context.Context, arguments string) (string, error)
+
+// Tool 可供 Agent 调用的工具
+type Tool struct {
+	Name        string                 // 工具名，需在同一个 Agent 内唯一
+	Description string                 // 工具描述，帮助模型判断何时调用
+	Parameters  map[string]interface{} // 参数的 JSON Schema，可由 JSONSchemaOf 生成
+	Handler     ToolHandler            // 工具处理函数
+	Timeout     time.Duration          // 单次调用超时，0 表示使用 AgentConfig.ToolTimeout
+}
+
+// NewTool 根据参数类型 T 自动生成参数 schema，并在调用前将 JSON 参数解析为 T
+// fn 的返回值为 string 时直接作为工具结果，否则序列化为 JSON
+//
+// 示例：
+//
+//	type WeatherArgs struct {
+//	    City string `json:"city" description:"城市名"`
+//	}
+//	tool := llm.NewTool("get_weather", "查询城市天气", func(ctx context.Context, args WeatherArgs) (interface{}, error) {
+//	    return map[string]string{"city": args.City, "weather": "sunny"}, nil
+//	})
+func NewTool[T any](name, description string, fn func(ctx context.Context, args T) (interface{}, error)) Tool {
+	var zero T
+	return Tool{
+		Name:        name,
+		Description: description,
+		Parameters:  JSONSchemaOf(zero),
+		Handler: func(ctx context.Context, arguments string) (string, error) {
+			var args T
+			if arguments != "" {
+				if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+					return "", fmt.Errorf("invalid arguments: %w", err)
+				}
+			}
+			out, err := fn(ctx, args)
+			if err != nil {
+				return "", err
+			}
+			if s, ok := out.(string); ok {
+				return s, nil
+			}
+			b, err := json.Marshal(out)
+			if err != nil {
+				return "", fmt.Errorf("marshalling tool result: %w", err)
+			}
+			return string(b), nil
+		},
+	}
+}
+
+// definition 返回 OpenAI 兼容格式的工具定义，各 Provider 会自行转换
+func (t *Tool) definition() map[string]interface{} {
+	fn := map[string]interface{}{"name": t.Name}
+	if t.Description != "" {
+		fn["description"] = t.Description
+	}
+	if t.Parameters != nil {
+		fn["parameters"] = t.Parameters
+	} else {
+		fn["parameters"] = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	return map[string]interface{}{"type": "function", "function": fn}
+}
+
+// AgentConfig Agent 配置
+type AgentConfig struct {
+	MaxSteps    int           // 最多请求模型的次数，默认 10
+	ToolTimeout time.Duration // 工具默认超时时间，0 表示不超时
+}
+
+// AgentStep Agent 单步的执行记录：一次模型调用及其触发的工具调用
+type AgentStep struct {
+	Index       int           // 步序号，从 0 开始
+	Response    *ChatResponse // 本步模型响应
+	ToolResults []ToolResult  // 本步执行的工具结果，按 Response.ToolCalls 的顺序排列
+	Duration    time.Duration // 本步总耗时（模型调用 + 工具执行）
+}
+
+// ToolResult 单次工具调用的执行记录
+type ToolResult struct {
+	ToolCall ToolCall      // 模型发起的工具调用
+	Output   string        // 回传给模型的内容，出错时为错误描述
+	Err      error         // 工具执行错误，错误也会以文本形式回传给模型
+	Duration time.Duration // 工具执行耗时
+}
+
+// AgentResult Agent 的运行结果
+type AgentResult struct {
+	Content  string      // 模型的最终回复
+	Messages []Message   // 完整对话记录，包含 assistant 的工具调用与 tool 消息，可用于继续对话
+	Steps    []AgentStep // 每一步的执行记录
+	Usage    Usage       // 所有模型调用累计的 token 用量
+}
+
+// Agent 在 Client 之上实现"调用模型 -> 执行工具 -> 回传结果"的循环，直到模型不再调用工具
+type Agent struct {
+	client *Client
+	cfg    AgentConfig
+	tools  map[string]Tool
+	order  []string
+	mu     sync.RWMutex
+}
+
+// NewAgent 创建 Agent
+func NewAgent(client *Client, cfg AgentConfig) *Agent {
+	if cfg.MaxSteps <= 0 {
+		cfg.MaxSteps = 10
+	}
+	return &Agent{
+		client: client,
+		cfg:    cfg,
+		tools:  make(map[string]Tool),
+	}
+}
+
+// RegisterTool 注册工具，工具名重复时返回错误
+func (a *Agent) RegisterTool(tool Tool) error {
+	if tool.Name == "" {
+		return fmt.Errorf("llm: tool name cannot be empty")
+	}
+	if tool.Handler == nil {
+		return fmt.Errorf("llm: tool %q handler cannot be nil", tool.Name)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, exists := a.tools[tool.Name]; exists {
+		return fmt.Errorf("llm: tool %q already registered", tool.Name)
+	}
+	a.tools[tool.Name] = tool
+	a.order = append(a.order, tool.Name)
+	return nil
+}
+
+// Run 以 req 为初始请求运行 Agent 循环
+// 已注册的工具会追加到 req.Tools 中；req 本身不会被修改
+// 超过 MaxSteps 时返回已执行的结果以及 ErrMaxStepsExceeded
+func (a *Agent) Run(ctx context.Context, req *ChatRequest) (*AgentResult, error) {
+	if req == nil {
+		return nil, fmt.Errorf("llm: agent request cannot be nil")
+	}
+
+	stepReq := *req
+	stepReq.Messages = append([]Message(nil), req.Messages...)
+	stepReq.Tools = append(append([]map[string]interface{}(nil), req.Tools...), a.toolDefinitions()...)
+
+	result := &AgentResult{}
+	for i := 0; i < a.cfg.MaxSteps; i++ {
+		stepStart := time.Now()
+		resp, err := a.client.Chat(ctx, &stepReq)
+		if err != nil {
+			result.Messages = stepReq.Messages
+			return result, fmt.Errorf("llm: agent step %d failed: %w", i, err)
+		}
+		result.Usage.PromptTokens += resp.Usage.PromptTokens
+		result.Usage.CompletionTokens += resp.Usage.CompletionTokens
+		result.Usage.TotalTokens += resp.Usage.TotalTokens
+
+		step := AgentStep{Index: i, Response: resp}
+		stepReq.Messages = append(stepReq.Messages, Message{
+			Role:      "assistant",
+			Content:   resp.Content,
+			ToolCalls: resp.ToolCalls,
+			Thinking:  resp.Thinking,
+		})
+
+		if len(resp.ToolCalls) == 0 {
+			step.Duration = time.Since(stepStart)
+			result.Steps = append(result.Steps, step)
+			result.Content = resp.Content
+			result.Messages = stepReq.Messages
+			return result, nil
+		}
+
+		for _, call := range resp.ToolCalls {
+			tr := a.callTool(ctx, call)
+			step.ToolResults = append(step.ToolResults, tr)
+			stepReq.Messages = append(stepReq.Messages, Message{
+				Role:       "tool",
+				Name:       call.Function.Name,
+				ToolCallID: call.ID,
+				Content:    tr.Output,
+			})
+		}
+		step.Duration = time.Since(stepStart)
+		result.Steps = append(result.Steps, step)
+
+		if err := ctx.Err(); err != nil {
+			result.Messages = stepReq.Messages
+			return result, err
+		}
+	}
+
+	result.Messages = stepReq.Messages
+	return result, ErrMaxStepsExceeded
+}
+
+// toolDefinitions 按注册顺序返回所有工具定义
+func (a *Agent) toolDefinitions() []map[string]interface{} {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	defs := make([]map[string]interface{}, 0, len(a.order))
+	for _, name := range a.order {
+		tool := a.tools[name]
+		defs = append(defs, tool.definition())
+	}
+	return defs
+}
+
+// callTool 执行单个工具调用，panic、超时与错误都会转为文本回传给模型，不会中断 Agent 循环
+func (a *Agent) callTool(ctx context.Context, call ToolCall) ToolResult {
+	start := time.Now()
+	tr := ToolResult{ToolCall: call}
+
+	a.mu.RLock()
+	tool, ok := a.tools[call.Function.Name]
+	a.mu.RUnlock()
+	if !ok {
+		tr.Err = fmt.Errorf("unknown tool %q", call.Function.Name)
+		tr.Output = "error: " + tr.Err.Error()
+		tr.Duration = time.Since(start)
+		return tr
+	}
+
+	timeout := tool.Timeout
+	if timeout <= 0 {
+		timeout = a.cfg.ToolTimeout
+	}
+	var (
+		toolCtx context.Context
+		cancel  context.CancelFunc
+	)
+	if timeout > 0 {
+		toolCtx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		toolCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	type result struct {
+		output string
+		err    error
+	}
+	resultChan := make(chan result, 1)
+	go func() {
+		res := result{err: fmt.Errorf("tool %q panicked", tool.Name)}
+		safego.SafeGo(toolCtx, func() {
+			out, err := tool.Handler(toolCtx, call.Function.Arguments)
+			res = result{output: out, err: err}
+		})
+		resultChan <- res
+	}()
+
+	select {
+	case res := <-resultChan:
+		tr.Output, tr.Err = res.output, res.err
+	case <-toolCtx.Done():
+		tr.Err = fmt.Errorf("tool %q: %w", tool.Name, toolCtx.Err())
+		if errors.Is(toolCtx.Err(), context.DeadlineExceeded) {
+			tr.Err = fmt.Errorf("tool %q timeout after %v", tool.Name, timeout)
+		}
+	}
+	tr.Duration = time.Since(start)
+	if tr.Err != nil {
+		tr.Output = "error: " + tr.Err.Error()
+		logger.WarnWithField(ctx, agentLogTag, "tool call failed", map[string]interface{}{
+			"tool":     call.Function.Name,
+			"call_id":  call.ID,
+			"error":    tr.Err.Error(),
+			"duration": tr.Duration.String(),
+		})
+	}
+	return tr
+}
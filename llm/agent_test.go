@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedChatter 按顺序返回预设响应，并记录收到的请求
+type scriptedChatter struct {
+	responses []*ChatResponse
+	requests  []*ChatRequest
+}
+
+func (s *scriptedChatter) Name() string { return "scripted" }
+
+func (s *scriptedChatter) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	cp := *req
+	cp.Messages = append([]Message(nil), req.Messages...)
+	s.requests = append(s.requests, &cp)
+	if len(s.requests) > len(s.responses) {
+		return nil, errors.New("no more scripted responses")
+	}
+	return s.responses[len(s.requests)-1], nil
+}
+
+func (s *scriptedChatter) ChatStream(ctx context.Context, req *ChatRequest, callback func(chunk string) error) (*ChatResponse, error) {
+	return s.Chat(ctx, req)
+}
+
+type weatherArgs struct {
+	City string `json:"city" description:"城市名"`
+	Unit string `json:"unit,omitempty" enum:"celsius,fahrenheit"`
+}
+
+func TestJSONSchemaOf(t *testing.T) {
+	schema := JSONSchemaOf(weatherArgs{})
+	assert.Equal(t, "object", schema["type"])
+	assert.Equal(t, []string{"city"}, schema["required"])
+
+	props := schema["properties"].(map[string]interface{})
+	city := props["city"].(map[string]interface{})
+	assert.Equal(t, "string", city["type"])
+	assert.Equal(t, "城市名", city["description"])
+	unit := props["unit"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"celsius", "fahrenheit"}, unit["enum"])
+}
+
+func TestAgent_Run(t *testing.T) {
+	chatter := &scriptedChatter{responses: []*ChatResponse{
+		{
+			ToolCalls: []ToolCall{
+				{ID: "call_1", Type: "function", Function: FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+				{ID: "call_2", Type: "function", Function: FunctionCall{Name: "unknown", Arguments: `{}`}},
+			},
+			Thinking:     []ThinkingBlock{{Thinking: "Need the weather.", Signature: "sig"}},
+			FinishReason: "tool_calls",
+			Usage:        Usage{TotalTokens: 10},
+		},
+		{Content: "Paris is sunny.", FinishReason: "stop", Usage: Usage{TotalTokens: 5}},
+	}}
+	agent := NewAgent(&Client{provider: chatter}, AgentConfig{})
+	require.NoError(t, agent.RegisterTool(NewTool("get_weather", "查询城市天气", func(ctx context.Context, args weatherArgs) (interface{}, error) {
+		return map[string]string{"city": args.City, "weather": "sunny"}, nil
+	})))
+
+	result, err := agent.Run(context.Background(), &ChatRequest{
+		Model:    "test",
+		Messages: []Message{{Role: "user", Content: "Weather in Paris?"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Paris is sunny.", result.Content)
+	assert.Equal(t, 15, result.Usage.TotalTokens)
+	require.Len(t, result.Steps, 2)
+	require.Len(t, result.Steps[0].ToolResults, 2)
+	assert.JSONEq(t, `{"city":"Paris","weather":"sunny"}`, result.Steps[0].ToolResults[0].Output)
+	assert.Error(t, result.Steps[0].ToolResults[1].Err)
+
+	// 第二次请求应包含 assistant 工具调用与两条 tool 消息
+	require.Len(t, chatter.requests, 2)
+	require.Len(t, chatter.requests[0].Tools, 1)
+	second := chatter.requests[1].Messages
+	require.Len(t, second, 4)
+	assert.Equal(t, "assistant", second[1].Role)
+	assert.Len(t, second[1].ToolCalls, 2)
+	assert.Equal(t, []ThinkingBlock{{Thinking: "Need the weather.", Signature: "sig"}}, second[1].Thinking)
+	assert.Equal(t, "tool", second[2].Role)
+	assert.Equal(t, "call_1", second[2].ToolCallID)
+	assert.Equal(t, "call_2", second[3].ToolCallID)
+	assert.Len(t, result.Messages, 5)
+}
+
+func TestAgent_Run_ToolTimeoutAndMaxSteps(t *testing.T) {
+	call := &ChatResponse{ToolCalls: []ToolCall{{ID: "call_1", Type: "function", Function: FunctionCall{Name: "slow"}}}}
+	chatter := &scriptedChatter{responses: []*ChatResponse{call, call}}
+	agent := NewAgent(&Client{provider: chatter}, AgentConfig{MaxSteps: 2, ToolTimeout: 20 * time.Millisecond})
+	require.NoError(t, agent.RegisterTool(Tool{
+		Name: "slow",
+		Handler: func(ctx context.Context, arguments string) (string, error) {
+			<-ctx.Done()
+			time.Sleep(50 * time.Millisecond)
+			return "late", nil
+		},
+	}))
+	assert.Error(t, agent.RegisterTool(Tool{Name: "slow", Handler: func(ctx context.Context, arguments string) (string, error) { return "", nil }}))
+
+	result, err := agent.Run(context.Background(), &ChatRequest{Messages: []Message{{Role: "user", Content: "go"}}})
+	assert.ErrorIs(t, err, ErrMaxStepsExceeded)
+	require.Len(t, result.Steps, 2)
+	assert.Contains(t, result.Steps[0].ToolResults[0].Output, "timeout")
+}
@@ -0,0 +1,344 @@
+// Package anthropic an provider for the anthropic messages api
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	xhttp "github.com/jessewkun/gocommon/http"
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProvider(serverURL string) *Provider {
+	cfg := Config{
+		APIKey:  "test-key",
+		APIURL:  serverURL,
+		Timeout: 5 * time.Second,
+	}
+	client := xhttp.NewClient(xhttp.Option{})
+	return NewProvider(client, cfg)
+}
+
+func TestAnthropicProvider_Chat_WithSystemPrompt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/messages", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("x-api-key"))
+		assert.Equal(t, defaultVersion, r.Header.Get("anthropic-version"))
+
+		var reqBody AnthropicMessagesRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		assert.Equal(t, "You are a helpful assistant.\n\nAnswer briefly.", reqBody.System)
+		assert.Equal(t, defaultMaxTokens, reqBody.MaxTokens)
+		require.NotNil(t, reqBody.Temperature)
+		assert.Equal(t, 0.5, *reqBody.Temperature)
+		assert.Nil(t, reqBody.Thinking)
+		require.Len(t, reqBody.Messages, 1)
+		assert.Equal(t, "user", reqBody.Messages[0].Role)
+		assert.Equal(t, "Hello", reqBody.Messages[0].Content[0].Text)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5",` +
+			`"content":[{"type":"text","text":"Hi there!"}],"stop_reason":"end_turn",` +
+			`"usage":{"input_tokens":12,"output_tokens":4,"cache_read_input_tokens":3}}`))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	resp, err := provider.Chat(context.Background(), &llm.ChatRequest{
+		Model:       "claude-sonnet-4-5",
+		Temperature: 0.5,
+		Messages: []llm.Message{
+			{Role: "system", Content: "You are a helpful assistant."},
+			{Role: "system", Content: "Answer briefly."},
+			{Role: "user", Content: "Hello"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there!", resp.Content)
+	assert.Equal(t, "stop", resp.FinishReason)
+	assert.Equal(t, llm.Usage{PromptTokens: 15, CompletionTokens: 4, TotalTokens: 19}, resp.Usage)
+}
+
+func TestAnthropicProvider_Chat_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens: field required"}}`))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	_, err := provider.Chat(context.Background(), &llm.ChatRequest{
+		Model:    "claude-sonnet-4-5",
+		Messages: []llm.Message{{Role: "user", Content: "Hello"}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid_request_error")
+}
+
+func TestAnthropicProvider_Chat_WithMultimodalAndTools(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody AnthropicMessagesRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+
+		// user (text + image), assistant (tool_use), user (two tool_results merged)
+		require.Len(t, reqBody.Messages, 3)
+		user := reqBody.Messages[0]
+		require.Len(t, user.Content, 3)
+		assert.Equal(t, "text", user.Content[0].Type)
+		assert.Equal(t, "image", user.Content[1].Type)
+		assert.Equal(t, "base64", user.Content[1].Source.Type)
+		assert.Equal(t, "image/png", user.Content[1].Source.MediaType)
+		assert.Equal(t, "iVBORw0KGgo=", user.Content[1].Source.Data)
+		assert.Equal(t, "url", user.Content[2].Source.Type)
+		assert.Equal(t, "https://example.com/cat.jpg", user.Content[2].Source.URL)
+
+		assistant := reqBody.Messages[1]
+		assert.Equal(t, "assistant", assistant.Role)
+		require.Len(t, assistant.Content, 2)
+		assert.Equal(t, "tool_use", assistant.Content[0].Type)
+		assert.Equal(t, "toolu_1", assistant.Content[0].ID)
+		assert.JSONEq(t, `{"city":"Paris"}`, string(assistant.Content[0].Input))
+		assert.JSONEq(t, `{}`, string(assistant.Content[1].Input))
+
+		results := reqBody.Messages[2]
+		assert.Equal(t, "user", results.Role)
+		require.Len(t, results.Content, 2)
+		assert.Equal(t, "tool_result", results.Content[0].Type)
+		assert.Equal(t, "toolu_1", results.Content[0].ToolUseID)
+		assert.Equal(t, "sunny", results.Content[0].Content)
+
+		require.Len(t, reqBody.Tools, 1)
+		assert.Equal(t, "get_weather", reqBody.Tools[0].Name)
+		assert.NotNil(t, reqBody.Tools[0].InputSchema)
+		require.NotNil(t, reqBody.ToolChoice)
+		assert.Equal(t, "tool", reqBody.ToolChoice.Type)
+		assert.Equal(t, "get_weather", reqBody.ToolChoice.Name)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_2","type":"message","role":"assistant",` +
+			`"content":[{"type":"text","text":"Checking again."},{"type":"tool_use","id":"toolu_3","name":"get_weather","input":{"city":"Rome"}}],` +
+			`"stop_reason":"tool_use","usage":{"input_tokens":50,"output_tokens":20}}`))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	resp, err := provider.Chat(context.Background(), &llm.ChatRequest{
+		Model: "claude-sonnet-4-5",
+		Messages: []llm.Message{
+			{Role: "user", Content: []interface{}{
+				map[string]interface{}{"type": "text", "text": "Compare these"},
+				map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "data:image/png;base64,iVBORw0KGgo="}},
+				map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "https://example.com/cat.jpg"}},
+			}},
+			{Role: "assistant", ToolCalls: []llm.ToolCall{
+				{ID: "toolu_1", Type: "function", Function: llm.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+				{ID: "toolu_2", Type: "function", Function: llm.FunctionCall{Name: "get_time"}},
+			}},
+			{Role: "tool", ToolCallID: "toolu_1", Content: "sunny"},
+			{Role: "tool", ToolCallID: "toolu_2", Content: "12:00"},
+		},
+		Tools: []map[string]interface{}{{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":       "get_weather",
+				"parameters": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}}},
+			},
+		}},
+		ToolChoice: map[string]interface{}{"type": "function", "function": map[string]interface{}{"name": "get_weather"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Checking again.", resp.Content)
+	assert.Equal(t, "tool_calls", resp.FinishReason)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "toolu_3", resp.ToolCalls[0].ID)
+	assert.Equal(t, "get_weather", resp.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"city":"Rome"}`, resp.ToolCalls[0].Function.Arguments)
+}
+
+func TestAnthropicProvider_Chat_ThinkingRoundTrip(t *testing.T) {
+	var requests []AnthropicMessagesRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody AnthropicMessagesRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		requests = append(requests, reqBody)
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(requests) == 1 {
+			_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant",` +
+				`"content":[{"type":"thinking","thinking":"Need the weather.","signature":"sig_1"},{"type":"redacted_thinking","data":"opaque"},` +
+				`{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"Paris"}}],` +
+				`"stop_reason":"tool_use","usage":{"input_tokens":10,"output_tokens":20}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"msg_2","type":"message","role":"assistant",` +
+			`"content":[{"type":"text","text":"Paris is sunny."}],"stop_reason":"end_turn","usage":{"input_tokens":30,"output_tokens":5}}`))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	exclude := true
+	req := &llm.ChatRequest{
+		Model:     "claude-sonnet-4-5",
+		Messages:  []llm.Message{{Role: "user", Content: "Weather in Paris?"}},
+		Reasoning: &llm.ReasoningConfig{Effort: "low", Exclude: &exclude},
+	}
+	resp, err := provider.Chat(context.Background(), req)
+	require.NoError(t, err)
+	// Exclude only hides the reasoning text, the signed blocks are still needed for the follow-up
+	assert.Empty(t, resp.Reasoning)
+	assert.Equal(t, []llm.ThinkingBlock{
+		{Thinking: "Need the weather.", Signature: "sig_1"},
+		{RedactedData: "opaque"},
+	}, resp.Thinking)
+	require.Len(t, resp.ToolCalls, 1)
+
+	req.Messages = append(req.Messages,
+		llm.Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls, Thinking: resp.Thinking},
+		llm.Message{Role: "tool", ToolCallID: "toolu_1", Content: "sunny"},
+	)
+	resp, err = provider.Chat(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "Paris is sunny.", resp.Content)
+
+	require.Len(t, requests, 2)
+	require.Len(t, requests[1].Messages, 3)
+	assistant := requests[1].Messages[1]
+	require.Len(t, assistant.Content, 3)
+	assert.Equal(t, AnthropicContentBlock{Type: "thinking", Thinking: "Need the weather.", Signature: "sig_1"}, assistant.Content[0])
+	assert.Equal(t, AnthropicContentBlock{Type: "redacted_thinking", Data: "opaque"}, assistant.Content[1])
+	assert.Equal(t, "tool_use", assistant.Content[2].Type)
+	assert.Equal(t, "toolu_1", assistant.Content[2].ID)
+}
+
+func TestAnthropicProvider_ChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody AnthropicMessagesRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		assert.True(t, reqBody.Stream)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"usage\":{\"input_tokens\":10,\"output_tokens\":1}}}\n\n"))
+		_, _ = w.Write([]byte("event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n"))
+		_, _ = w.Write([]byte("event: ping\ndata: {\"type\":\"ping\"}\n\n"))
+		_, _ = w.Write([]byte("event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hello\"}}\n\n"))
+		_, _ = w.Write([]byte("event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\" World\"}}\n\n"))
+		_, _ = w.Write([]byte("event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n"))
+		_, _ = w.Write([]byte("event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"max_tokens\"},\"usage\":{\"output_tokens\":5}}\n\n"))
+		_, _ = w.Write([]byte("event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	var chunks []string
+	resp, err := provider.ChatStream(context.Background(), &llm.ChatRequest{
+		Model:    "claude-sonnet-4-5",
+		Messages: []llm.Message{{Role: "user", Content: "Say Hello World"}},
+	}, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hello", " World"}, chunks)
+	assert.Equal(t, "Hello World", resp.Content)
+	assert.Equal(t, "length", resp.FinishReason)
+	assert.Equal(t, llm.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}, resp.Usage)
+}
+
+func TestAnthropicProvider_ChatStreamEvents_WithThinkingAndTools(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody AnthropicMessagesRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		require.NotNil(t, reqBody.Thinking)
+		assert.Equal(t, "enabled", reqBody.Thinking.Type)
+		assert.Equal(t, 2048, reqBody.Thinking.BudgetTokens)
+		assert.Greater(t, reqBody.MaxTokens, reqBody.Thinking.BudgetTokens)
+		assert.Nil(t, reqBody.Temperature)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":8}}}\n\n"))
+		_, _ = w.Write([]byte("data: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"thinking\",\"thinking\":\"\"}}\n\n"))
+		_, _ = w.Write([]byte("data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"thinking_delta\",\"thinking\":\"Need the time.\"}}\n\n"))
+		_, _ = w.Write([]byte("data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"signature_delta\",\"signature\":\"sig\"}}\n\n"))
+		_, _ = w.Write([]byte("data: {\"type\":\"content_block_stop\",\"index\":0}\n\n"))
+		_, _ = w.Write([]byte("data: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"tool_use\",\"id\":\"toolu_1\",\"name\":\"get_weather\",\"input\":{}}}\n\n"))
+		_, _ = w.Write([]byte("data: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"city\\\":\"}}\n\n"))
+		_, _ = w.Write([]byte("data: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"\\\"Paris\\\"}\"}}\n\n"))
+		_, _ = w.Write([]byte("data: {\"type\":\"content_block_stop\",\"index\":1}\n\n"))
+		_, _ = w.Write([]byte("data: {\"type\":\"content_block_start\",\"index\":2,\"content_block\":{\"type\":\"tool_use\",\"id\":\"toolu_2\",\"name\":\"get_time\",\"input\":{}}}\n\n"))
+		_, _ = w.Write([]byte("data: {\"type\":\"content_block_stop\",\"index\":2}\n\n"))
+		_, _ = w.Write([]byte("data: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"tool_use\"},\"usage\":{\"output_tokens\":30}}\n\n"))
+		_, _ = w.Write([]byte("data: {\"type\":\"message_stop\"}\n\n"))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	var reasoning string
+	var toolDeltas int
+	resp, err := provider.ChatStreamEvents(context.Background(), &llm.ChatRequest{
+		Model:     "claude-sonnet-4-5",
+		Messages:  []llm.Message{{Role: "user", Content: "Weather and time in Paris?"}},
+		Reasoning: &llm.ReasoningConfig{Effort: "low"},
+	}, func(event llm.StreamEvent) error {
+		switch event.Type {
+		case llm.StreamEventReasoningDelta:
+			reasoning += event.Text
+		case llm.StreamEventToolCallDelta:
+			toolDeltas++
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Need the time.", reasoning)
+	assert.Equal(t, "Need the time.", resp.Reasoning)
+	assert.Equal(t, []llm.ThinkingBlock{{Thinking: "Need the time.", Signature: "sig"}}, resp.Thinking)
+	assert.Equal(t, 5, toolDeltas)
+	assert.Equal(t, "tool_calls", resp.FinishReason)
+	require.Len(t, resp.ToolCalls, 2)
+	assert.Equal(t, "toolu_1", resp.ToolCalls[0].ID)
+	assert.JSONEq(t, `{"city":"Paris"}`, resp.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, "get_time", resp.ToolCalls[1].Function.Name)
+	assert.Equal(t, "{}", resp.ToolCalls[1].Function.Arguments)
+	assert.Equal(t, 38, resp.Usage.TotalTokens)
+}
+
+func TestAnthropicProvider_ChatStreamEvents_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\n"))
+		_, _ = w.Write([]byte("event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	var gotErr error
+	_, err := provider.ChatStreamEvents(context.Background(), &llm.ChatRequest{
+		Model:    "claude-sonnet-4-5",
+		Messages: []llm.Message{{Role: "user", Content: "Hello"}},
+	}, func(event llm.StreamEvent) error {
+		if event.Type == llm.StreamEventError {
+			gotErr = event.Err
+		}
+		return nil
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "overloaded_error")
+	assert.Equal(t, err, gotErr)
+}
+
+func TestThinkingBudget(t *testing.T) {
+	disabled := false
+	small := 100
+	assert.Equal(t, 0, thinkingBudget(nil))
+	assert.Equal(t, 0, thinkingBudget(&llm.ReasoningConfig{Enabled: &disabled, Effort: "high"}))
+	assert.Equal(t, 0, thinkingBudget(&llm.ReasoningConfig{Effort: "none"}))
+	assert.Equal(t, 16384, thinkingBudget(&llm.ReasoningConfig{Effort: "high"}))
+	assert.Equal(t, 8192, thinkingBudget(&llm.ReasoningConfig{}))
+	assert.Equal(t, minThinkingBudget, thinkingBudget(&llm.ReasoningConfig{MaxTokens: &small}))
+}
@@ -0,0 +1,627 @@
+// Package anthropic an provider for the anthropic messages api
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	xhttp "github.com/jessewkun/gocommon/http"
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/jessewkun/gocommon/logger"
+)
+
+const logTag = "LLM_ANTHROPIC"
+
+// minThinkingBudget is the smallest budget_tokens accepted by the API
+const minThinkingBudget = 1024
+
+// effortBudgets maps ReasoningConfig.Effort to a thinking budget
+var effortBudgets = map[string]int{
+	"minimal": 1024,
+	"low":     2048,
+	"medium":  8192,
+	"high":    16384,
+	"xhigh":   32768,
+}
+
+// Provider implements llm.Chatter and llm.EventStreamer for the Anthropic Messages API
+type Provider struct {
+	client *xhttp.Client
+	cfg    Config
+}
+
+// NewProvider creates a new Anthropic Provider
+func NewProvider(client *xhttp.Client, cfg Config) *Provider {
+	return &Provider{client: client, cfg: withDefaults(cfg)}
+}
+
+// Name implements llm.Provider
+func (p *Provider) Name() string {
+	return providerName
+}
+
+// Chat implements llm.Chatter for non-streaming chat
+func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	anthropicReq, err := p.toAnthropicRequest(req, false)
+	if err != nil {
+		return nil, err
+	}
+	bodyBytes, err := json.Marshal(anthropicReq)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: marshalling chat request: %w", err)
+	}
+
+	resp, err := p.client.Post(ctx, xhttp.RequestPost{
+		URL:     p.cfg.APIURL + "/messages",
+		Payload: bodyBytes,
+		Headers: p.buildHeaders(),
+		Timeout: p.cfg.Timeout,
+	})
+	if err != nil {
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("anthropic: chat api call failed: %w", err))
+	}
+	if resp == nil || len(resp.Body) == 0 {
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("anthropic: empty response"))
+	}
+
+	var apiResp AnthropicMessagesResponse
+	if err := json.Unmarshal(resp.Body, &apiResp); err != nil {
+		return nil, llm.NewError(providerName, resp.StatusCode, fmt.Errorf("anthropic: unmarshalling chat response: %w (body: %s)", err, string(resp.Body)))
+	}
+	if apiResp.Error != nil {
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("anthropic: api error: %w", apiResp.Error.withResponse(resp)))
+	}
+
+	chatResp := toLLMChatResponse(&apiResp, excludeReasoning(req))
+	chatResp.RawResponse = resp.Body
+	return chatResp, nil
+}
+
+// ChatStream implements llm.Chatter for streaming chat
+func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, callback func(chunk string) error) (*llm.ChatResponse, error) {
+	return p.ChatStreamEvents(ctx, req, llm.TextCallback(callback))
+}
+
+// ChatStreamEvents implements llm.EventStreamer for streaming chat with structured events
+func (p *Provider) ChatStreamEvents(ctx context.Context, req *llm.ChatRequest, handler llm.StreamHandler) (*llm.ChatResponse, error) {
+	anthropicReq, err := p.toAnthropicRequest(req, true)
+	if err != nil {
+		return nil, err
+	}
+	bodyBytes, err := json.Marshal(anthropicReq)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: marshalling stream request: %w", err)
+	}
+
+	timeout := p.cfg.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Minute
+	}
+
+	acc := llm.NewStreamAccumulator(handler)
+	exclude := excludeReasoning(req)
+	var usage AnthropicUsage
+	// tool_use blocks are numbered by their position among tool calls, not among all content blocks
+	toolIndex := make(map[int]int)
+	toolHasInput := make(map[int]bool)
+	// thinking blocks are collected with their signature and handed over when the block stops
+	thinking := make(map[int]*llm.ThinkingBlock)
+
+	err = p.client.PostStream(ctx, xhttp.RequestPost{
+		URL:     p.cfg.APIURL + "/messages",
+		Payload: bodyBytes,
+		Headers: p.buildHeaders(),
+		Timeout: timeout,
+	}, func(line []byte) error {
+		// Every data line carries its own "type", so the preceding "event:" lines can be ignored
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			return nil
+		}
+		data := bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+		if len(data) == 0 {
+			return nil
+		}
+
+		var event AnthropicStreamEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			logger.WarnWithField(ctx, logTag, "failed to parse anthropic stream line", map[string]interface{}{
+				"line":  string(line),
+				"error": err.Error(),
+			})
+			return nil // Continue to next line
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				usage = event.Message.Usage
+			}
+		case "content_block_start":
+			block := event.ContentBlock
+			if block == nil {
+				return nil
+			}
+			switch block.Type {
+			case "thinking":
+				thinking[event.Index] = &llm.ThinkingBlock{Thinking: block.Thinking, Signature: block.Signature}
+			case "redacted_thinking":
+				thinking[event.Index] = &llm.ThinkingBlock{RedactedData: block.Data}
+			case "tool_use":
+				idx := len(toolIndex)
+				toolIndex[event.Index] = idx
+				return acc.ToolCall(llm.ToolCallDelta{Index: idx, ID: block.ID, Type: "function", Name: block.Name})
+			}
+		case "content_block_delta":
+			if event.Delta == nil {
+				return nil
+			}
+			switch event.Delta.Type {
+			case "text_delta":
+				return acc.Text(event.Delta.Text)
+			case "thinking_delta":
+				if b, ok := thinking[event.Index]; ok {
+					b.Thinking += event.Delta.Thinking
+				}
+				if exclude {
+					return nil
+				}
+				return acc.Reasoning(event.Delta.Thinking)
+			case "signature_delta":
+				if b, ok := thinking[event.Index]; ok {
+					b.Signature += event.Delta.Signature
+				}
+			case "input_json_delta":
+				idx, ok := toolIndex[event.Index]
+				if !ok || event.Delta.PartialJSON == "" {
+					return nil
+				}
+				toolHasInput[idx] = true
+				return acc.ToolCall(llm.ToolCallDelta{Index: idx, Arguments: event.Delta.PartialJSON})
+			}
+		case "content_block_stop":
+			if b, ok := thinking[event.Index]; ok {
+				acc.Thinking(*b)
+				delete(thinking, event.Index)
+				return nil
+			}
+			// Tools without parameters may not stream any input, keep the arguments valid JSON
+			if idx, ok := toolIndex[event.Index]; ok && !toolHasInput[idx] {
+				return acc.ToolCall(llm.ToolCallDelta{Index: idx, Arguments: "{}"})
+			}
+		case "message_delta":
+			if event.Delta != nil {
+				acc.Finish(toFinishReason(event.Delta.StopReason))
+			}
+			if event.Usage != nil {
+				usage.OutputTokens = event.Usage.OutputTokens
+				return acc.Usage(toLLMUsage(usage))
+			}
+		case "error":
+			if event.Error != nil {
+				return fmt.Errorf("api error: %w", event.Error)
+			}
+			return fmt.Errorf("api error: %s", string(data))
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, acc.Fail(llm.NewError(providerName, 0, fmt.Errorf("anthropic: stream failed: %w", err)))
+	}
+
+	return acc.Done()
+}
+
+func (p *Provider) buildHeaders() map[string]string {
+	return map[string]string{
+		"Content-Type":      "application/json",
+		"x-api-key":         p.cfg.APIKey,
+		"anthropic-version": p.cfg.Version,
+	}
+}
+
+// --- Helper Functions ---
+
+func (p *Provider) toAnthropicRequest(req *llm.ChatRequest, stream bool) (*AnthropicMessagesRequest, error) {
+	anthropicReq := &AnthropicMessagesRequest{
+		Model:         req.Model,
+		MaxTokens:     p.cfg.MaxTokens,
+		TopP:          req.TopP,
+		TopK:          req.TopK,
+		StopSequences: req.Stop,
+		Stream:        stream,
+	}
+	if req.MaxTokens != nil {
+		anthropicReq.MaxTokens = *req.MaxTokens
+	}
+	if req.User != "" {
+		anthropicReq.Metadata = &AnthropicMetadata{UserID: req.User}
+	}
+
+	if budget := thinkingBudget(req.Reasoning); budget > 0 {
+		anthropicReq.Thinking = &AnthropicThinking{Type: "enabled", BudgetTokens: budget}
+		// budget_tokens must be less than max_tokens, leave room for the answer itself
+		if anthropicReq.MaxTokens <= budget {
+			anthropicReq.MaxTokens = budget + p.cfg.MaxTokens
+		}
+		// temperature and top_k are not compatible with extended thinking
+		anthropicReq.TopK = nil
+	} else {
+		temperature := req.Temperature
+		anthropicReq.Temperature = &temperature
+	}
+
+	system, messages, err := toAnthropicMessages(req.Messages)
+	if err != nil {
+		return nil, err
+	}
+	if req.JSONSchema != nil {
+		// The Messages API has no native structured output, describe the schema in the system prompt instead
+		instruction, err := schemaInstruction(req.JSONSchema)
+		if err != nil {
+			return nil, err
+		}
+		if system != "" {
+			system += "\n\n"
+		}
+		system += instruction
+	}
+	anthropicReq.System = system
+	anthropicReq.Messages = messages
+
+	tools, err := toAnthropicTools(req.Tools)
+	if err != nil {
+		return nil, err
+	}
+	anthropicReq.Tools = tools
+	anthropicReq.ToolChoice = toAnthropicToolChoice(req.ToolChoice)
+
+	return anthropicReq, nil
+}
+
+// schemaInstruction builds the system prompt instruction asking for a JSON reply that conforms to the schema
+func schemaInstruction(format *llm.JSONSchemaFormat) (string, error) {
+	schema, err := json.Marshal(format.Schema)
+	if err != nil {
+		return "", fmt.Errorf("anthropic: marshalling json schema: %w", err)
+	}
+	instruction := "Respond with only a JSON value that conforms to the following JSON schema, without any explanation or markdown."
+	if format.Description != "" {
+		instruction += " " + format.Description
+	}
+	return instruction + "\n" + string(schema), nil
+}
+
+// thinkingBudget returns the extended thinking budget derived from ReasoningConfig, 0 means disabled.
+// MaxTokens takes precedence over Effort; an empty or unknown effort uses the "medium" budget.
+func thinkingBudget(r *llm.ReasoningConfig) int {
+	if r == nil || (r.Enabled != nil && !*r.Enabled) {
+		return 0
+	}
+	if r.MaxTokens != nil {
+		if *r.MaxTokens < minThinkingBudget {
+			return minThinkingBudget
+		}
+		return *r.MaxTokens
+	}
+	if r.Effort == "none" {
+		return 0
+	}
+	if budget, ok := effortBudgets[r.Effort]; ok {
+		return budget
+	}
+	return effortBudgets["medium"]
+}
+
+// excludeReasoning reports whether thinking content should be left out of the response
+func excludeReasoning(req *llm.ChatRequest) bool {
+	return req.Reasoning != nil && req.Reasoning.Exclude != nil && *req.Reasoning.Exclude
+}
+
+// toAnthropicMessages extracts the system prompt and converts the remaining messages.
+// All system messages are joined into the top level system prompt, tool results are sent as
+// tool_result blocks of a user message, and consecutive messages of the same role are merged
+// because the API requires user and assistant turns to alternate.
+func toAnthropicMessages(msgs []llm.Message) (string, []AnthropicMessage, error) {
+	var system []string
+	out := make([]AnthropicMessage, 0, len(msgs))
+	appendBlocks := func(role string, blocks []AnthropicContentBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(out); n > 0 && out[n-1].Role == role {
+			out[n-1].Content = append(out[n-1].Content, blocks...)
+			return
+		}
+		out = append(out, AnthropicMessage{Role: role, Content: blocks})
+	}
+
+	for _, msg := range msgs {
+		switch msg.Role {
+		case "system":
+			if text := contentText(msg.Content); text != "" {
+				system = append(system, text)
+			}
+		case "tool":
+			block := AnthropicContentBlock{Type: "tool_result", ToolUseID: msg.ToolCallID}
+			if s, ok := msg.Content.(string); ok {
+				block.Content = s
+			} else {
+				block.Content = contentToBlocks(msg.Content)
+			}
+			appendBlocks("user", []AnthropicContentBlock{block})
+		case "assistant":
+			// thinking blocks must come first and be sent back unchanged, otherwise the API rejects
+			// tool use follow-ups when extended thinking is enabled
+			blocks := thinkingToBlocks(msg.Thinking)
+			blocks = append(blocks, contentToBlocks(msg.Content)...)
+			for _, tc := range msg.ToolCalls {
+				input := json.RawMessage("{}")
+				if args := strings.TrimSpace(tc.Function.Arguments); args != "" {
+					if !json.Valid([]byte(args)) {
+						return "", nil, fmt.Errorf("anthropic: invalid arguments for tool call %s", tc.ID)
+					}
+					input = json.RawMessage(args)
+				}
+				blocks = append(blocks, AnthropicContentBlock{
+					Type:  "tool_use",
+					ID:    tc.ID,
+					Name:  tc.Function.Name,
+					Input: input,
+				})
+			}
+			appendBlocks("assistant", blocks)
+		default:
+			appendBlocks("user", contentToBlocks(msg.Content))
+		}
+	}
+	return strings.Join(system, "\n\n"), out, nil
+}
+
+// thinkingToBlocks converts the thinking blocks of an assistant message back to thinking / redacted_thinking blocks
+func thinkingToBlocks(thinking []llm.ThinkingBlock) []AnthropicContentBlock {
+	blocks := make([]AnthropicContentBlock, 0, len(thinking))
+	for _, t := range thinking {
+		if t.RedactedData != "" {
+			blocks = append(blocks, AnthropicContentBlock{Type: "redacted_thinking", Data: t.RedactedData})
+			continue
+		}
+		blocks = append(blocks, AnthropicContentBlock{Type: "thinking", Thinking: t.Thinking, Signature: t.Signature})
+	}
+	return blocks
+}
+
+// contentText returns the text of llm.Message.Content, text parts of multimodal content are joined
+func contentText(content interface{}) string {
+	if s, ok := content.(string); ok {
+		return s
+	}
+	var texts []string
+	for _, block := range contentToBlocks(content) {
+		if block.Type == "text" {
+			texts = append(texts, block.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// contentToBlocks converts llm.Message.Content to content blocks, empty text is dropped as the API rejects it
+// Supports:
+// - string: simple text content
+// - []interface{}: multimodal content array with format:
+//   - {"type": "text", "text": "..."}
+//   - {"type": "image_url", "image_url": {"url": "data:<mime>;base64,<data>" or "https://..."}}
+func contentToBlocks(content interface{}) []AnthropicContentBlock {
+	if content == nil {
+		return nil
+	}
+	if s, ok := content.(string); ok {
+		if s == "" {
+			return nil
+		}
+		return []AnthropicContentBlock{{Type: "text", Text: s}}
+	}
+
+	arr, ok := content.([]interface{})
+	if !ok {
+		// Fallback: convert to string
+		return []AnthropicContentBlock{{Type: "text", Text: llm.ContentString(content)}}
+	}
+
+	blocks := make([]AnthropicContentBlock, 0, len(arr))
+	for _, item := range arr {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		itemType, _ := m["type"].(string)
+		switch itemType {
+		case "text":
+			if text, ok := m["text"].(string); ok && text != "" {
+				blocks = append(blocks, AnthropicContentBlock{Type: "text", Text: text})
+			}
+		case "image_url":
+			imageURL, ok := m["image_url"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			url, ok := imageURL["url"].(string)
+			if !ok {
+				continue
+			}
+			if source := toImageSource(url); source != nil {
+				blocks = append(blocks, AnthropicContentBlock{Type: "image", Source: source})
+			}
+		}
+	}
+	return blocks
+}
+
+// toImageSource parses a data URI (data:<mime>;base64,<data>) or an http(s) URL into an image source
+func toImageSource(url string) *AnthropicImageSource {
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		return &AnthropicImageSource{Type: "url", URL: url}
+	}
+	const prefix = "data:"
+	if !strings.HasPrefix(url, prefix) {
+		return nil
+	}
+	rest := url[len(prefix):]
+	idx := strings.Index(rest, ";base64,")
+	if idx == -1 {
+		return nil
+	}
+	return &AnthropicImageSource{
+		Type:      "base64",
+		MediaType: rest[:idx],
+		Data:      rest[idx+len(";base64,"):],
+	}
+}
+
+// toAnthropicTools converts OpenAI compatible tool definitions
+// ({"type": "function", "function": {"name": ..., "description": ..., "parameters": ...}})
+// into Anthropic tools
+func toAnthropicTools(tools []map[string]interface{}) ([]AnthropicTool, error) {
+	if len(tools) == 0 {
+		return nil, nil
+	}
+	out := make([]AnthropicTool, 0, len(tools))
+	for _, t := range tools {
+		raw, err := json.Marshal(t)
+		if err != nil {
+			return nil, fmt.Errorf("anthropic: marshalling tool definition: %w", err)
+		}
+		var def struct {
+			Type     string `json:"type"`
+			Function struct {
+				Name        string      `json:"name"`
+				Description string      `json:"description"`
+				Parameters  interface{} `json:"parameters"`
+			} `json:"function"`
+		}
+		if err := json.Unmarshal(raw, &def); err != nil {
+			return nil, fmt.Errorf("anthropic: parsing tool definition: %w", err)
+		}
+		if def.Type != "" && def.Type != "function" {
+			continue
+		}
+		if def.Function.Name == "" {
+			return nil, fmt.Errorf("anthropic: tool definition missing function name")
+		}
+		schema := def.Function.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		out = append(out, AnthropicTool{
+			Name:        def.Function.Name,
+			Description: def.Function.Description,
+			InputSchema: schema,
+		})
+	}
+	return out, nil
+}
+
+// toAnthropicToolChoice maps the OpenAI compatible tool_choice ("auto", "none", "required"
+// or {"type": "function", "function": {"name": ...}}) to the Anthropic tool_choice
+func toAnthropicToolChoice(choice interface{}) *AnthropicToolChoice {
+	if choice == nil {
+		return nil
+	}
+	if s, ok := choice.(string); ok {
+		switch s {
+		case "auto", "none", "any":
+			return &AnthropicToolChoice{Type: s}
+		case "required":
+			return &AnthropicToolChoice{Type: "any"}
+		default:
+			return nil
+		}
+	}
+	raw, err := json.Marshal(choice)
+	if err != nil {
+		return nil
+	}
+	var named struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(raw, &named); err != nil || named.Function.Name == "" {
+		return nil
+	}
+	return &AnthropicToolChoice{Type: "tool", Name: named.Function.Name}
+}
+
+// toFinishReason maps Anthropic stop reasons to the OpenAI style finish reasons used by llm.ChatResponse
+func toFinishReason(stopReason string) string {
+	switch stopReason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "refusal":
+		return "content_filter"
+	default:
+		return stopReason
+	}
+}
+
+func toLLMUsage(u AnthropicUsage) llm.Usage {
+	prompt := u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+	return llm.Usage{
+		PromptTokens:     prompt,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      prompt + u.OutputTokens,
+	}
+}
+
+func toLLMChatResponse(resp *AnthropicMessagesResponse, exclude bool) *llm.ChatResponse {
+	var content strings.Builder
+	var reasoning strings.Builder
+	var toolCalls []llm.ToolCall
+	var thinking []llm.ThinkingBlock
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			content.WriteString(block.Text)
+		case "thinking":
+			thinking = append(thinking, llm.ThinkingBlock{Thinking: block.Thinking, Signature: block.Signature})
+			if !exclude {
+				reasoning.WriteString(block.Thinking)
+			}
+		case "redacted_thinking":
+			thinking = append(thinking, llm.ThinkingBlock{RedactedData: block.Data})
+		case "tool_use":
+			args := "{}"
+			if len(block.Input) > 0 {
+				args = string(block.Input)
+			}
+			toolCalls = append(toolCalls, llm.ToolCall{
+				ID:   block.ID,
+				Type: "function",
+				Function: llm.FunctionCall{
+					Name:      block.Name,
+					Arguments: args,
+				},
+			})
+		}
+	}
+	return &llm.ChatResponse{
+		Content:      content.String(),
+		Reasoning:    reasoning.String(),
+		ToolCalls:    toolCalls,
+		Thinking:     thinking,
+		FinishReason: toFinishReason(resp.StopReason),
+		Usage:        toLLMUsage(resp.Usage),
+	}
+}
+
+// Ensure *Provider implements the interfaces
+var _ llm.Chatter = (*Provider)(nil)
+var _ llm.EventStreamer = (*Provider)(nil)
@@ -0,0 +1,70 @@
+// Package anthropic an provider for the anthropic messages api
+package anthropic
+
+import (
+	"fmt"
+	"time"
+
+	xhttp "github.com/jessewkun/gocommon/http"
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/spf13/cast"
+)
+
+const providerName = "anthropic"
+
+const (
+	defaultAPIURL    = "https://api.anthropic.com/v1"
+	defaultVersion   = "2023-06-01"
+	defaultMaxTokens = 4096
+)
+
+func init() {
+	llm.Register(providerName, func(config interface{}) (llm.Provider, error) {
+		cfg, err := parseConfig(config)
+		if err != nil {
+			return nil, fmt.Errorf("parsing anthropic config: %w", err)
+		}
+		// The client timeout will be overridden by the per-request timeout from cfg.Timeout
+		client := xhttp.NewClient(xhttp.Option{})
+		return NewProvider(client, cfg), nil
+	})
+}
+
+// parseConfig supports Config or map for flexibility
+func parseConfig(config interface{}) (Config, error) {
+	if c, ok := config.(Config); ok {
+		return withDefaults(c), nil
+	}
+	m, ok := config.(map[string]interface{})
+	if !ok {
+		return Config{}, fmt.Errorf("config must be of type anthropic.Config or map[string]interface{}")
+	}
+	cfg := Config{
+		APIKey:    cast.ToString(m["api_key"]),
+		APIURL:    cast.ToString(m["api_url"]),
+		Version:   cast.ToString(m["version"]),
+		MaxTokens: cast.ToInt(m["max_tokens"]),
+	}
+	if cfg.APIKey == "" {
+		return Config{}, fmt.Errorf("api_key is required for anthropic provider")
+	}
+	if v, has := m["timeout"]; has {
+		if d, ok := v.(time.Duration); ok {
+			cfg.Timeout = d
+		}
+	}
+	return withDefaults(cfg), nil
+}
+
+func withDefaults(c Config) Config {
+	if c.APIURL == "" {
+		c.APIURL = defaultAPIURL
+	}
+	if c.Version == "" {
+		c.Version = defaultVersion
+	}
+	if c.MaxTokens <= 0 {
+		c.MaxTokens = defaultMaxTokens
+	}
+	return c
+}
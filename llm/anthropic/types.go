@@ -0,0 +1,173 @@
+// Package anthropic an provider for the anthropic messages api
+package anthropic
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	xhttp "github.com/jessewkun/gocommon/http"
+)
+
+// Config for the Anthropic provider
+type Config struct {
+	APIKey    string        `mapstructure:"api_key"`
+	APIURL    string        `mapstructure:"api_url"`    // API Base URL, defaults to "https://api.anthropic.com/v1"
+	Version   string        `mapstructure:"version"`    // anthropic-version header, defaults to "2023-06-01"
+	MaxTokens int           `mapstructure:"max_tokens"` // Default max_tokens when the request does not set one (required by the API), defaults to 4096
+	Timeout   time.Duration `mapstructure:"timeout"`
+}
+
+// AnthropicImageSource is the source of an image content block
+type AnthropicImageSource struct {
+	Type      string `json:"type"`                 // "base64" or "url"
+	MediaType string `json:"media_type,omitempty"` // e.g. "image/png", only for base64
+	Data      string `json:"data,omitempty"`       // base64 encoded data, only for base64
+	URL       string `json:"url,omitempty"`        // only for url
+}
+
+// AnthropicContentBlock is a single content block of a message.
+// Depending on Type only a subset of the fields is used:
+//   - text: Text
+//   - image: Source
+//   - tool_use: ID, Name, Input
+//   - tool_result: ToolUseID, Content, IsError
+//   - thinking: Thinking, Signature
+//   - redacted_thinking: Data
+type AnthropicContentBlock struct {
+	Type      string                `json:"type"`
+	Text      string                `json:"text,omitempty"`
+	Source    *AnthropicImageSource `json:"source,omitempty"`
+	ID        string                `json:"id,omitempty"`
+	Name      string                `json:"name,omitempty"`
+	Input     json.RawMessage       `json:"input,omitempty"`
+	ToolUseID string                `json:"tool_use_id,omitempty"`
+	Content   interface{}           `json:"content,omitempty"` // string or []AnthropicContentBlock
+	IsError   bool                  `json:"is_error,omitempty"`
+	Thinking  string                `json:"thinking,omitempty"`
+	Signature string                `json:"signature,omitempty"`
+	Data      string                `json:"data,omitempty"`
+}
+
+// AnthropicMessage is a single message of the conversation, role is "user" or "assistant"
+type AnthropicMessage struct {
+	Role    string                  `json:"role"`
+	Content []AnthropicContentBlock `json:"content"`
+}
+
+// AnthropicTool describes a tool the model may use
+type AnthropicTool struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	InputSchema interface{} `json:"input_schema"`
+}
+
+// AnthropicToolChoice controls how the model uses the tools
+type AnthropicToolChoice struct {
+	Type string `json:"type"`           // "auto", "any", "tool" or "none"
+	Name string `json:"name,omitempty"` // only for "tool"
+}
+
+// AnthropicThinking is the extended thinking configuration
+type AnthropicThinking struct {
+	Type         string `json:"type"`                    // "enabled" or "disabled"
+	BudgetTokens int    `json:"budget_tokens,omitempty"` // must be >= 1024 and less than max_tokens
+}
+
+// AnthropicMetadata is the request metadata
+type AnthropicMetadata struct {
+	UserID string `json:"user_id,omitempty"`
+}
+
+// AnthropicMessagesRequest is the request body of POST /v1/messages
+type AnthropicMessagesRequest struct {
+	Model         string               `json:"model"`
+	System        string               `json:"system,omitempty"`
+	Messages      []AnthropicMessage   `json:"messages"`
+	MaxTokens     int                  `json:"max_tokens"`
+	Temperature   *float64             `json:"temperature,omitempty"`
+	TopP          *float64             `json:"top_p,omitempty"`
+	TopK          *int                 `json:"top_k,omitempty"`
+	StopSequences []string             `json:"stop_sequences,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	Tools         []AnthropicTool      `json:"tools,omitempty"`
+	ToolChoice    *AnthropicToolChoice `json:"tool_choice,omitempty"`
+	Thinking      *AnthropicThinking   `json:"thinking,omitempty"`
+	Metadata      *AnthropicMetadata   `json:"metadata,omitempty"`
+}
+
+// AnthropicUsage is the token usage reported by the API
+type AnthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// AnthropicError is the error object returned by the API
+type AnthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+
+	status     int           // HTTP status code of the response carrying the error
+	retryAfter time.Duration // parsed from the Retry-After response header
+}
+
+// Error implements error
+func (e *AnthropicError) Error() string {
+	return e.Type + ": " + e.Message
+}
+
+// StatusCode returns the HTTP status code of the response, 0 if unknown
+func (e *AnthropicError) StatusCode() int {
+	return e.status
+}
+
+// RetryAfter returns the delay suggested by the Retry-After response header, 0 if absent
+func (e *AnthropicError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// RateLimited reports whether the error is caused by rate limiting
+func (e *AnthropicError) RateLimited() bool {
+	return e.status == http.StatusTooManyRequests || e.Type == "rate_limit_error"
+}
+
+func (e *AnthropicError) withResponse(resp *xhttp.Response) *AnthropicError {
+	e.status = resp.StatusCode
+	e.retryAfter = xhttp.ParseRetryAfter(resp.Header)
+	return e
+}
+
+// AnthropicMessagesResponse is the response body of POST /v1/messages
+type AnthropicMessagesResponse struct {
+	ID         string                  `json:"id"`
+	Type       string                  `json:"type"` // "message" or "error"
+	Role       string                  `json:"role"`
+	Model      string                  `json:"model"`
+	Content    []AnthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      AnthropicUsage          `json:"usage"`
+	Error      *AnthropicError         `json:"error,omitempty"`
+}
+
+// AnthropicStreamDelta is the delta of content_block_delta and message_delta events
+type AnthropicStreamDelta struct {
+	Type        string `json:"type"` // "text_delta", "input_json_delta", "thinking_delta" or "signature_delta"
+	Text        string `json:"text,omitempty"`
+	PartialJSON string `json:"partial_json,omitempty"`
+	Thinking    string `json:"thinking,omitempty"`
+	Signature   string `json:"signature,omitempty"`
+	StopReason  string `json:"stop_reason,omitempty"` // only for message_delta
+}
+
+// AnthropicStreamEvent is the data of a single server-sent event
+type AnthropicStreamEvent struct {
+	Type         string                     `json:"type"`
+	Message      *AnthropicMessagesResponse `json:"message,omitempty"`       // message_start
+	Index        int                        `json:"index"`                   // content_block_*
+	ContentBlock *AnthropicContentBlock     `json:"content_block,omitempty"` // content_block_start
+	Delta        *AnthropicStreamDelta      `json:"delta,omitempty"`         // content_block_delta, message_delta
+	Usage        *AnthropicUsage            `json:"usage,omitempty"`         // message_delta
+	Error        *AnthropicError            `json:"error,omitempty"`         // error
+}
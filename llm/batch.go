@@ -0,0 +1,250 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jessewkun/gocommon/safego"
+)
+
+// BatchConfig 批量向量化配置
+type BatchConfig struct {
+	MaxInputs   int                  // 单个请求的最大文本条数，默认按 Provider 取值（openai 2048，gemini 100，其他 100）
+	MaxTokens   int                  // 单个请求的最大 token 数（估算值），默认按 Provider 取值（openai 300000，其他 100000），小于 0 表示不限制
+	Concurrency int                  // 并发请求数，默认 4
+	MaxRetries  int                  // 单个批次失败后的最大重试次数，默认 2，小于 0 表示不重试
+	BaseDelay   time.Duration        // 首次重试的等待时间，之后按指数增长，默认 500ms
+	MaxDelay    time.Duration        // 单次等待时间上限，默认 10s
+	ShouldRetry func(err error) bool // 判断错误是否需要重试，默认除 429 以外的 4xx 错误均不重试
+	Tokenizer   *Tokenizer           // token 估算方式，默认 TokenizerFor(provider, model)
+}
+
+// embeddingLimits 各 Provider 单个向量化请求的默认限制
+var embeddingLimits = map[string]struct{ inputs, tokens int }{
+	"openai":     {2048, 300000},
+	"openrouter": {2048, 300000},
+	"gemini":     {100, 100000},
+}
+
+// BatchEmbed 将 req.Input 按条数与 token 数拆分为多个批次，使用有限的并发请求 embedder，并按原始顺序合并结果
+// 返回结果中 Data[i].Index 即为 req.Input 中的下标，Usage 为所有批次的累计用量；req 本身不会被修改
+// 任一批次重试耗尽后立即取消其余批次并返回错误
+func BatchEmbed(ctx context.Context, embedder Embedder, req *EmbeddingRequest, cfg BatchConfig) (*EmbeddingResponse, error) {
+	return batchEmbed(ctx, embedder.Name(), embedder.CreateEmbeddings, req, cfg)
+}
+
+// CreateEmbeddingsBatch 与 BatchEmbed 相同，每个批次都会经过 Client 的拦截器与钩子
+func (c *Client) CreateEmbeddingsBatch(ctx context.Context, req *EmbeddingRequest, cfg BatchConfig) (*EmbeddingResponse, error) {
+	if _, ok := c.provider.(Embedder); !ok {
+		return nil, fmt.Errorf("llm: provider %q does not support embeddings", c.provider.Name())
+	}
+	return batchEmbed(ctx, c.provider.Name(), c.CreateEmbeddings, req, cfg)
+}
+
+type embedFunc func(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error)
+
+// embeddingBatch 一个批次在原始输入中的范围 [start, end)
+type embeddingBatch struct {
+	start, end int
+}
+
+func batchEmbed(ctx context.Context, provider string, embed embedFunc, req *EmbeddingRequest, cfg BatchConfig) (*EmbeddingResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("llm: embedding request cannot be nil")
+	}
+	cfg = batchConfigWithDefaults(provider, req.Model, cfg)
+	result := &EmbeddingResponse{Model: req.Model, Data: make([]Embedding, len(req.Input))}
+	if len(req.Input) == 0 {
+		return result, nil
+	}
+
+	batches := splitEmbeddingBatches(req.Input, cfg)
+	responses := make([]*EmbeddingResponse, len(batches))
+	errs := make([]error, len(batches))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sem := make(chan struct{}, cfg.Concurrency)
+	var wg safego.WaitGroupWrapper
+	for i, b := range batches {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		i, b := i, b
+		wg.Wrap(ctx, func() {
+			defer func() {
+				<-sem
+				if errs[i] != nil {
+					cancel()
+				}
+			}()
+			// embed panic 时由 safego 捕获，该批次以此错误结束
+			errs[i] = fmt.Errorf("llm: embedding batch [%d, %d) panicked", b.start, b.end)
+			resp, err := embedWithRetry(ctx, embed, &EmbeddingRequest{Model: req.Model, Input: req.Input[b.start:b.end]}, cfg)
+			if err != nil {
+				err = fmt.Errorf("llm: embedding batch [%d, %d): %w", b.start, b.end, err)
+			}
+			responses[i], errs[i] = resp, err
+		})
+	}
+	wg.Wait()
+
+	// 优先返回真正失败的批次，而不是因取消而失败的批次
+	var firstErr error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if firstErr == nil || (errors.Is(firstErr, context.Canceled) && !errors.Is(err, context.Canceled)) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	for i, b := range batches {
+		resp := responses[i]
+		if resp == nil {
+			// 外部 ctx 在派发完所有批次前被取消
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("llm: embedding batch [%d, %d) returned no response", b.start, b.end)
+		}
+		if len(resp.Data) != b.end-b.start {
+			return nil, fmt.Errorf("llm: embedding batch [%d, %d) returned %d embeddings", b.start, b.end, len(resp.Data))
+		}
+		// Provider 返回的 Index 是批次内的下标，有越界或重复时整个批次按返回顺序处理
+		positional := !validEmbeddingIndexes(resp.Data)
+		for j, emb := range resp.Data {
+			k := emb.Index
+			if positional {
+				k = j
+			}
+			emb.Index = b.start + k
+			result.Data[b.start+k] = emb
+		}
+		if result.Model == "" {
+			result.Model = resp.Model
+		}
+		result.Backend = resp.Backend
+		result.Usage.PromptTokens += resp.Usage.PromptTokens
+		result.Usage.CompletionTokens += resp.Usage.CompletionTokens
+		result.Usage.TotalTokens += resp.Usage.TotalTokens
+	}
+	return result, nil
+}
+
+// validEmbeddingIndexes 判断 Index 是否恰好覆盖 [0, len(data))
+func validEmbeddingIndexes(data []Embedding) bool {
+	seen := make([]bool, len(data))
+	for _, emb := range data {
+		if emb.Index < 0 || emb.Index >= len(data) || seen[emb.Index] {
+			return false
+		}
+		seen[emb.Index] = true
+	}
+	return true
+}
+
+func batchConfigWithDefaults(provider, model string, cfg BatchConfig) BatchConfig {
+	limits, ok := embeddingLimits[provider]
+	if !ok {
+		limits.inputs, limits.tokens = 100, 100000
+	}
+	if cfg.MaxInputs <= 0 {
+		cfg.MaxInputs = limits.inputs
+	}
+	if cfg.MaxTokens == 0 {
+		cfg.MaxTokens = limits.tokens
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 2
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = 500 * time.Millisecond
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = 10 * time.Second
+	}
+	if cfg.ShouldRetry == nil {
+		cfg.ShouldRetry = shouldRetryEmbedding
+	}
+	if cfg.Tokenizer == nil {
+		cfg.Tokenizer = TokenizerFor(provider, model)
+	}
+	return cfg
+}
+
+// splitEmbeddingBatches 按顺序切分批次，单条文本超出 MaxTokens 时单独成为一个批次
+func splitEmbeddingBatches(input []string, cfg BatchConfig) []embeddingBatch {
+	var batches []embeddingBatch
+	start, tokens := 0, 0
+	for i, text := range input {
+		n := 0
+		if cfg.MaxTokens > 0 {
+			n = cfg.Tokenizer.CountText(text)
+		}
+		if i > start && (i-start >= cfg.MaxInputs || (cfg.MaxTokens > 0 && tokens+n > cfg.MaxTokens)) {
+			batches = append(batches, embeddingBatch{start, i})
+			start, tokens = i, 0
+		}
+		tokens += n
+	}
+	return append(batches, embeddingBatch{start, len(input)})
+}
+
+func embedWithRetry(ctx context.Context, embed embedFunc, req *EmbeddingRequest, cfg BatchConfig) (*EmbeddingResponse, error) {
+	for i := 0; ; i++ {
+		resp, err := embed(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if i >= cfg.MaxRetries || ctx.Err() != nil || !cfg.ShouldRetry(err) {
+			return nil, err
+		}
+
+		delay := RetryAfter(err)
+		if delay <= 0 {
+			delay = cfg.BaseDelay << uint(i)
+			// 在 [d/2, d) 之间随机，避免多个批次同时重试
+			delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		}
+		if delay <= 0 || delay > cfg.MaxDelay {
+			delay = cfg.MaxDelay
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// shouldRetryEmbedding 除 429 以外的 4xx 错误（参数错误、鉴权失败等）重试无意义，其余错误均重试
+func shouldRetryEmbedding(err error) bool {
+	if IsRateLimited(err) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var sc interface{ StatusCode() int }
+	if errors.As(err, &sc) && sc.StatusCode() >= 400 && sc.StatusCode() < 500 {
+		return false
+	}
+	return true
+}
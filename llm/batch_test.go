@@ -0,0 +1,158 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchEmbedder 将文本 "n" 向量化为 [n]，逆序返回以验证按 Index 合并；可按批次首条文本注入失败
+type batchEmbedder struct {
+	mu       sync.Mutex
+	batches  [][]string
+	failures map[string]int // 批次首条文本 -> 剩余失败次数
+	active   int32
+	peak     int32
+}
+
+func (e *batchEmbedder) Name() string { return "batch" }
+
+func (e *batchEmbedder) CreateEmbeddings(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	n := atomic.AddInt32(&e.active, 1)
+	defer atomic.AddInt32(&e.active, -1)
+	for {
+		peak := atomic.LoadInt32(&e.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&e.peak, peak, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	e.mu.Lock()
+	e.batches = append(e.batches, req.Input)
+	if e.failures[req.Input[0]] > 0 {
+		e.failures[req.Input[0]]--
+		e.mu.Unlock()
+		return nil, fmt.Errorf("batch %s failed", req.Input[0])
+	}
+	e.mu.Unlock()
+
+	resp := &EmbeddingResponse{Model: req.Model, Usage: Usage{PromptTokens: len(req.Input), TotalTokens: len(req.Input)}}
+	for i := len(req.Input) - 1; i >= 0; i-- {
+		v, _ := strconv.Atoi(req.Input[i])
+		resp.Data = append(resp.Data, Embedding{Index: i, Vector: []float32{float32(v)}})
+	}
+	return resp, nil
+}
+
+func numberedInput(n int) []string {
+	input := make([]string, n)
+	for i := range input {
+		input[i] = strconv.Itoa(i)
+	}
+	return input
+}
+
+func TestBatchEmbed(t *testing.T) {
+	embedder := &batchEmbedder{failures: map[string]int{"30": 1}}
+	req := &EmbeddingRequest{Model: "m", Input: numberedInput(95)}
+
+	resp, err := BatchEmbed(context.Background(), embedder, req, BatchConfig{MaxInputs: 10, Concurrency: 3, BaseDelay: time.Millisecond})
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 95)
+	for i, emb := range resp.Data {
+		assert.Equal(t, i, emb.Index)
+		assert.Equal(t, []float32{float32(i)}, emb.Vector)
+	}
+	assert.Equal(t, 95, resp.Usage.TotalTokens)
+	assert.Equal(t, "m", resp.Model)
+	assert.Len(t, embedder.batches, 11, "10 batches plus one retry")
+	assert.LessOrEqual(t, embedder.peak, int32(3))
+	assert.Greater(t, embedder.peak, int32(1))
+}
+
+func TestBatchEmbed_SplitByTokens(t *testing.T) {
+	input := []string{"a b c", "d e", "f", "g h i j k", "l"}
+	batches := splitEmbeddingBatches(input, BatchConfig{MaxInputs: 10, MaxTokens: 4, Tokenizer: TokenizerOpenAI})
+	// 第 4 条文本单独超出上限，自成一个批次
+	assert.Equal(t, []embeddingBatch{{0, 1}, {1, 3}, {3, 4}, {4, 5}}, batches)
+
+	batches = splitEmbeddingBatches(input, BatchConfig{MaxInputs: 2, MaxTokens: -1})
+	assert.Equal(t, []embeddingBatch{{0, 2}, {2, 4}, {4, 5}}, batches)
+}
+
+// sameIndexEmbedder 按顺序返回向量，但所有 Index 均为 0
+type sameIndexEmbedder struct{}
+
+func (sameIndexEmbedder) Name() string { return "same-index" }
+
+func (sameIndexEmbedder) CreateEmbeddings(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	resp := &EmbeddingResponse{Model: req.Model}
+	for _, text := range req.Input {
+		v, _ := strconv.Atoi(text)
+		resp.Data = append(resp.Data, Embedding{Vector: []float32{float32(v)}})
+	}
+	return resp, nil
+}
+
+func TestBatchEmbed_DuplicateIndexes(t *testing.T) {
+	resp, err := BatchEmbed(context.Background(), sameIndexEmbedder{}, &EmbeddingRequest{Input: numberedInput(7)}, BatchConfig{MaxInputs: 3})
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 7)
+	// Index 重复时按返回顺序合并，不会留下空向量
+	for i, emb := range resp.Data {
+		assert.Equal(t, i, emb.Index)
+		assert.Equal(t, []float32{float32(i)}, emb.Vector)
+	}
+}
+
+func TestBatchEmbed_Failure(t *testing.T) {
+	embedder := &batchEmbedder{failures: map[string]int{"20": 5}}
+	_, err := BatchEmbed(context.Background(), embedder, &EmbeddingRequest{Input: numberedInput(50)},
+		BatchConfig{MaxInputs: 10, Concurrency: 1, MaxRetries: 1, BaseDelay: time.Millisecond})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "embedding batch [20, 30): batch 20 failed")
+	// 并发为 1 时，失败批次之后的批次不会再被派发
+	assert.Len(t, embedder.batches, 4)
+
+	// 4xx 参数错误不重试
+	assert.False(t, shouldRetryEmbedding(statusError(400)))
+	assert.True(t, shouldRetryEmbedding(statusError(429)))
+	assert.True(t, shouldRetryEmbedding(statusError(503)))
+	assert.True(t, shouldRetryEmbedding(errors.New("connection reset")))
+}
+
+func TestClient_CreateEmbeddingsBatch(t *testing.T) {
+	var calls int32
+	client := &Client{provider: &batchEmbedder{}}
+	client.Use(&countingHook{calls: &calls})
+	resp, err := client.CreateEmbeddingsBatch(context.Background(), &EmbeddingRequest{Input: numberedInput(5)}, BatchConfig{MaxInputs: 2})
+	require.NoError(t, err)
+	assert.Len(t, resp.Data, 5)
+	assert.Equal(t, int32(3), calls, "hooks run once per batch")
+
+	_, err = (&Client{provider: &scriptedChatter{}}).CreateEmbeddingsBatch(context.Background(), &EmbeddingRequest{Input: []string{"a"}}, BatchConfig{})
+	assert.EqualError(t, err, `llm: provider "scripted" does not support embeddings`)
+}
+
+type statusError int
+
+func (e statusError) Error() string   { return "status " + strconv.Itoa(int(e)) }
+func (e statusError) StatusCode() int { return int(e) }
+
+type countingHook struct{ calls *int32 }
+
+func (h *countingHook) Before(ctx context.Context, info *CallInfo) error {
+	atomic.AddInt32(h.calls, 1)
+	return nil
+}
+
+func (h *countingHook) After(ctx context.Context, info *CallInfo, usage Usage, err error) {}
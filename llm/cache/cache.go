@@ -0,0 +1,238 @@
+// Package cache 以拦截器的方式为 llm.Client 提供 Chat 与 CreateEmbeddings 的响应缓存
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/jessewkun/gocommon/logger"
+)
+
+const TAG = "LLM_CACHE"
+
+// keyVersion 缓存 key 的版本号，请求或响应结构不兼容变更时递增，使旧缓存失效
+const keyVersion = "v1"
+
+// Config 缓存配置
+type Config struct {
+	KeyPrefix    string        // 缓存 key 前缀，默认 llm:cache
+	ChatTTL      time.Duration // Chat 响应的缓存时间，默认 1 小时
+	EmbeddingTTL time.Duration // Embedding 响应的缓存时间，默认 24 小时
+	// CacheAllChat 为 false 时只缓存确定性的 Chat 请求（Temperature 为 0 或设置了 Seed）
+	CacheAllChat bool
+}
+
+// Stats 缓存命中统计
+type Stats struct {
+	ChatHits        int64 `json:"chat_hits"`        // Chat 命中次数
+	ChatMisses      int64 `json:"chat_misses"`      // Chat 未命中次数
+	EmbeddingHits   int64 `json:"embedding_hits"`   // Embedding 命中次数
+	EmbeddingMisses int64 `json:"embedding_misses"` // Embedding 未命中次数
+	Errors          int64 `json:"errors"`           // 存储读写或序列化失败次数
+}
+
+// HitRate 总命中率
+func (s Stats) HitRate() float64 {
+	hits := s.ChatHits + s.EmbeddingHits
+	total := hits + s.ChatMisses + s.EmbeddingMisses
+	if total == 0 {
+		return 0
+	}
+	return float64(hits) / float64(total)
+}
+
+// Cache Chat 与 CreateEmbeddings 的响应缓存，通过 Interceptor 注册到 llm.Client 后生效
+// Agent、ChatJSON、CreateEmbeddingsBatch 等基于 llm.Client 的调用都会经过缓存；流式调用等其他请求直接透传
+// 命中缓存时不会调用 Provider，也不会触发 llm.Client 上注册的钩子；返回的 Usage 为首次调用时的用量
+// 存储异常时直接调用 Provider，不影响业务
+type Cache struct {
+	store Store
+	cfg   Config
+	stats Stats
+}
+
+// New 使用指定存储创建缓存
+func New(store Store, cfg Config) *Cache {
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = "llm:cache"
+	}
+	if cfg.ChatTTL <= 0 {
+		cfg.ChatTTL = time.Hour
+	}
+	if cfg.EmbeddingTTL <= 0 {
+		cfg.EmbeddingTTL = 24 * time.Hour
+	}
+	return &Cache{store: store, cfg: cfg}
+}
+
+type ctxKey int
+
+const (
+	bypassKey ctxKey = iota
+	ttlKey
+)
+
+// WithBypass 使本次调用跳过缓存，既不读取也不写入
+func WithBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey, true)
+}
+
+// WithTTL 为本次调用指定缓存时间，覆盖 Config 中的默认值
+func WithTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, ttlKey, ttl)
+}
+
+func bypass(ctx context.Context) bool {
+	b, _ := ctx.Value(bypassKey).(bool)
+	return b
+}
+
+func ttlFromContext(ctx context.Context, def time.Duration) time.Duration {
+	if ttl, ok := ctx.Value(ttlKey).(time.Duration); ok && ttl > 0 {
+		return ttl
+	}
+	return def
+}
+
+// Interceptor 返回缓存拦截器，通过 client.Intercept 注册
+// 建议注册在重试等拦截器外层，命中缓存时不再经过内层拦截器
+func (c *Cache) Interceptor() llm.Interceptor {
+	return func(next llm.Handler) llm.Handler {
+		return func(ctx context.Context, call *llm.Call) (*llm.Result, error) {
+			if bypass(ctx) {
+				return next(ctx, call)
+			}
+			switch call.Operation {
+			case llm.OperationChat:
+				if !c.cacheable(call.Chat) {
+					return next(ctx, call)
+				}
+				return c.chat(ctx, call, next)
+			case llm.OperationEmbeddings:
+				return c.embeddings(ctx, call, next)
+			}
+			return next(ctx, call)
+		}
+	}
+}
+
+// chat 相同请求在有效期内直接返回缓存的响应
+func (c *Cache) chat(ctx context.Context, call *llm.Call, next llm.Handler) (*llm.Result, error) {
+	key, err := c.key(call.Provider, "chat", call.Chat)
+	if err != nil {
+		c.warn(ctx, "build chat cache key failed", err)
+		return next(ctx, call)
+	}
+
+	var cached llm.ChatResponse
+	if c.load(ctx, key, &cached) {
+		atomic.AddInt64(&c.stats.ChatHits, 1)
+		return &llm.Result{Chat: &cached}, nil
+	}
+	atomic.AddInt64(&c.stats.ChatMisses, 1)
+
+	result, err := next(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	c.save(ctx, key, result.Chat, ttlFromContext(ctx, c.cfg.ChatTTL))
+	return result, nil
+}
+
+// embeddings 相同请求在有效期内直接返回缓存的响应
+func (c *Cache) embeddings(ctx context.Context, call *llm.Call, next llm.Handler) (*llm.Result, error) {
+	key, err := c.key(call.Provider, "embeddings", call.Embedding)
+	if err != nil {
+		c.warn(ctx, "build embeddings cache key failed", err)
+		return next(ctx, call)
+	}
+
+	var cached llm.EmbeddingResponse
+	if c.load(ctx, key, &cached) {
+		atomic.AddInt64(&c.stats.EmbeddingHits, 1)
+		return &llm.Result{Embedding: &cached}, nil
+	}
+	atomic.AddInt64(&c.stats.EmbeddingMisses, 1)
+
+	result, err := next(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	c.save(ctx, key, result.Embedding, ttlFromContext(ctx, c.cfg.EmbeddingTTL))
+	return result, nil
+}
+
+// Stats 获取缓存命中统计
+func (c *Cache) Stats() Stats {
+	return Stats{
+		ChatHits:        atomic.LoadInt64(&c.stats.ChatHits),
+		ChatMisses:      atomic.LoadInt64(&c.stats.ChatMisses),
+		EmbeddingHits:   atomic.LoadInt64(&c.stats.EmbeddingHits),
+		EmbeddingMisses: atomic.LoadInt64(&c.stats.EmbeddingMisses),
+		Errors:          atomic.LoadInt64(&c.stats.Errors),
+	}
+}
+
+// ResetStats 重置统计信息，不会清空缓存
+func (c *Cache) ResetStats() {
+	atomic.StoreInt64(&c.stats.ChatHits, 0)
+	atomic.StoreInt64(&c.stats.ChatMisses, 0)
+	atomic.StoreInt64(&c.stats.EmbeddingHits, 0)
+	atomic.StoreInt64(&c.stats.EmbeddingMisses, 0)
+	atomic.StoreInt64(&c.stats.Errors, 0)
+}
+
+// cacheable 判断 Chat 请求是否可以缓存
+func (c *Cache) cacheable(req *llm.ChatRequest) bool {
+	return c.cfg.CacheAllChat || req.Temperature == 0 || req.Seed != nil
+}
+
+// key 计算请求的缓存 key：对 Provider 名称与请求的 JSON 序列化结果做 sha256
+// encoding/json 对 map 按 key 排序输出，因此相同内容的请求总是得到相同的 key
+func (c *Cache) key(provider, kind string, req interface{}) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(keyVersion + "\x00" + provider + "\x00" + kind + "\x00"))
+	h.Write(data)
+	return c.cfg.KeyPrefix + ":" + kind + ":" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *Cache) load(ctx context.Context, key string, v interface{}) bool {
+	data, ok, err := c.store.Get(ctx, key)
+	if err != nil {
+		c.warn(ctx, "get llm cache failed", err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		c.warn(ctx, "decode llm cache failed", err)
+		return false
+	}
+	return true
+}
+
+func (c *Cache) save(ctx context.Context, key string, v interface{}, ttl time.Duration) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		c.warn(ctx, "encode llm cache failed", err)
+		return
+	}
+	if err := c.store.Set(ctx, key, data, ttl); err != nil {
+		c.warn(ctx, "set llm cache failed", err)
+	}
+}
+
+func (c *Cache) warn(ctx context.Context, msg string, err error) {
+	atomic.AddInt64(&c.stats.Errors, 1)
+	logger.WarnWithField(ctx, TAG, msg, map[string]interface{}{"err": err.Error()})
+}
@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jessewkun/gocommon/db/localcache"
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider 记录调用次数，每次返回不同内容以便区分是否命中缓存
+type fakeProvider struct {
+	chatCalls      int
+	embeddingCalls int
+}
+
+func (f *fakeProvider) Name() string { return "cache-fake" }
+
+func (f *fakeProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	f.chatCalls++
+	return &llm.ChatResponse{
+		Content:      fmt.Sprintf("reply %d", f.chatCalls),
+		FinishReason: "stop",
+		Usage:        llm.Usage{TotalTokens: 10},
+	}, nil
+}
+
+func (f *fakeProvider) ChatStream(ctx context.Context, req *llm.ChatRequest, callback func(chunk string) error) (*llm.ChatResponse, error) {
+	return f.Chat(ctx, req)
+}
+
+func (f *fakeProvider) CreateEmbeddings(ctx context.Context, req *llm.EmbeddingRequest) (*llm.EmbeddingResponse, error) {
+	f.embeddingCalls++
+	resp := &llm.EmbeddingResponse{Model: req.Model}
+	for i := range req.Input {
+		resp.Data = append(resp.Data, llm.Embedding{Index: i, Vector: []float32{float32(f.embeddingCalls), 0.5}})
+	}
+	return resp, nil
+}
+
+func newTestClient(t *testing.T, cfg Config) (*llm.Client, *Cache, *fakeProvider) {
+	f := &fakeProvider{}
+	name := fmt.Sprintf("cache-test-%s", t.Name())
+	llm.Register(name, func(config interface{}) (llm.Provider, error) { return f, nil })
+	client, err := llm.NewClient(name, nil)
+	require.NoError(t, err)
+	lc, err := localcache.NewDefaultBigCache()
+	require.NoError(t, err)
+	t.Cleanup(func() { lc.Close() })
+	c := New(NewLocalStore(lc), cfg)
+	client.Intercept(c.Interceptor())
+	return client, c, f
+}
+
+func TestCache_Chat(t *testing.T) {
+	client, c, f := newTestClient(t, Config{})
+	ctx := context.Background()
+	req := &llm.ChatRequest{
+		Model:    "m",
+		Messages: []llm.Message{{Role: "user", Content: "hi"}},
+		Tools:    []map[string]interface{}{{"type": "function", "function": map[string]interface{}{"name": "a", "description": "b"}}},
+	}
+
+	first, err := client.Chat(ctx, req)
+	require.NoError(t, err)
+	// 内容相同但 map 写入顺序不同的请求命中同一缓存
+	same := *req
+	same.Tools = []map[string]interface{}{{"function": map[string]interface{}{"description": "b", "name": "a"}, "type": "function"}}
+	second, err := client.Chat(ctx, &same)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, f.chatCalls)
+
+	// 不同的请求、跳过缓存以及非确定性请求都会调用 Provider
+	_, err = client.Chat(ctx, &llm.ChatRequest{Model: "m", Messages: []llm.Message{{Role: "user", Content: "hello"}}})
+	require.NoError(t, err)
+	resp, err := client.Chat(WithBypass(ctx), req)
+	require.NoError(t, err)
+	assert.Equal(t, "reply 3", resp.Content)
+	hot := *req
+	hot.Temperature = 0.7
+	_, err = client.Chat(ctx, &hot)
+	require.NoError(t, err)
+	_, err = client.Chat(ctx, &hot)
+	require.NoError(t, err)
+	assert.Equal(t, 5, f.chatCalls)
+
+	assert.Equal(t, Stats{ChatHits: 1, ChatMisses: 2}, c.Stats())
+	assert.InDelta(t, 1.0/3, c.Stats().HitRate(), 1e-9)
+	c.ResetStats()
+	assert.Equal(t, Stats{}, c.Stats())
+}
+
+func TestCache_CreateEmbeddings(t *testing.T) {
+	client, c, f := newTestClient(t, Config{EmbeddingTTL: 50 * time.Millisecond})
+	ctx := context.Background()
+	req := &llm.EmbeddingRequest{Model: "e", Input: []string{"a", "b"}}
+
+	first, err := client.CreateEmbeddings(ctx, req)
+	require.NoError(t, err)
+	second, err := client.CreateEmbeddings(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, f.embeddingCalls)
+
+	// 过期后重新调用 Provider
+	time.Sleep(80 * time.Millisecond)
+	third, err := client.CreateEmbeddings(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, float32(2), third.Data[0].Vector[0])
+
+	// 单次请求指定的 TTL 覆盖默认值
+	long := &llm.EmbeddingRequest{Model: "e", Input: []string{"c"}}
+	_, err = client.CreateEmbeddings(WithTTL(ctx, time.Hour), long)
+	require.NoError(t, err)
+	time.Sleep(80 * time.Millisecond)
+	_, err = client.CreateEmbeddings(ctx, long)
+	require.NoError(t, err)
+	assert.Equal(t, 3, f.embeddingCalls)
+	assert.Equal(t, Stats{EmbeddingHits: 2, EmbeddingMisses: 3}, c.Stats())
+}
+
+func TestCache_ClientHelpers(t *testing.T) {
+	client, c, f := newTestClient(t, Config{})
+	ctx := context.Background()
+
+	// 基于 *llm.Client 的辅助方法同样经过缓存
+	req := &llm.EmbeddingRequest{Model: "e", Input: []string{"a", "b", "c"}}
+	first, err := client.CreateEmbeddingsBatch(ctx, req, llm.BatchConfig{MaxInputs: 2, Concurrency: 1})
+	require.NoError(t, err)
+	second, err := client.CreateEmbeddingsBatch(ctx, req, llm.BatchConfig{MaxInputs: 2, Concurrency: 1})
+	require.NoError(t, err)
+	assert.Equal(t, first.Data, second.Data)
+	assert.Equal(t, 2, f.embeddingCalls)
+
+	agent := llm.NewAgent(client, llm.AgentConfig{})
+	chat := &llm.ChatRequest{Model: "m", Messages: []llm.Message{{Role: "user", Content: "hi"}}}
+	_, err = agent.Run(ctx, chat)
+	require.NoError(t, err)
+	_, err = agent.Run(ctx, chat)
+	require.NoError(t, err)
+	assert.Equal(t, 1, f.chatCalls)
+	assert.Equal(t, Stats{ChatHits: 1, ChatMisses: 1, EmbeddingHits: 2, EmbeddingMisses: 2}, c.Stats())
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/jessewkun/gocommon/db/localcache"
+	"github.com/jessewkun/gocommon/db/redis"
+)
+
+// Store 缓存存储，值为序列化后的响应
+type Store interface {
+	// Get 获取缓存，不存在时 ok 为 false
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set 写入缓存，ttl 为 0 表示不过期（受存储自身淘汰策略约束）
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// LocalStore 基于 db/localcache 的进程内存储
+type LocalStore struct {
+	cache localcache.Cache
+}
+
+var _ Store = (*LocalStore)(nil)
+
+// NewLocalStore 使用 localcache.Cache 创建存储
+// 注意 bigcache 的 LifeWindow 同样会淘汰数据，实际有效期取 TTL 与 LifeWindow 的较小值
+func NewLocalStore(c localcache.Cache) *LocalStore {
+	return &LocalStore{cache: c}
+}
+
+// Get 获取缓存
+func (s *LocalStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	v, ok := s.cache.Get(key)
+	if !ok {
+		return nil, false, nil
+	}
+	str, ok := v.(string)
+	if !ok {
+		return nil, false, fmt.Errorf("llm cache: unexpected value type %T", v)
+	}
+	return []byte(str), true, nil
+}
+
+// Set 写入缓存
+func (s *LocalStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	// localcache 以 JSON 序列化值，使用 string 保证读取时类型不变
+	return s.cache.SetWithTTL(key, string(value), ttl)
+}
+
+// RedisStore 基于 db/redis 的存储，适用于多实例共享缓存
+type RedisStore struct {
+	dbIns string
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// NewRedisStore 创建 redis 存储，dbIns 为 redis 模块中配置的实例名
+func NewRedisStore(dbIns string) *RedisStore {
+	return &RedisStore{dbIns: dbIns}
+}
+
+// Get 获取缓存
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	conn, err := redis.GetConn(s.dbIns)
+	if err != nil {
+		return nil, false, err
+	}
+	data, err := conn.Get(ctx, key).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Set 写入缓存
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	conn, err := redis.GetConn(s.dbIns)
+	if err != nil {
+		return err
+	}
+	return conn.Set(ctx, key, value, ttl).Err()
+}
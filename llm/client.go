@@ -7,7 +7,9 @@ import (
 
 // Client 是与 LLM 服务交互的统一客户端
 type Client struct {
-	provider Provider
+	provider     Provider
+	hooks        []Hook
+	interceptors []Interceptor
 }
 
 // NewClient 使用指定的 Provider 创建一个新的客户端
@@ -22,34 +24,109 @@ func NewClient(name string, config interface{}) (*Client, error) {
 // Chat 执行一次聊天请求 (非流式)
 // 如果 Provider 不支持聊天，将返回错误
 func (c *Client) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
-	chatter, ok := c.provider.(Chatter)
-	if !ok {
+	if _, ok := c.provider.(Chatter); !ok {
 		return nil, fmt.Errorf("llm: provider %q does not support chat", c.provider.Name())
 	}
-	return chatter.Chat(ctx, req)
+	result, err := c.handle(ctx, &Call{Operation: OperationChat, Provider: c.provider.Name(), Chat: req})
+	if err != nil {
+		return nil, err
+	}
+	return result.Chat, nil
 }
 
 // ChatStream 执行一次聊天请求 (流式)
 // 如果 Provider 不支持聊天，将返回错误
 func (c *Client) ChatStream(ctx context.Context, req *ChatRequest, callback func(chunk string) error) (*ChatResponse, error) {
-	chatter, ok := c.provider.(Chatter)
-	if !ok {
+	if _, ok := c.provider.(Chatter); !ok {
+		return nil, fmt.Errorf("llm: provider %q does not support chat", c.provider.Name())
+	}
+	return c.chatStream(ctx, req, TextCallback(callback))
+}
+
+// ChatStreamEvents 执行一次聊天请求 (流式)，以结构化事件的形式推送增量
+// Provider 未实现 EventStreamer 时，仅推送 text_delta、done 与 error 事件
+func (c *Client) ChatStreamEvents(ctx context.Context, req *ChatRequest, handler StreamHandler) (*ChatResponse, error) {
+	_, isStreamer := c.provider.(EventStreamer)
+	_, isChatter := c.provider.(Chatter)
+	if !isStreamer && !isChatter {
 		return nil, fmt.Errorf("llm: provider %q does not support chat", c.provider.Name())
 	}
-	return chatter.ChatStream(ctx, req, callback)
+	return c.chatStream(ctx, req, handler)
+}
+
+func (c *Client) chatStream(ctx context.Context, req *ChatRequest, handler StreamHandler) (*ChatResponse, error) {
+	result, err := c.handle(ctx, &Call{Operation: OperationChatStream, Provider: c.provider.Name(), Chat: req, Stream: handler})
+	if err != nil {
+		return nil, err
+	}
+	return result.Chat, nil
+}
+
+func (c *Client) chatStreamEvents(ctx context.Context, req *ChatRequest, handler StreamHandler) (*ChatResponse, error) {
+	if streamer, ok := c.provider.(EventStreamer); ok {
+		return streamer.ChatStreamEvents(ctx, req, handler)
+	}
+	chatter := c.provider.(Chatter)
+	resp, err := chatter.ChatStream(ctx, req, func(chunk string) error {
+		return handler(StreamEvent{Type: StreamEventTextDelta, Text: chunk})
+	})
+	if err != nil {
+		_ = handler(StreamEvent{Type: StreamEventError, Err: err})
+		return nil, err
+	}
+	if err := handler(StreamEvent{Type: StreamEventDone, FinishReason: resp.FinishReason, Response: resp}); err != nil {
+		return nil, err
+	}
+	return resp, nil
 }
 
 // CreateEmbeddings 执行一次向量化请求
 // 如果 Provider 不支持向量化，将返回错误
 func (c *Client) CreateEmbeddings(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
-	embedder, ok := c.provider.(Embedder)
-	if !ok {
+	if _, ok := c.provider.(Embedder); !ok {
 		return nil, fmt.Errorf("llm: provider %q does not support embeddings", c.provider.Name())
 	}
-	return embedder.CreateEmbeddings(ctx, req)
+	result, err := c.handle(ctx, &Call{Operation: OperationEmbeddings, Provider: c.provider.Name(), Embedding: req})
+	if err != nil {
+		return nil, err
+	}
+	return result.Embedding, nil
+}
+
+// GenerateImages 执行一次图片生成请求
+// 如果 Provider 不支持图片生成，将返回错误
+func (c *Client) GenerateImages(ctx context.Context, req *ImageRequest) (*ImageResponse, error) {
+	if _, ok := c.provider.(ImageGenerator); !ok {
+		return nil, fmt.Errorf("llm: provider %q does not support image generation", c.provider.Name())
+	}
+	result, err := c.handle(ctx, &Call{Operation: OperationImages, Provider: c.provider.Name(), Image: req})
+	if err != nil {
+		return nil, err
+	}
+	return result.Image, nil
+}
+
+// Transcribe 执行一次语音转写请求
+// 如果 Provider 不支持语音转写，将返回错误
+func (c *Client) Transcribe(ctx context.Context, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	if _, ok := c.provider.(Transcriber); !ok {
+		return nil, fmt.Errorf("llm: provider %q does not support transcription", c.provider.Name())
+	}
+	result, err := c.handle(ctx, &Call{Operation: OperationTranscribe, Provider: c.provider.Name(), Transcription: req})
+	if err != nil {
+		return nil, err
+	}
+	return result.Transcription, nil
 }
 
 // ProviderName 返回当前客户端使用的 Provider 名称
 func (c *Client) ProviderName() string {
 	return c.provider.Name()
 }
+
+func usageOf(resp *ChatResponse) Usage {
+	if resp == nil {
+		return Usage{}
+	}
+	return resp.Usage
+}
@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mediaProvider 实现 ImageGenerator 与 Transcriber
+type mediaProvider struct{}
+
+func (mediaProvider) Name() string { return "media" }
+
+func (mediaProvider) GenerateImages(ctx context.Context, req *ImageRequest) (*ImageResponse, error) {
+	return &ImageResponse{Images: []Image{{URL: "https://example.com/" + req.Prompt}}, Usage: Usage{TotalTokens: 7}}, nil
+}
+
+func (mediaProvider) Transcribe(ctx context.Context, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	return &TranscriptionResponse{Text: string(req.File)}, nil
+}
+
+func TestClient_MediaCapabilities(t *testing.T) {
+	var events []string
+	hook := &recordHook{name: "hook", events: &events}
+	client := &Client{provider: mediaProvider{}}
+	client.Use(hook)
+
+	images, err := client.GenerateImages(context.Background(), &ImageRequest{Model: "img", Prompt: "cat"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/cat", images.Images[0].URL)
+	assert.Equal(t, 7, hook.usage.TotalTokens)
+
+	text, err := client.Transcribe(context.Background(), &TranscriptionRequest{Model: "asr", File: []byte("hello")})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", text.Text)
+	assert.Equal(t, []string{"hook.before:images:img", "hook.after", "hook.before:transcribe:asr", "hook.after"}, events)
+
+	_, err = client.Chat(context.Background(), &ChatRequest{})
+	assert.EqualError(t, err, `llm: provider "media" does not support chat`)
+
+	chatOnly := &Client{provider: &scriptedChatter{}}
+	_, err = chatOnly.GenerateImages(context.Background(), &ImageRequest{})
+	assert.EqualError(t, err, `llm: provider "scripted" does not support image generation`)
+	_, err = chatOnly.Transcribe(context.Background(), &TranscriptionRequest{})
+	assert.EqualError(t, err, `llm: provider "scripted" does not support transcription`)
+}
@@ -0,0 +1,182 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrorCategory 错误分类
+type ErrorCategory string
+
+const (
+	ErrorRateLimited    ErrorCategory = "rate_limited"    // 限流或配额耗尽，通常可稍后重试
+	ErrorAuth           ErrorCategory = "auth"            // API Key 无效、无权限
+	ErrorInvalidRequest ErrorCategory = "invalid_request" // 请求参数错误、模型不存在等
+	ErrorContextLength  ErrorCategory = "context_length"  // 输入超出模型上下文长度
+	ErrorSafetyBlocked  ErrorCategory = "safety_blocked"  // 被内容安全策略拦截
+	ErrorServer         ErrorCategory = "server"          // 服务端错误或过载
+	ErrorTimeout        ErrorCategory = "timeout"         // 请求超时
+	ErrorUnknown        ErrorCategory = "unknown"         // 无法识别的错误，如网络错误、响应解析失败
+)
+
+// Error 统一的 Provider 错误，所有 Provider 返回的 API 错误都会被包装为 *Error，可通过 errors.As 获取
+// Unwrap 返回原始错误，仍可通过 errors.As 获取 Provider 自身的错误类型（如 *openai.APIError）
+//
+//	var e *llm.Error
+//	if errors.As(err, &e) && e.Category == llm.ErrorContextLength {
+//	    // 裁剪对话后重试
+//	}
+type Error struct {
+	Category   ErrorCategory // 错误分类
+	Provider   string        // Provider 名称
+	Status     int           // HTTP 状态码，未知时为 0（如网络错误、流式响应中的错误）
+	RetryDelay time.Duration // 服务端建议的重试等待时间，来自 Retry-After 响应头
+	Err        error         // 原始错误
+}
+
+// contextLengthHints 各 Provider 上下文超长错误信息中的关键字（小写）
+var contextLengthHints = []string{
+	"context_length_exceeded",
+	"maximum context length",
+	"context window",
+	"prompt is too long",
+	"too many tokens",
+	"input token count",
+	"exceeds the maximum number of tokens",
+}
+
+// safetyHints 各 Provider 内容安全拦截错误信息中的关键字（小写）
+var safetyHints = []string{
+	"content_policy_violation",
+	"content management policy",
+	"content_filter",
+	"safety system",
+}
+
+// NewError 将 Provider 返回的错误包装为 *Error，分类、状态码与重试等待时间从错误链中推断
+// status 大于 0 时优先使用；err 已经是 *Error 时原样返回
+func NewError(provider string, status int, err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	e = &Error{Provider: provider, Status: status, Err: err}
+	var sc interface{ StatusCode() int }
+	if e.Status <= 0 && errors.As(err, &sc) {
+		e.Status = sc.StatusCode()
+	}
+	var ra interface{ RetryAfter() time.Duration }
+	if errors.As(err, &ra) {
+		e.RetryDelay = ra.RetryAfter()
+	}
+	e.Category = classifyError(e.Status, err)
+	return e
+}
+
+// WrapError 与 NewError 相同，err 为 nil 时返回 nil
+func WrapError(provider string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return NewError(provider, 0, err)
+}
+
+// classifyError 根据状态码与错误信息推断错误分类
+func classifyError(status int, err error) ErrorCategory {
+	var rl interface{ RateLimited() bool }
+	if status == http.StatusTooManyRequests || (errors.As(err, &rl) && rl.RateLimited()) {
+		return ErrorRateLimited
+	}
+	msg := strings.ToLower(err.Error())
+	for _, hint := range contextLengthHints {
+		if strings.Contains(msg, hint) {
+			return ErrorContextLength
+		}
+	}
+	for _, hint := range safetyHints {
+		if strings.Contains(msg, hint) {
+			return ErrorSafetyBlocked
+		}
+	}
+	var te interface{ Timeout() bool }
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &te) && te.Timeout()) {
+		return ErrorTimeout
+	}
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrorAuth
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return ErrorTimeout
+	case status >= http.StatusInternalServerError:
+		return ErrorServer
+	case status >= http.StatusBadRequest:
+		return ErrorInvalidRequest
+	}
+	return ErrorUnknown
+}
+
+// Error 实现 error 接口，返回原始错误信息
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("llm: %s %s error", e.Provider, e.Category)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode 返回 HTTP 状态码，未知时为 0
+func (e *Error) StatusCode() int {
+	return e.Status
+}
+
+// RetryAfter 返回服务端建议的重试等待时间
+func (e *Error) RetryAfter() time.Duration {
+	return e.RetryDelay
+}
+
+// RateLimited 是否为限流错误
+func (e *Error) RateLimited() bool {
+	return e.Category == ErrorRateLimited
+}
+
+// Timeout 是否为超时错误
+func (e *Error) Timeout() bool {
+	return e.Category == ErrorTimeout
+}
+
+// CategoryOf 返回错误链中 *Error 的分类，不是 *Error 时返回空字符串
+func CategoryOf(err error) ErrorCategory {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Category
+	}
+	return ""
+}
+
+// IsRateLimited 判断错误是否为限流错误
+// Provider 的错误类型实现 RateLimited() bool 时以其为准，否则根据 StatusCode() 是否为 429 判断
+func IsRateLimited(err error) bool {
+	var rl interface{ RateLimited() bool }
+	if errors.As(err, &rl) && rl.RateLimited() {
+		return true
+	}
+	var sc interface{ StatusCode() int }
+	return errors.As(err, &sc) && sc.StatusCode() == http.StatusTooManyRequests
+}
+
+// RetryAfter 返回错误中携带的服务端建议等待时间（通常来自 Retry-After 响应头），未携带时返回 0
+func RetryAfter(err error) time.Duration {
+	var ra interface{ RetryAfter() time.Duration }
+	if errors.As(err, &ra) {
+		return ra.RetryAfter()
+	}
+	return 0
+}
@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// apiError 模拟 Provider 自身的错误类型
+type apiError struct {
+	status      int
+	message     string
+	rateLimited bool
+	retryAfter  time.Duration
+}
+
+func (e *apiError) Error() string             { return e.message }
+func (e *apiError) StatusCode() int           { return e.status }
+func (e *apiError) RateLimited() bool         { return e.rateLimited }
+func (e *apiError) RetryAfter() time.Duration { return e.retryAfter }
+
+func TestNewError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		err    error
+		want   ErrorCategory
+	}{
+		{"rate limited by status", 0, &apiError{status: 429, message: "slow down"}, ErrorRateLimited},
+		{"rate limited by provider", 0, &apiError{status: 400, message: "quota", rateLimited: true}, ErrorRateLimited},
+		{"auth", 0, &apiError{status: 401, message: "invalid api key"}, ErrorAuth},
+		{"forbidden", 403, errors.New("permission denied"), ErrorAuth},
+		{"openai context length", 0, &apiError{status: 400, message: "This model's maximum context length is 8192 tokens"}, ErrorContextLength},
+		{"anthropic context length", 0, &apiError{status: 400, message: "prompt is too long: 210000 tokens > 200000 maximum"}, ErrorContextLength},
+		{"content policy", 0, &apiError{status: 400, message: "Your request was rejected as a result of our safety system"}, ErrorSafetyBlocked},
+		{"invalid request", 0, &apiError{status: 404, message: "model not found"}, ErrorInvalidRequest},
+		{"server", 0, &apiError{status: 503, message: "overloaded"}, ErrorServer},
+		{"gateway timeout", 504, errors.New("upstream timeout"), ErrorTimeout},
+		{"deadline", 0, fmt.Errorf("call failed: %w", context.DeadlineExceeded), ErrorTimeout},
+		{"network", 0, errors.New("connection reset"), ErrorUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewError("openai", tt.status, tt.err)
+			assert.Equal(t, tt.want, e.Category)
+			assert.Equal(t, "openai", e.Provider)
+			assert.Equal(t, tt.err.Error(), e.Error())
+		})
+	}
+}
+
+func TestError_ErrorsAs(t *testing.T) {
+	inner := &apiError{status: http.StatusTooManyRequests, message: "rate limit", retryAfter: 3 * time.Second}
+	err := fmt.Errorf("router: %w", NewError("openai", 0, fmt.Errorf("openai: api error: %w", inner)))
+
+	var e *Error
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, ErrorRateLimited, e.Category)
+	assert.Equal(t, 429, e.StatusCode())
+	assert.Equal(t, 3*time.Second, e.RetryAfter())
+	assert.True(t, IsRateLimited(err))
+	assert.Equal(t, 3*time.Second, RetryAfter(err))
+	assert.Equal(t, ErrorRateLimited, CategoryOf(err))
+	assert.Equal(t, ErrorCategory(""), CategoryOf(errors.New("plain")))
+
+	// 仍可获取 Provider 自身的错误类型
+	var ae *apiError
+	require.True(t, errors.As(err, &ae))
+	assert.Same(t, inner, ae)
+
+	// 已经是 *Error 时原样返回
+	assert.Same(t, e, NewError("router", 500, err))
+	assert.Nil(t, WrapError("openai", nil))
+}
+
+func TestClient_WrapsProviderErrors(t *testing.T) {
+	var events []string
+	hook := &recordHook{name: "hook", events: &events}
+	client := &Client{provider: &scriptedChatter{}}
+	client.Use(hook)
+
+	_, err := client.Chat(context.Background(), &ChatRequest{})
+	var e *Error
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, "scripted", e.Provider)
+	assert.Equal(t, ErrorUnknown, e.Category)
+	assert.Equal(t, "no more scripted responses", err.Error())
+	assert.Same(t, e, hook.err, "hooks receive the wrapped error")
+}
@@ -0,0 +1,201 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_ScriptedChat(t *testing.T) {
+	p, err := Register("fake-scripted-test", Config{Responses: []Response{
+		{Content: "hello", Usage: llm.Usage{PromptTokens: 3, CompletionTokens: 1, TotalTokens: 4}},
+		ToolCall("call_1", "get_weather", `{"city":"Beijing"}`),
+	}})
+	require.NoError(t, err)
+
+	client, err := llm.NewClient("fake-scripted-test", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "fake-scripted-test", client.ProviderName())
+
+	req := &llm.ChatRequest{Model: "m", Messages: []llm.Message{{Role: "user", Content: "hi"}}}
+	resp, err := client.Chat(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", resp.Content)
+	assert.Equal(t, "stop", resp.FinishReason)
+	assert.Equal(t, 4, resp.Usage.TotalTokens)
+
+	resp, err = client.Chat(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "tool_calls", resp.FinishReason)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "get_weather", resp.ToolCalls[0].Function.Name)
+
+	_, err = client.Chat(context.Background(), req)
+	assert.ErrorContains(t, err, "no scripted response")
+	assert.Len(t, p.Requests(), 3)
+	assert.Equal(t, "hi", p.Requests()[0].Messages[0].Content)
+}
+
+func TestProvider_StreamEvents(t *testing.T) {
+	p, err := NewProvider(Config{Responses: []Response{{
+		Reasoning: "thinking",
+		Chunks:    []string{"Hel", "lo"},
+		ToolCalls: []llm.ToolCall{{ID: "call_1", Type: "function", Function: llm.FunctionCall{Name: "search", Arguments: `{"q":"go"}`}}},
+		Usage:     llm.Usage{TotalTokens: 7},
+	}}})
+	require.NoError(t, err)
+
+	var types []llm.StreamEventType
+	var text string
+	resp, err := p.ChatStreamEvents(context.Background(), &llm.ChatRequest{}, func(event llm.StreamEvent) error {
+		types = append(types, event.Type)
+		if event.Type == llm.StreamEventTextDelta {
+			text += event.Text
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", text)
+	assert.Equal(t, "Hello", resp.Content)
+	assert.Equal(t, "thinking", resp.Reasoning)
+	assert.Equal(t, "tool_calls", resp.FinishReason)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, `{"q":"go"}`, resp.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, []llm.StreamEventType{
+		llm.StreamEventReasoningDelta,
+		llm.StreamEventTextDelta,
+		llm.StreamEventTextDelta,
+		llm.StreamEventToolCallDelta,
+		llm.StreamEventUsage,
+		llm.StreamEventDone,
+	}, types)
+}
+
+func TestProvider_ScriptedError(t *testing.T) {
+	p, err := NewProvider(Config{Name: "flaky", Responses: []Response{
+		{Chunks: []string{"partial"}, Error: &Error{Category: llm.ErrorRateLimited, Status: 429, Message: "slow down", RetryAfter: time.Second}},
+		Fail(llm.ErrorContextLength, 400, "too long"),
+	}})
+	require.NoError(t, err)
+
+	var chunks []string
+	_, err = p.ChatStream(context.Background(), &llm.ChatRequest{}, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	assert.Equal(t, []string{"partial"}, chunks)
+	var llmErr *llm.Error
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, "flaky", llmErr.Provider)
+	assert.True(t, llmErr.RateLimited())
+	assert.Equal(t, time.Second, llmErr.RetryAfter())
+	assert.EqualError(t, err, "fake: slow down")
+
+	_, err = p.Chat(context.Background(), &llm.ChatRequest{})
+	assert.Equal(t, llm.ErrorContextLength, llm.CategoryOf(err))
+}
+
+func TestProvider_ResponderAndDelay(t *testing.T) {
+	p, err := NewProvider(Config{Responder: func(req *llm.ChatRequest) (Response, error) {
+		return Response{Content: "echo: " + req.Messages[0].Content.(string), Delay: time.Second}, nil
+	}})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = p.Chat(ctx, &llm.ChatRequest{Messages: []llm.Message{{Role: "user", Content: "ping"}}})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	p.Push(Text("pushed"))
+	resp, err := p.Chat(context.Background(), &llm.ChatRequest{Messages: []llm.Message{{Role: "user", Content: "ping"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "pushed", resp.Content)
+}
+
+func TestProvider_Embeddings(t *testing.T) {
+	p, err := NewProvider(Config{EmbeddingDims: 16})
+	require.NoError(t, err)
+
+	resp, err := p.CreateEmbeddings(context.Background(), &llm.EmbeddingRequest{Model: "e", Input: []string{"a", "b", "a"}})
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 3)
+	assert.Len(t, resp.Data[0].Vector, 16)
+	assert.Equal(t, resp.Data[0].Vector, resp.Data[2].Vector)
+	assert.NotEqual(t, resp.Data[0].Vector, resp.Data[1].Vector)
+	assert.Equal(t, 2, resp.Data[2].Index)
+
+	var norm float32
+	for _, v := range resp.Data[1].Vector {
+		norm += v * v
+	}
+	assert.InDelta(t, 1, norm, 1e-5)
+	assert.Len(t, p.EmbeddingRequests(), 1)
+}
+
+func TestRecorder_Replay(t *testing.T) {
+	inner, err := NewProvider(Config{Name: "openai", Responses: []Response{
+		{Content: "first", Chunks: []string{"fir", "st"}, Usage: llm.Usage{TotalTokens: 5}},
+		Fail(llm.ErrorServer, 503, "overloaded"),
+	}})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "testdata", "fixtures.json")
+	rec := NewRecorder(inner, path)
+	assert.Equal(t, "openai", rec.Name())
+
+	ok := &llm.ChatRequest{Model: "gpt", Messages: []llm.Message{{Role: "user", Content: "one"}}}
+	bad := &llm.ChatRequest{Model: "gpt", Messages: []llm.Message{{Role: "user", Content: "two"}}}
+	embed := &llm.EmbeddingRequest{Model: "emb", Input: []string{"x"}}
+
+	_, err = rec.ChatStream(context.Background(), ok, func(string) error { return nil })
+	require.NoError(t, err)
+	_, err = rec.Chat(context.Background(), bad)
+	require.Error(t, err)
+	want, err := rec.CreateEmbeddings(context.Background(), embed)
+	require.NoError(t, err)
+	require.NoError(t, rec.Save())
+
+	fixtures, err := LoadFixtures(path)
+	require.NoError(t, err)
+	assert.Equal(t, "openai", fixtures.Provider)
+	require.Len(t, fixtures.Interactions, 3)
+	assert.Equal(t, []string{"fir", "st"}, fixtures.Interactions[0].Response.Chunks)
+	assert.Equal(t, "one", fixtures.Interactions[0].Prompt)
+
+	replay, err := NewProvider(Config{Fixtures: path})
+	require.NoError(t, err)
+
+	// a streamed recording replays for non-streaming calls too, and the last entry repeats
+	for i := 0; i < 2; i++ {
+		resp, err := replay.Chat(context.Background(), ok)
+		require.NoError(t, err)
+		assert.Equal(t, "first", resp.Content)
+		assert.Equal(t, 5, resp.Usage.TotalTokens)
+	}
+	_, err = replay.Chat(context.Background(), bad)
+	assert.Equal(t, llm.ErrorServer, llm.CategoryOf(err))
+	assert.ErrorContains(t, err, "overloaded")
+
+	got, err := replay.CreateEmbeddings(context.Background(), embed)
+	require.NoError(t, err)
+	assert.Equal(t, want.Data, got.Data)
+
+	_, err = replay.Chat(context.Background(), &llm.ChatRequest{Model: "gpt", Messages: []llm.Message{{Role: "user", Content: "three"}}})
+	assert.ErrorContains(t, err, "no fixture recorded")
+}
+
+func TestParseConfig(t *testing.T) {
+	p, err := llm.NewProvider(providerName, map[string]interface{}{"name": "stub", "embedding_dims": "4"})
+	require.NoError(t, err)
+	assert.Equal(t, "stub", p.Name())
+	assert.Equal(t, 4, p.(*Provider).cfg.EmbeddingDims)
+
+	_, err = llm.NewProvider(providerName, "invalid")
+	assert.Error(t, err)
+}
@@ -0,0 +1,336 @@
+package fake
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jessewkun/gocommon/llm"
+)
+
+// Provider implements llm.Chatter, llm.EventStreamer and llm.Embedder with scripted responses or replayed fixtures
+// Scripted responses are used first, then the Responder, then the fixtures
+type Provider struct {
+	cfg Config
+
+	mu                sync.Mutex
+	responses         []Response
+	fixtures          map[string][]Interaction // key -> recorded interactions, consumed in order, the last one repeats
+	chatRequests      []*llm.ChatRequest
+	embeddingRequests []*llm.EmbeddingRequest
+}
+
+// NewProvider creates a fake Provider, the fixture file is loaded when cfg.Fixtures is set
+func NewProvider(cfg Config) (*Provider, error) {
+	if cfg.Name == "" {
+		cfg.Name = providerName
+	}
+	if cfg.EmbeddingDims <= 0 {
+		cfg.EmbeddingDims = 8
+	}
+	p := &Provider{cfg: cfg, responses: append([]Response(nil), cfg.Responses...)}
+	if cfg.Fixtures != "" {
+		fixtures, err := LoadFixtures(cfg.Fixtures)
+		if err != nil {
+			return nil, err
+		}
+		p.fixtures = make(map[string][]Interaction)
+		for _, it := range fixtures.Interactions {
+			p.fixtures[it.Key] = append(p.fixtures[it.Key], it)
+		}
+	}
+	return p, nil
+}
+
+// Name implements llm.Provider
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// Push appends scripted chat responses
+func (p *Provider) Push(responses ...Response) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.responses = append(p.responses, responses...)
+}
+
+// Requests returns copies of the chat requests received so far
+func (p *Provider) Requests() []*llm.ChatRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*llm.ChatRequest(nil), p.chatRequests...)
+}
+
+// EmbeddingRequests returns copies of the embedding requests received so far
+func (p *Provider) EmbeddingRequests() []*llm.EmbeddingRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*llm.EmbeddingRequest(nil), p.embeddingRequests...)
+}
+
+// Reset drops the pending scripted responses and the received requests
+func (p *Provider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.responses = nil
+	p.chatRequests = nil
+	p.embeddingRequests = nil
+}
+
+// Chat implements llm.Chatter
+func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	resp, err := p.next(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error.toLLM(p.cfg.Name)
+	}
+	return resp.toLLM(), nil
+}
+
+// ChatStream implements llm.Chatter
+func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, callback func(chunk string) error) (*llm.ChatResponse, error) {
+	return p.ChatStreamEvents(ctx, req, llm.TextCallback(callback))
+}
+
+// ChatStreamEvents implements llm.EventStreamer, pushing Reasoning, Chunks, ToolCalls and Usage in order
+func (p *Provider) ChatStreamEvents(ctx context.Context, req *llm.ChatRequest, handler llm.StreamHandler) (*llm.ChatResponse, error) {
+	resp, err := p.next(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	acc := llm.NewStreamAccumulator(handler)
+	if err := acc.Reasoning(resp.Reasoning); err != nil {
+		return nil, acc.Fail(err)
+	}
+	chunks := resp.Chunks
+	if len(chunks) == 0 && resp.Content != "" {
+		chunks = []string{resp.Content}
+	}
+	for _, chunk := range chunks {
+		if err := ctx.Err(); err != nil {
+			return nil, acc.Fail(err)
+		}
+		if err := acc.Text(chunk); err != nil {
+			return nil, acc.Fail(err)
+		}
+	}
+	if resp.Error != nil {
+		return nil, acc.Fail(resp.Error.toLLM(p.cfg.Name))
+	}
+	for i, tc := range resp.ToolCalls {
+		if err := acc.ToolCall(llm.ToolCallDelta{Index: i, ID: tc.ID, Type: tc.Type, Name: tc.Function.Name, Arguments: tc.Function.Arguments}); err != nil {
+			return nil, acc.Fail(err)
+		}
+	}
+	if resp.Usage != (llm.Usage{}) {
+		if err := acc.Usage(resp.Usage); err != nil {
+			return nil, acc.Fail(err)
+		}
+	}
+	acc.Finish(resp.finishReason())
+	return acc.Done()
+}
+
+// CreateEmbeddings implements llm.Embedder
+// Without Config.Embedder or fixtures, each input gets a deterministic unit vector derived from its hash,
+// identical inputs always produce identical vectors
+func (p *Provider) CreateEmbeddings(ctx context.Context, req *llm.EmbeddingRequest) (*llm.EmbeddingResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cp := *req
+	cp.Input = append([]string(nil), req.Input...)
+	p.mu.Lock()
+	p.embeddingRequests = append(p.embeddingRequests, &cp)
+	p.mu.Unlock()
+
+	if p.cfg.Embedder != nil {
+		return p.cfg.Embedder(req)
+	}
+	if p.fixtures != nil {
+		it, err := p.replay(RequestKey(req), req.Model)
+		if err != nil {
+			return nil, err
+		}
+		if it.Response != nil && it.Response.Error != nil {
+			return nil, it.Response.Error.toLLM(p.cfg.Name)
+		}
+		if it.Embedding == nil {
+			return nil, fmt.Errorf("fake: fixture %s has no embedding response", it.Key)
+		}
+		resp := *it.Embedding
+		return &resp, nil
+	}
+
+	resp := &llm.EmbeddingResponse{Model: req.Model}
+	for i, text := range req.Input {
+		resp.Data = append(resp.Data, llm.Embedding{Index: i, Object: "embedding", Vector: Vector(text, p.cfg.EmbeddingDims)})
+		resp.Usage.PromptTokens += llm.TokenizerOpenAI.CountText(text)
+	}
+	resp.Usage.TotalTokens = resp.Usage.PromptTokens
+	return resp, nil
+}
+
+// next records the request and returns the response to use for it
+func (p *Provider) next(ctx context.Context, req *llm.ChatRequest) (*Response, error) {
+	cp := *req
+	cp.Messages = append([]llm.Message(nil), req.Messages...)
+	p.mu.Lock()
+	p.chatRequests = append(p.chatRequests, &cp)
+	var resp *Response
+	if len(p.responses) > 0 {
+		r := p.responses[0]
+		p.responses = p.responses[1:]
+		resp = &r
+	}
+	p.mu.Unlock()
+
+	if resp == nil && p.cfg.Responder != nil {
+		r, err := p.cfg.Responder(req)
+		if err != nil {
+			return nil, err
+		}
+		resp = &r
+	}
+	if resp == nil && p.fixtures != nil {
+		it, err := p.replay(RequestKey(req), req.Model)
+		if err != nil {
+			return nil, err
+		}
+		resp = it.Response
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("fake: no scripted response for request %d", len(p.Requests()))
+	}
+
+	if resp.Delay > 0 {
+		timer := time.NewTimer(resp.Delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return resp, ctx.Err()
+}
+
+// replay returns the next recorded interaction for key
+func (p *Provider) replay(key, model string) (Interaction, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	list := p.fixtures[key]
+	if len(list) == 0 {
+		return Interaction{}, fmt.Errorf("fake: no fixture recorded for request %s (model %q), re-record the fixtures", key, model)
+	}
+	if len(list) > 1 {
+		p.fixtures[key] = list[1:]
+	}
+	return list[0], nil
+}
+
+func (r *Response) finishReason() string {
+	if r.FinishReason != "" {
+		return r.FinishReason
+	}
+	if len(r.ToolCalls) > 0 {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+func (r *Response) toLLM() *llm.ChatResponse {
+	content := r.Content
+	if content == "" && len(r.Chunks) > 0 {
+		for _, c := range r.Chunks {
+			content += c
+		}
+	}
+	return &llm.ChatResponse{
+		Content:      content,
+		Reasoning:    r.Reasoning,
+		ToolCalls:    r.ToolCalls,
+		Thinking:     r.Thinking,
+		FinishReason: r.finishReason(),
+		Usage:        r.Usage,
+	}
+}
+
+func (e *Error) toLLM(provider string) error {
+	category := e.Category
+	if category == "" {
+		category = llm.ErrorUnknown
+	}
+	return &llm.Error{
+		Category:   category,
+		Provider:   provider,
+		Status:     e.Status,
+		RetryDelay: e.RetryAfter,
+		Err:        fmt.Errorf("fake: %s", e.Message),
+	}
+}
+
+// Vector returns a deterministic unit vector of the given dimensions derived from the hash of text
+func Vector(text string, dims int) []float32 {
+	vec := make([]float32, dims)
+	var norm float64
+	block := sha256.Sum256([]byte(text))
+	for i := range vec {
+		if i > 0 && i%8 == 0 {
+			block = sha256.Sum256(block[:])
+		}
+		// map 4 bytes of the hash to [-1, 1)
+		u := binary.BigEndian.Uint32(block[(i%8)*4:])
+		v := float64(u)/float64(math.MaxUint32)*2 - 1
+		vec[i] = float32(v)
+		norm += v * v
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vec {
+			vec[i] = float32(float64(vec[i]) / norm)
+		}
+	}
+	return vec
+}
+
+// LoadFixtures reads a fixture file written by Recorder
+func LoadFixtures(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fake: reading fixtures: %w", err)
+	}
+	var fixtures Fixtures
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("fake: parsing fixtures %s: %w", path, err)
+	}
+	return &fixtures, nil
+}
+
+// RequestKey returns the key identifying a chat or embedding request in fixtures
+// Streaming and non-streaming chat calls with the same request share the same key
+func RequestKey(req interface{}) string {
+	var kind string
+	switch req.(type) {
+	case *llm.ChatRequest:
+		kind = "chat"
+	case *llm.EmbeddingRequest:
+		kind = "embeddings"
+	}
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(append([]byte(kind+"\n"), data...))
+	return fmt.Sprintf("%x", sum[:8])
+}
+
+// Ensure *Provider implements the interfaces
+var _ llm.Chatter = (*Provider)(nil)
+var _ llm.EventStreamer = (*Provider)(nil)
+var _ llm.Embedder = (*Provider)(nil)
@@ -0,0 +1,180 @@
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jessewkun/gocommon/llm"
+)
+
+// Recorder wraps a real provider and records every chat and embedding call, Save writes them to a fixture file
+// which can be replayed with Config.Fixtures
+//
+//	if os.Getenv("LLM_RECORD") != "" {
+//	    upstream, _ := llm.NewProvider("openai", cfg)
+//	    rec := fake.NewRecorder(upstream, "testdata/summarize.json")
+//	    defer rec.Save()
+//	    provider = rec
+//	} else {
+//	    provider, _ = fake.NewProvider(fake.Config{Fixtures: "testdata/summarize.json"})
+//	}
+type Recorder struct {
+	inner llm.Provider
+	path  string
+
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+// NewRecorder creates a Recorder writing to path
+func NewRecorder(inner llm.Provider, path string) *Recorder {
+	return &Recorder{inner: inner, path: path}
+}
+
+// Name implements llm.Provider, returning the name of the wrapped provider
+func (r *Recorder) Name() string {
+	return r.inner.Name()
+}
+
+// Chat implements llm.Chatter
+func (r *Recorder) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	chatter, ok := r.inner.(llm.Chatter)
+	if !ok {
+		return nil, fmt.Errorf("fake: provider %q does not support chat", r.inner.Name())
+	}
+	resp, err := chatter.Chat(ctx, req)
+	r.recordChat(llm.OperationChat, req, resp, nil, err)
+	return resp, err
+}
+
+// ChatStream implements llm.Chatter
+func (r *Recorder) ChatStream(ctx context.Context, req *llm.ChatRequest, callback func(chunk string) error) (*llm.ChatResponse, error) {
+	return r.ChatStreamEvents(ctx, req, llm.TextCallback(callback))
+}
+
+// ChatStreamEvents implements llm.EventStreamer, the text chunks are recorded as Response.Chunks
+func (r *Recorder) ChatStreamEvents(ctx context.Context, req *llm.ChatRequest, handler llm.StreamHandler) (*llm.ChatResponse, error) {
+	var chunks []string
+	record := func(event llm.StreamEvent) error {
+		if event.Type == llm.StreamEventTextDelta {
+			chunks = append(chunks, event.Text)
+		}
+		if handler == nil {
+			return nil
+		}
+		return handler(event)
+	}
+
+	var (
+		resp *llm.ChatResponse
+		err  error
+	)
+	switch inner := r.inner.(type) {
+	case llm.EventStreamer:
+		resp, err = inner.ChatStreamEvents(ctx, req, record)
+	case llm.Chatter:
+		resp, err = inner.ChatStream(ctx, req, func(chunk string) error {
+			return record(llm.StreamEvent{Type: llm.StreamEventTextDelta, Text: chunk})
+		})
+	default:
+		return nil, fmt.Errorf("fake: provider %q does not support chat", r.inner.Name())
+	}
+	r.recordChat(llm.OperationChatStream, req, resp, chunks, err)
+	return resp, err
+}
+
+// CreateEmbeddings implements llm.Embedder
+func (r *Recorder) CreateEmbeddings(ctx context.Context, req *llm.EmbeddingRequest) (*llm.EmbeddingResponse, error) {
+	embedder, ok := r.inner.(llm.Embedder)
+	if !ok {
+		return nil, fmt.Errorf("fake: provider %q does not support embeddings", r.inner.Name())
+	}
+	resp, err := embedder.CreateEmbeddings(ctx, req)
+	if errors.Is(err, context.Canceled) {
+		return resp, err
+	}
+	it := Interaction{
+		Key:       RequestKey(req),
+		Operation: llm.OperationEmbeddings,
+		Model:     req.Model,
+		Prompt:    strings.Join(req.Input, "\n"),
+	}
+	if err != nil {
+		it.Response = &Response{Error: recordError(err)}
+	} else {
+		cp := *resp
+		cp.RawResponse = nil
+		it.Embedding = &cp
+	}
+	r.add(it)
+	return resp, err
+}
+
+// Interactions returns the interactions recorded so far
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.interactions...)
+}
+
+// Save writes the recorded interactions to the fixture file, creating the parent directory if needed
+func (r *Recorder) Save() error {
+	data, err := json.MarshalIndent(Fixtures{Provider: r.inner.Name(), Interactions: r.Interactions()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("fake: marshalling fixtures: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("fake: creating fixtures dir: %w", err)
+	}
+	if err := os.WriteFile(r.path, data, 0o644); err != nil {
+		return fmt.Errorf("fake: writing fixtures: %w", err)
+	}
+	return nil
+}
+
+func (r *Recorder) recordChat(op llm.Operation, req *llm.ChatRequest, resp *llm.ChatResponse, chunks []string, err error) {
+	// calls canceled by the caller say nothing about the provider
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	it := Interaction{Key: RequestKey(req), Operation: op, Model: req.Model}
+	if n := len(req.Messages); n > 0 {
+		it.Prompt = llm.ContentString(req.Messages[n-1].Content)
+	}
+	recorded := &Response{Chunks: chunks}
+	if resp != nil {
+		recorded.Content = resp.Content
+		recorded.Reasoning = resp.Reasoning
+		recorded.ToolCalls = resp.ToolCalls
+		recorded.Thinking = resp.Thinking
+		recorded.FinishReason = resp.FinishReason
+		recorded.Usage = resp.Usage
+	}
+	if err != nil {
+		recorded.Error = recordError(err)
+	}
+	it.Response = recorded
+	r.add(it)
+}
+
+func (r *Recorder) add(it Interaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, it)
+}
+
+func recordError(err error) *Error {
+	e := llm.NewError("", 0, err)
+	return &Error{Category: e.Category, Status: e.Status, Message: err.Error(), RetryAfter: e.RetryDelay}
+}
+
+// Ensure *Recorder implements the interfaces
+var _ llm.Chatter = (*Recorder)(nil)
+var _ llm.EventStreamer = (*Recorder)(nil)
+var _ llm.Embedder = (*Recorder)(nil)
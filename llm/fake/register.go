@@ -0,0 +1,52 @@
+package fake
+
+import (
+	"fmt"
+
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/spf13/cast"
+)
+
+const providerName = "fake"
+
+func init() {
+	llm.Register(providerName, func(config interface{}) (llm.Provider, error) {
+		cfg, err := parseConfig(config)
+		if err != nil {
+			return nil, fmt.Errorf("parsing fake config: %w", err)
+		}
+		return NewProvider(cfg)
+	})
+}
+
+// Register creates a fake Provider and registers it under name, llm.NewProvider(name, nil) and llm.NewClient(name, nil)
+// always return this instance so that tests can script responses and inspect requests
+// name must be unique, registering the same name twice panics
+func Register(name string, cfg Config) (*Provider, error) {
+	if cfg.Name == "" {
+		cfg.Name = name
+	}
+	p, err := NewProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	llm.Register(name, func(config interface{}) (llm.Provider, error) { return p, nil })
+	return p, nil
+}
+
+// parseConfig supports Config, map or nil
+func parseConfig(config interface{}) (Config, error) {
+	switch c := config.(type) {
+	case nil:
+		return Config{}, nil
+	case Config:
+		return c, nil
+	case map[string]interface{}:
+		return Config{
+			Name:          cast.ToString(c["name"]),
+			Fixtures:      cast.ToString(c["fixtures"]),
+			EmbeddingDims: cast.ToInt(c["embedding_dims"]),
+		}, nil
+	}
+	return Config{}, fmt.Errorf("config must be of type fake.Config or map[string]interface{}")
+}
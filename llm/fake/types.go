@@ -0,0 +1,71 @@
+// Package fake provides a deterministic llm provider for tests: scripted responses, streamed chunks, tool calls
+// and replay of fixtures recorded from real providers
+package fake
+
+import (
+	"time"
+
+	"github.com/jessewkun/gocommon/llm"
+)
+
+// Response is a scripted (or recorded) chat response
+type Response struct {
+	Content      string              `json:"content,omitempty"`
+	Reasoning    string              `json:"reasoning,omitempty"`
+	ToolCalls    []llm.ToolCall      `json:"tool_calls,omitempty"`
+	Thinking     []llm.ThinkingBlock `json:"thinking,omitempty"`
+	FinishReason string              `json:"finish_reason,omitempty"` // defaults to "stop", or "tool_calls" when ToolCalls is set
+	Usage        llm.Usage           `json:"usage"`
+	Chunks       []string            `json:"chunks,omitempty"` // text chunks pushed by streaming calls, defaults to Content as a single chunk
+	Delay        time.Duration       `json:"delay,omitempty"`  // simulated latency before responding
+	Error        *Error              `json:"error,omitempty"`  // returned instead of the response; streaming calls push Chunks first
+}
+
+// Error is a scripted error, returned to the caller as *llm.Error
+type Error struct {
+	Category   llm.ErrorCategory `json:"category"`
+	Status     int               `json:"status,omitempty"`
+	Message    string            `json:"message"`
+	RetryAfter time.Duration     `json:"retry_after,omitempty"`
+}
+
+// Text returns a Response with the given content
+func Text(content string) Response {
+	return Response{Content: content}
+}
+
+// ToolCall returns a Response calling a single tool with JSON arguments
+func ToolCall(id, name, arguments string) Response {
+	return Response{ToolCalls: []llm.ToolCall{{ID: id, Type: "function", Function: llm.FunctionCall{Name: name, Arguments: arguments}}}}
+}
+
+// Fail returns a Response failing with the given category and message
+func Fail(category llm.ErrorCategory, status int, message string) Response {
+	return Response{Error: &Error{Category: category, Status: status, Message: message}}
+}
+
+// Config for the fake provider
+type Config struct {
+	Name          string                                                          // name returned by Name(), defaults to "fake"
+	Responses     []Response                                                      // scripted chat responses, consumed in order
+	Responder     func(req *llm.ChatRequest) (Response, error)                    // used when Responses is exhausted
+	Fixtures      string                                                          // path of a fixture file to replay, see Recorder
+	EmbeddingDims int                                                             // dimensions of generated embeddings, defaults to 8
+	Embedder      func(req *llm.EmbeddingRequest) (*llm.EmbeddingResponse, error) // overrides the generated embeddings
+}
+
+// Interaction is a single recorded call in a fixture file
+type Interaction struct {
+	Key       string                 `json:"key"`       // hash of the request, see RequestKey
+	Operation llm.Operation          `json:"operation"` // chat, chat_stream or embeddings
+	Model     string                 `json:"model"`
+	Prompt    string                 `json:"prompt,omitempty"` // last message or inputs, for readability only
+	Response  *Response              `json:"response,omitempty"`
+	Embedding *llm.EmbeddingResponse `json:"embedding,omitempty"`
+}
+
+// Fixtures is the content of a fixture file
+type Fixtures struct {
+	Provider     string        `json:"provider"` // name of the recorded provider
+	Interactions []Interaction `json:"interactions"`
+}
@@ -83,6 +83,36 @@ func TestGeminiProvider_Chat_WithSystemPrompt(t *testing.T) {
 	assert.Equal(t, "STOP", resp.FinishReason)
 }
 
+func TestGeminiProvider_TrimmedMessages(t *testing.T) {
+	msgs := []llm.Message{
+		{Role: "system", Content: "You are a helpful assistant."},
+		{Role: "user", Content: strings.Repeat("old question ", 50)},
+		{Role: "assistant", Content: strings.Repeat("old answer ", 50)},
+		{Role: "user", Content: "What's the weather in Paris?"},
+	}
+	trimmed, err := llm.TrimMessages(context.Background(), msgs, llm.TrimConfig{
+		MaxTokens: 60,
+		Summarizer: func(ctx context.Context, dropped []llm.Message) (string, error) {
+			return "The user asked an old question.", nil
+		},
+		SummaryPrefix: "Summary: ",
+	})
+	require.NoError(t, err)
+
+	// 摘要必须出现在 system instruction 中，而不是被当作第二条 system 消息丢弃
+	req, err := newTestProvider("http://localhost").toGeminiChatRequest(context.Background(), &llm.ChatRequest{Model: "gemini-2.0-flash", Messages: trimmed})
+	require.NoError(t, err)
+	require.NotNil(t, req.SystemInstruction)
+	var system []string
+	for _, part := range req.SystemInstruction.Parts {
+		system = append(system, part.Text)
+	}
+	assert.Contains(t, strings.Join(system, "\n"), "You are a helpful assistant.")
+	assert.Contains(t, strings.Join(system, "\n"), "Summary: The user asked an old question.")
+	require.Len(t, req.Contents, 1)
+	assert.Equal(t, "What's the weather in Paris?", req.Contents[0].Parts[0].Text)
+}
+
 func TestGeminiProvider_ChatStream(t *testing.T) {
 	// 1. Setup mock server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -198,3 +228,218 @@ func TestGeminiProvider_Chat_WithMultimodal(t *testing.T) {
 	assert.Equal(t, "I see a test image.", resp.Content)
 	assert.Equal(t, "STOP", resp.FinishReason)
 }
+
+func TestGeminiProvider_Chat_WithToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody GeminiChatRequest
+		err := json.NewDecoder(r.Body).Decode(&reqBody)
+		require.NoError(t, err)
+
+		// Assert OpenAI style tools are converted to function declarations
+		require.Len(t, reqBody.Tools, 1)
+		require.Len(t, reqBody.Tools[0].FunctionDeclarations, 1)
+		assert.Equal(t, "get_weather", reqBody.Tools[0].FunctionDeclarations[0].Name)
+		require.NotNil(t, reqBody.ToolConfig)
+		assert.Equal(t, "AUTO", reqBody.ToolConfig.FunctionCallingConfig.Mode)
+
+		// Assert the previous call and its result are sent back as functionCall / functionResponse
+		require.Len(t, reqBody.Contents, 3)
+		assert.Equal(t, "model", reqBody.Contents[1].Role)
+		require.NotNil(t, reqBody.Contents[1].Parts[0].FunctionCall)
+		assert.Equal(t, "Beijing", reqBody.Contents[1].Parts[0].FunctionCall.Args["city"])
+		require.NotNil(t, reqBody.Contents[2].Parts[0].FunctionResponse)
+		assert.Equal(t, "get_weather", reqBody.Contents[2].Parts[0].FunctionResponse.Name)
+		assert.Equal(t, "sunny", reqBody.Contents[2].Parts[0].FunctionResponse.Response["content"])
+
+		w.Header().Set("Content-Type", "application/json")
+		resp := GeminiChatResponse{
+			Candidates: []GeminiCandidate{
+				{
+					Content: GeminiContent{
+						Role: "model",
+						Parts: []GeminiPart{{FunctionCall: &GeminiFunctionCall{
+							Name: "get_weather",
+							Args: map[string]interface{}{"city": "Paris"},
+						}}},
+					},
+					FinishReason: "STOP",
+				},
+			},
+		}
+		err = json.NewEncoder(w).Encode(resp)
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	req := &llm.ChatRequest{
+		Model: "gemini-2.0-flash",
+		Messages: []llm.Message{
+			{Role: "user", Content: "What's the weather in Beijing and Paris?"},
+			{Role: "assistant", ToolCalls: []llm.ToolCall{{ID: "call_1", Type: "function", Function: llm.FunctionCall{Name: "get_weather", Arguments: `{"city":"Beijing"}`}}}},
+			{Role: "tool", ToolCallID: "call_1", Content: "sunny"},
+		},
+		Tools: []map[string]interface{}{
+			{"type": "function", "function": map[string]interface{}{"name": "get_weather", "parameters": map[string]interface{}{"type": "object"}}},
+		},
+		ToolChoice: "auto",
+	}
+
+	resp, err := provider.Chat(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "get_weather", resp.ToolCalls[0].Function.Name)
+	assert.NotEmpty(t, resp.ToolCalls[0].ID)
+	assert.JSONEq(t, `{"city":"Paris"}`, resp.ToolCalls[0].Function.Arguments)
+}
+
+func TestGeminiProvider_Chat_WithParallelToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody GeminiChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+
+		// Both results are sent back in one content, in the order of the calls
+		require.Len(t, reqBody.Contents, 3)
+		require.Len(t, reqBody.Contents[1].Parts, 2)
+		results := reqBody.Contents[2]
+		assert.Equal(t, "user", results.Role)
+		require.Len(t, results.Parts, 2)
+		require.NotNil(t, results.Parts[0].FunctionResponse)
+		assert.Equal(t, "get_weather", results.Parts[0].FunctionResponse.Name)
+		assert.Equal(t, "sunny", results.Parts[0].FunctionResponse.Response["content"])
+		require.NotNil(t, results.Parts[1].FunctionResponse)
+		assert.Equal(t, "get_time", results.Parts[1].FunctionResponse.Name)
+		assert.Equal(t, "12:00", results.Parts[1].FunctionResponse.Response["content"])
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"Sunny, 12:00."}]},"finishReason":"STOP"}]}`))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	resp, err := provider.Chat(context.Background(), &llm.ChatRequest{
+		Model: "gemini-2.0-flash",
+		Messages: []llm.Message{
+			{Role: "user", Content: "Weather and time in Paris?"},
+			{Role: "assistant", ToolCalls: []llm.ToolCall{
+				{ID: "call_0_get_weather", Type: "function", Function: llm.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+				{ID: "call_1_get_time", Type: "function", Function: llm.FunctionCall{Name: "get_time", Arguments: `{}`}},
+			}},
+			{Role: "tool", ToolCallID: "call_0_get_weather", Name: "get_weather", Content: "sunny"},
+			{Role: "tool", ToolCallID: "call_1_get_time", Content: "12:00"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Sunny, 12:00.", resp.Content)
+}
+
+func TestGeminiProvider_ChatStreamEvents_WithThoughts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody GeminiChatRequest
+		err := json.NewDecoder(r.Body).Decode(&reqBody)
+		require.NoError(t, err)
+		require.NotNil(t, reqBody.GenerationConfig.ThinkingConfig)
+		assert.True(t, reqBody.GenerationConfig.ThinkingConfig.IncludeThoughts)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"candidates\": [{\"content\": {\"parts\": [{\"text\": \"Considering\", \"thought\": true}]}}]}\n\n"))
+		_, _ = w.Write([]byte("data: {\"candidates\": [{\"content\": {\"parts\": [{\"functionCall\": {\"name\": \"get_time\", \"args\": {}}}]}, \"finishReason\": \"STOP\"}], \"usageMetadata\": {\"promptTokenCount\": 4, \"totalTokenCount\": 9}}\n\n"))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	req := &llm.ChatRequest{
+		Model:     "gemini-2.5-flash",
+		Messages:  []llm.Message{{Role: "user", Content: "What time is it?"}},
+		Reasoning: &llm.ReasoningConfig{Effort: "low"},
+	}
+
+	var types []llm.StreamEventType
+	fullResp, err := provider.ChatStreamEvents(context.Background(), req, func(event llm.StreamEvent) error {
+		types = append(types, event.Type)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []llm.StreamEventType{llm.StreamEventReasoningDelta, llm.StreamEventToolCallDelta, llm.StreamEventUsage, llm.StreamEventDone}, types)
+	assert.Equal(t, "Considering", fullResp.Reasoning)
+	assert.Empty(t, fullResp.Content)
+	require.Len(t, fullResp.ToolCalls, 1)
+	assert.Equal(t, "get_time", fullResp.ToolCalls[0].Function.Name)
+	assert.Equal(t, 5, fullResp.Usage.CompletionTokens)
+}
+
+func TestGeminiProvider_Chat_WithJSONSchema(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody GeminiChatRequest
+		err := json.NewDecoder(r.Body).Decode(&reqBody)
+		require.NoError(t, err)
+
+		assert.Equal(t, "application/json", reqBody.GenerationConfig.ResponseMimeType)
+		schema := reqBody.GenerationConfig.ResponseSchema.(map[string]interface{})
+		assert.Equal(t, "object", schema["type"])
+		assert.NotContains(t, schema, "additionalProperties")
+		// nullable type arrays become a single type with "nullable"
+		unit := schema["properties"].(map[string]interface{})["unit"].(map[string]interface{})
+		assert.Equal(t, "string", unit["type"])
+		assert.Equal(t, true, unit["nullable"])
+		assert.Equal(t, []interface{}{"c", "f"}, unit["enum"])
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"{\"name\":\"Paris\"}"}]},"finishReason":"STOP"}]}`))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	resp, err := provider.Chat(context.Background(), &llm.ChatRequest{
+		Model:    "gemini-2.0-flash",
+		Messages: []llm.Message{{Role: "user", Content: "Capital of France?"}},
+		JSONSchema: &llm.JSONSchemaFormat{
+			Name: "city",
+			Schema: llm.JSONSchemaOf(struct {
+				Name string  `json:"name"`
+				Unit *string `json:"unit" enum:"c,f"`
+			}{}),
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"Paris"}`, resp.Content)
+}
+
+func TestGeminiProvider_SafetyBlocked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, ":generateContent"):
+			_, _ = w.Write([]byte(`{"promptFeedback":{"blockReason":"SAFETY","safetyRatings":[{"category":"HARM_CATEGORY_DANGEROUS_CONTENT","probability":"HIGH"}]}}`))
+		case strings.HasSuffix(r.URL.Path, ":streamGenerateContent"):
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"candidates\": [{\"finishReason\": \"PROHIBITED_CONTENT\"}]}\n\n"))
+		default:
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":{"code":429,"message":"Resource has been exhausted","status":"RESOURCE_EXHAUSTED"}}`))
+		}
+	}))
+	defer server.Close()
+	provider := newTestProvider(server.URL)
+	req := &llm.ChatRequest{Model: "gemini-pro", Messages: []llm.Message{{Role: "user", Content: "hi"}}}
+
+	_, err := provider.Chat(context.Background(), req)
+	var e *llm.Error
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, llm.ErrorSafetyBlocked, e.Category)
+	assert.Equal(t, "gemini", e.Provider)
+	assert.EqualError(t, err, "gemini: prompt blocked: SAFETY")
+
+	_, err = provider.ChatStream(context.Background(), req, func(string) error { return nil })
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, llm.ErrorSafetyBlocked, e.Category)
+	assert.Contains(t, err.Error(), "response blocked: PROHIBITED_CONTENT")
+
+	_, err = provider.CreateEmbeddings(context.Background(), &llm.EmbeddingRequest{Model: "embedding-001", Input: []string{"hi"}})
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, llm.ErrorRateLimited, e.Category)
+	assert.Equal(t, http.StatusTooManyRequests, e.Status)
+	var geminiErr *GeminiError
+	require.ErrorAs(t, err, &geminiErr)
+	assert.Equal(t, "RESOURCE_EXHAUSTED", geminiErr.Status)
+}
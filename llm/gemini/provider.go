@@ -54,14 +54,20 @@ func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRes
 		Timeout: p.cfg.Timeout,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("gemini: chat api call failed: %w", err)
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("gemini: chat api call failed: %w", err))
 	}
 
 	var geminiResp GeminiChatResponse
 	if err := json.Unmarshal(resp.Body, &geminiResp); err != nil {
-		return nil, fmt.Errorf("gemini: unmarshalling chat response: %w (body: %s)", err, string(resp.Body))
+		return nil, llm.NewError(providerName, resp.StatusCode, fmt.Errorf("gemini: unmarshalling chat response: %w (body: %s)", err, string(resp.Body)))
+	}
+	if geminiResp.Error != nil {
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("gemini: api error: %w", geminiResp.Error.withResponse(resp)))
 	}
 
+	if err := safetyError(geminiResp.PromptFeedback, geminiResp.Candidates, resp.StatusCode); err != nil {
+		return nil, err
+	}
 	if len(geminiResp.Candidates) == 0 {
 		return nil, fmt.Errorf("gemini: no candidates returned in response")
 	}
@@ -71,6 +77,11 @@ func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRes
 
 // ChatStream implements llm.Chatter for streaming chat
 func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, callback func(chunk string) error) (*llm.ChatResponse, error) {
+	return p.ChatStreamEvents(ctx, req, llm.TextCallback(callback))
+}
+
+// ChatStreamEvents implements llm.EventStreamer for streaming chat with structured events
+func (p *Provider) ChatStreamEvents(ctx context.Context, req *llm.ChatRequest, handler llm.StreamHandler) (*llm.ChatResponse, error) {
 	geminiReq, err := p.toGeminiChatRequest(ctx, req)
 	if err != nil {
 		return nil, err
@@ -82,14 +93,15 @@ func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, callbac
 
 	apiURL := fmt.Sprintf("%s/v1beta/models/%s:streamGenerateContent?alt=sse&key=%s", p.cfg.APIURL, req.Model, p.cfg.APIKey)
 
-	var fullContent strings.Builder
-	var finalResponse llm.ChatResponse
+	acc := llm.NewStreamAccumulator(handler)
+	toolCallCount := 0
 
 	timeout := p.cfg.Timeout
 	if timeout == 0 {
 		timeout = 5 * time.Minute
 	}
 
+	emitted := false // whether any content part has been received, used to detect responses blocked by safety filters
 	err = p.client.PostStream(ctx, xhttp.RequestPost{
 		URL:     apiURL,
 		Payload: bodyBytes,
@@ -114,37 +126,64 @@ func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, callbac
 			return nil // Continue to next line
 		}
 
+		if fb := streamResp.PromptFeedback; fb != nil && fb.BlockReason != "" {
+			return safetyError(fb, nil, 0)
+		}
 		if len(streamResp.Candidates) > 0 {
 			candidate := streamResp.Candidates[0]
-			// Check for content
-			if len(candidate.Content.Parts) > 0 && candidate.Content.Parts[0].Text != "" {
-				chunk := candidate.Content.Parts[0].Text
-				fullContent.WriteString(chunk)
-				if err := callback(chunk); err != nil {
-					return fmt.Errorf("callback error: %w", err)
+			if blockedFinishReasons[candidate.FinishReason] && !emitted && len(candidate.Content.Parts) == 0 {
+				return blockedError(candidate.FinishReason, 0)
+			}
+			if len(candidate.Content.Parts) > 0 {
+				emitted = true
+			}
+			for _, part := range candidate.Content.Parts {
+				switch {
+				case part.FunctionCall != nil:
+					// Function calls are not split across chunks, each part carries a complete call
+					tc := toLLMToolCall(part.FunctionCall, toolCallCount)
+					if err := acc.ToolCall(llm.ToolCallDelta{
+						Index:     toolCallCount,
+						ID:        tc.ID,
+						Type:      tc.Type,
+						Name:      tc.Function.Name,
+						Arguments: tc.Function.Arguments,
+					}); err != nil {
+						return err
+					}
+					toolCallCount++
+				case part.Thought:
+					if err := acc.Reasoning(part.Text); err != nil {
+						return err
+					}
+				default:
+					if err := acc.Text(part.Text); err != nil {
+						return err
+					}
 				}
 			}
 			// Check for finish reason
-			if candidate.FinishReason != "" {
-				finalResponse.FinishReason = candidate.FinishReason
-			}
+			acc.Finish(candidate.FinishReason)
 		}
 		if streamResp.UsageMetadata != nil {
-			finalResponse.Usage.PromptTokens = streamResp.UsageMetadata.PromptTokenCount
-			finalResponse.Usage.TotalTokens = streamResp.UsageMetadata.TotalTokenCount
-			// CompletionTokens can be derived if needed
-			finalResponse.Usage.CompletionTokens = streamResp.UsageMetadata.TotalTokenCount - streamResp.UsageMetadata.PromptTokenCount
+			if err := acc.Usage(llm.Usage{
+				PromptTokens: streamResp.UsageMetadata.PromptTokenCount,
+				TotalTokens:  streamResp.UsageMetadata.TotalTokenCount,
+				// CompletionTokens can be derived if needed
+				CompletionTokens: streamResp.UsageMetadata.TotalTokenCount - streamResp.UsageMetadata.PromptTokenCount,
+			}); err != nil {
+				return err
+			}
 		}
 
 		return nil
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("gemini: stream failed: %w", err)
+		return nil, acc.Fail(llm.NewError(providerName, 0, fmt.Errorf("gemini: stream failed: %w", err)))
 	}
 
-	finalResponse.Content = fullContent.String()
-	return &finalResponse, nil
+	return acc.Done()
 }
 
 // --- Embedder Implementation ---
@@ -182,12 +221,15 @@ func (p *Provider) CreateEmbeddings(ctx context.Context, req *llm.EmbeddingReque
 		Timeout: p.cfg.Timeout,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("gemini: embedding api call failed: %w", err)
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("gemini: embedding api call failed: %w", err))
 	}
 
 	var geminiResp GeminiEmbeddingResponse
 	if err := json.Unmarshal(resp.Body, &geminiResp); err != nil {
-		return nil, fmt.Errorf("gemini: unmarshalling embedding response: %w (body: %s)", err, string(resp.Body))
+		return nil, llm.NewError(providerName, resp.StatusCode, fmt.Errorf("gemini: unmarshalling embedding response: %w (body: %s)", err, string(resp.Body)))
+	}
+	if geminiResp.Error != nil {
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("gemini: embedding api error: %w", geminiResp.Error.withResponse(resp)))
 	}
 
 	return &llm.EmbeddingResponse{
@@ -223,6 +265,23 @@ func (p *Provider) toGeminiChatRequest(ctx context.Context, req *llm.ChatRequest
 	if req.Stop != nil && len(req.Stop) > 0 {
 		geminiReq.GenerationConfig.StopSequences = req.Stop
 	}
+	if req.JSONSchema != nil {
+		geminiReq.GenerationConfig.ResponseMimeType = "application/json"
+		geminiReq.GenerationConfig.ResponseSchema = sanitizeSchema(req.JSONSchema.Schema)
+	} else if req.ResponseFormat == "json_object" {
+		geminiReq.GenerationConfig.ResponseMimeType = "application/json"
+	}
+	if req.Reasoning != nil {
+		thinking := &ThinkingConfig{ThinkingBudget: req.Reasoning.MaxTokens}
+		if req.Reasoning.Exclude == nil || !*req.Reasoning.Exclude {
+			thinking.IncludeThoughts = true
+		}
+		if req.Reasoning.Enabled != nil && !*req.Reasoning.Enabled {
+			zero := 0
+			thinking = &ThinkingConfig{ThinkingBudget: &zero}
+		}
+		geminiReq.GenerationConfig.ThinkingConfig = thinking
+	}
 
 	messages := req.Messages
 	// Find and set system instruction if it exists
@@ -236,27 +295,259 @@ func (p *Provider) toGeminiChatRequest(ctx context.Context, req *llm.ChatRequest
 		messages = messages[1:]
 	}
 
+	// Gemini identifies function responses by name, so remember the name of every tool call id
+	callNames := make(map[string]string)
 	contents := make([]GeminiContent, 0, len(messages))
+	prevTool := false // whether the last content holds tool results
 	for _, msg := range messages {
-		role := "user" // Default role
-		if msg.Role == "assistant" {
-			role = "model"
-		} else if msg.Role == "system" {
+		switch msg.Role {
+		case "system":
 			// If a system message is found not at the beginning, ignore it for contents.
 			// The Gemini API expects only one system instruction at the start.
 			logger.Warn(ctx, logTag, "System message found in a position other than the first message, it will be ignored.")
 			continue
+		case "tool":
+			name := msg.Name
+			if name == "" {
+				name = callNames[msg.ToolCallID]
+			}
+			part := GeminiPart{FunctionResponse: &GeminiFunctionResponse{
+				Name:     name,
+				Response: toFunctionResponse(msg.Content),
+			}}
+			// Results of parallel tool calls must be sent back in a single content,
+			// one functionResponse part per call and in the order of the calls
+			if prevTool {
+				last := &contents[len(contents)-1]
+				last.Parts = append(last.Parts, part)
+				continue
+			}
+			contents = append(contents, GeminiContent{Role: "user", Parts: []GeminiPart{part}})
+			prevTool = true
+			continue
+		}
+		prevTool = false
+
+		role := "user" // Default role
+		if msg.Role == "assistant" {
+			role = "model"
+		}
+		var parts []GeminiPart
+		if len(msg.ToolCalls) == 0 || llm.ContentString(msg.Content) != "" {
+			parts = p.contentToGeminiParts(msg.Content)
+		}
+		for _, tc := range msg.ToolCalls {
+			callNames[tc.ID] = tc.Function.Name
+			var args map[string]interface{}
+			if tc.Function.Arguments != "" {
+				if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+					return nil, fmt.Errorf("gemini: invalid arguments for tool call %s: %w", tc.ID, err)
+				}
+			}
+			parts = append(parts, GeminiPart{FunctionCall: &GeminiFunctionCall{
+				Name: tc.Function.Name,
+				Args: args,
+			}})
 		}
 		contents = append(contents, GeminiContent{
 			Role:  role,
-			Parts: p.contentToGeminiParts(msg.Content),
+			Parts: parts,
 		})
 	}
 	geminiReq.Contents = contents
 
+	tools, err := toGeminiTools(req.Tools)
+	if err != nil {
+		return nil, err
+	}
+	geminiReq.Tools = tools
+	geminiReq.ToolConfig = toGeminiToolConfig(req.ToolChoice)
+
 	return geminiReq, nil
 }
 
+// toGeminiTools converts OpenAI compatible tool definitions
+// ({"type": "function", "function": {"name": ..., "description": ..., "parameters": ...}})
+// into Gemini function declarations
+func toGeminiTools(tools []map[string]interface{}) ([]GeminiTool, error) {
+	if len(tools) == 0 {
+		return nil, nil
+	}
+	decls := make([]GeminiFunctionDeclaration, 0, len(tools))
+	for _, t := range tools {
+		raw, err := json.Marshal(t)
+		if err != nil {
+			return nil, fmt.Errorf("gemini: marshalling tool definition: %w", err)
+		}
+		var def struct {
+			Type     string                    `json:"type"`
+			Function GeminiFunctionDeclaration `json:"function"`
+		}
+		if err := json.Unmarshal(raw, &def); err != nil {
+			return nil, fmt.Errorf("gemini: parsing tool definition: %w", err)
+		}
+		if def.Type != "" && def.Type != "function" {
+			continue
+		}
+		if def.Function.Name == "" {
+			return nil, fmt.Errorf("gemini: tool definition missing function name")
+		}
+		def.Function.Parameters = sanitizeSchema(def.Function.Parameters)
+		decls = append(decls, def.Function)
+	}
+	if len(decls) == 0 {
+		return nil, nil
+	}
+	return []GeminiTool{{FunctionDeclarations: decls}}, nil
+}
+
+// sanitizeSchema removes JSON Schema keywords that are not part of the OpenAPI subset accepted by Gemini,
+// nullable type arrays such as ["string","null"] are converted to a single type with "nullable": true
+func sanitizeSchema(schema interface{}) interface{} {
+	switch v := schema.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		nullable := false
+		for key, val := range v {
+			switch key {
+			case "additionalProperties", "$schema":
+				continue
+			case "type":
+				if types, ok := schemaTypes(val); ok {
+					var rest []string
+					for _, t := range types {
+						if t == "null" {
+							nullable = true
+							continue
+						}
+						rest = append(rest, t)
+					}
+					if len(rest) == 1 {
+						out[key] = rest[0]
+					}
+					continue
+				}
+			case "enum":
+				if values, ok := val.([]interface{}); ok {
+					enum := make([]interface{}, 0, len(values))
+					for _, e := range values {
+						if e != nil {
+							enum = append(enum, e)
+						}
+					}
+					out[key] = enum
+					continue
+				}
+			}
+			out[key] = sanitizeSchema(val)
+		}
+		if nullable {
+			out["nullable"] = true
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = sanitizeSchema(val)
+		}
+		return out
+	default:
+		return schema
+	}
+}
+
+// schemaTypes returns the entries of a type array, ok is false when the type is not an array
+func schemaTypes(v interface{}) ([]string, bool) {
+	switch types := v.(type) {
+	case []string:
+		return types, true
+	case []interface{}:
+		out := make([]string, 0, len(types))
+		for _, t := range types {
+			if s, ok := t.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// toGeminiToolConfig maps the OpenAI compatible tool_choice ("auto", "none", "required"
+// or {"type": "function", "function": {"name": ...}}) to the Gemini function calling config
+func toGeminiToolConfig(choice interface{}) *GeminiToolConfig {
+	if choice == nil {
+		return nil
+	}
+	if s, ok := choice.(string); ok {
+		mode := ""
+		switch s {
+		case "auto":
+			mode = "AUTO"
+		case "none":
+			mode = "NONE"
+		case "required", "any":
+			mode = "ANY"
+		default:
+			return nil
+		}
+		return &GeminiToolConfig{FunctionCallingConfig: &GeminiFunctionCallingConfig{Mode: mode}}
+	}
+	raw, err := json.Marshal(choice)
+	if err != nil {
+		return nil
+	}
+	var named struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(raw, &named); err != nil || named.Function.Name == "" {
+		return nil
+	}
+	return &GeminiToolConfig{FunctionCallingConfig: &GeminiFunctionCallingConfig{
+		Mode:                 "ANY",
+		AllowedFunctionNames: []string{named.Function.Name},
+	}}
+}
+
+// toFunctionResponse converts the content of a tool message into the object expected by Gemini.
+// JSON objects are passed through as-is, anything else is wrapped as {"content": ...}
+func toFunctionResponse(content interface{}) map[string]interface{} {
+	if m, ok := content.(map[string]interface{}); ok {
+		return m
+	}
+	text := llm.ContentString(content)
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &obj); err == nil && obj != nil {
+		return obj
+	}
+	return map[string]interface{}{"content": text}
+}
+
+// toLLMToolCall converts a Gemini function call into llm.ToolCall.
+// Gemini does not always return call ids, so a stable id is generated from the position when missing.
+func toLLMToolCall(fc *GeminiFunctionCall, index int) llm.ToolCall {
+	id := fc.ID
+	if id == "" {
+		id = fmt.Sprintf("call_%d_%s", index, fc.Name)
+	}
+	args := "{}"
+	if len(fc.Args) > 0 {
+		if b, err := json.Marshal(fc.Args); err == nil {
+			args = string(b)
+		}
+	}
+	return llm.ToolCall{
+		ID:   id,
+		Type: "function",
+		Function: llm.FunctionCall{
+			Name:      fc.Name,
+			Arguments: args,
+		},
+	}
+}
+
 // contentToGeminiParts converts llm.Message.Content to []GeminiPart
 // Supports:
 // - string: simple text content
@@ -333,25 +624,73 @@ func parseDataURI(uri string) *InlineData {
 	}
 }
 
+// blockedFinishReasons are the finish reasons meaning the response was blocked by safety filters
+var blockedFinishReasons = map[string]bool{
+	"SAFETY":             true,
+	"BLOCKLIST":          true,
+	"PROHIBITED_CONTENT": true,
+	"SPII":               true,
+	"IMAGE_SAFETY":       true,
+}
+
+// safetyError returns an llm.Error of category safety_blocked when the prompt is blocked,
+// or when the first candidate is blocked before producing any content
+func safetyError(feedback *PromptFeedback, candidates []GeminiCandidate, status int) error {
+	if feedback != nil && feedback.BlockReason != "" {
+		return &llm.Error{
+			Category: llm.ErrorSafetyBlocked,
+			Provider: providerName,
+			Status:   status,
+			Err:      fmt.Errorf("gemini: prompt blocked: %s", feedback.BlockReason),
+		}
+	}
+	if len(candidates) > 0 && blockedFinishReasons[candidates[0].FinishReason] && len(candidates[0].Content.Parts) == 0 {
+		return blockedError(candidates[0].FinishReason, status)
+	}
+	return nil
+}
+
+func blockedError(finishReason string, status int) error {
+	return &llm.Error{
+		Category: llm.ErrorSafetyBlocked,
+		Provider: providerName,
+		Status:   status,
+		Err:      fmt.Errorf("gemini: response blocked: %s", finishReason),
+	}
+}
+
 func (p *Provider) toLLMChatResponse(geminiResp *GeminiChatResponse) *llm.ChatResponse {
-	var content string
+	var content strings.Builder
+	var reasoning strings.Builder
+	var toolCalls []llm.ToolCall
 	var finishReason string
 	if len(geminiResp.Candidates) > 0 {
 		// Assuming the first candidate is the one we want
 		candidate := geminiResp.Candidates[0]
-		if len(candidate.Content.Parts) > 0 {
-			content = candidate.Content.Parts[0].Text
+		for _, part := range candidate.Content.Parts {
+			if part.FunctionCall != nil {
+				toolCalls = append(toolCalls, toLLMToolCall(part.FunctionCall, len(toolCalls)))
+				continue
+			}
+			if part.Thought {
+				reasoning.WriteString(part.Text)
+				continue
+			}
+			content.WriteString(part.Text)
 		}
 		finishReason = candidate.FinishReason
 	}
 	// Note: Gemini API v1beta for non-streaming chat doesn't return token usage.
 	// This would need to be fetched from a different field or API if available.
 	return &llm.ChatResponse{
-		Content:      content,
+		Content:      content.String(),
+		Reasoning:    reasoning.String(),
+		ToolCalls:    toolCalls,
 		FinishReason: finishReason,
 	}
 }
 
 // Ensure *Provider implements the interfaces
 var _ llm.Chatter = (*Provider)(nil)
+var _ llm.EventStreamer = (*Provider)(nil)
 var _ llm.Embedder = (*Provider)(nil)
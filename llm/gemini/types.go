@@ -2,7 +2,10 @@
 package gemini
 
 import (
+	"net/http"
 	"time"
+
+	xhttp "github.com/jessewkun/gocommon/http"
 )
 
 // Config for the Gemini provider
@@ -13,10 +16,50 @@ type Config struct {
 }
 
 // GeminiPart is a part of a GeminiContent
-// Can contain either text or inline binary data (e.g., images)
+// Can contain text, inline binary data (e.g., images), a function call or a function response
 type GeminiPart struct {
-	Text       string      `json:"text,omitempty"`
-	InlineData *InlineData `json:"inlineData,omitempty"`
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"` // true when Text is a thought summary
+	InlineData       *InlineData             `json:"inlineData,omitempty"`
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// GeminiFunctionCall is a function call predicted by the model
+type GeminiFunctionCall struct {
+	ID   string                 `json:"id,omitempty"`
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// GeminiFunctionResponse is the result of a function call sent back to the model
+type GeminiFunctionResponse struct {
+	ID       string                 `json:"id,omitempty"`
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+// GeminiFunctionDeclaration describes a function the model may call
+type GeminiFunctionDeclaration struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Parameters  interface{} `json:"parameters,omitempty"`
+}
+
+// GeminiTool is a set of function declarations available to the model
+type GeminiTool struct {
+	FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+// GeminiFunctionCallingConfig controls how the model uses the declared functions
+type GeminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode"` // "AUTO", "ANY" or "NONE"
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+// GeminiToolConfig is the tool configuration shared by all tools in the request
+type GeminiToolConfig struct {
+	FunctionCallingConfig *GeminiFunctionCallingConfig `json:"functionCallingConfig,omitempty"`
 }
 
 // InlineData represents inline binary data (e.g., images) for multimodal input
@@ -54,6 +97,7 @@ type SafetyRating struct {
 type GeminiChatResponse struct {
 	Candidates     []GeminiCandidate `json:"candidates"`
 	PromptFeedback *PromptFeedback   `json:"promptFeedback,omitempty"`
+	Error          *GeminiError      `json:"error,omitempty"`
 }
 
 // GeminiEmbeddingRequest is the request for the Gemini Embedding API
@@ -70,15 +114,62 @@ type EmbeddingValue struct {
 // GeminiEmbeddingResponse is the response from the Gemini Embedding API
 type GeminiEmbeddingResponse struct {
 	Embedding EmbeddingValue `json:"embedding"`
+	Error     *GeminiError   `json:"error,omitempty"`
+}
+
+// GeminiError is the error object returned by the API
+type GeminiError struct {
+	Code    int    `json:"code"`    // HTTP status code
+	Message string `json:"message"` // error message
+	Status  string `json:"status"`  // e.g. "RESOURCE_EXHAUSTED", "INVALID_ARGUMENT"
+
+	retryAfter time.Duration // parsed from the Retry-After response header
+}
+
+// Error implements error
+func (e *GeminiError) Error() string {
+	return e.Status + ": " + e.Message
+}
+
+// StatusCode returns the HTTP status code of the error
+func (e *GeminiError) StatusCode() int {
+	return e.Code
+}
+
+// RetryAfter returns the delay suggested by the Retry-After response header, 0 if absent
+func (e *GeminiError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// RateLimited reports whether the error is caused by rate limiting
+func (e *GeminiError) RateLimited() bool {
+	return e.Code == http.StatusTooManyRequests || e.Status == "RESOURCE_EXHAUSTED"
+}
+
+func (e *GeminiError) withResponse(resp *xhttp.Response) *GeminiError {
+	if e.Code == 0 {
+		e.Code = resp.StatusCode
+	}
+	e.retryAfter = xhttp.ParseRetryAfter(resp.Header)
+	return e
 }
 
 // GenerationConfig controls the generation of the response
 type GenerationConfig struct {
-	Temperature     float64  `json:"temperature,omitempty"`
-	TopP            *float64 `json:"topP,omitempty"`
-	TopK            *int     `json:"topK,omitempty"`
-	MaxOutputTokens *int     `json:"maxOutputTokens,omitempty"`
-	StopSequences   []string `json:"stopSequences,omitempty"`
+	Temperature      float64         `json:"temperature,omitempty"`
+	TopP             *float64        `json:"topP,omitempty"`
+	TopK             *int            `json:"topK,omitempty"`
+	MaxOutputTokens  *int            `json:"maxOutputTokens,omitempty"`
+	StopSequences    []string        `json:"stopSequences,omitempty"`
+	ThinkingConfig   *ThinkingConfig `json:"thinkingConfig,omitempty"`
+	ResponseMimeType string          `json:"responseMimeType,omitempty"` // "application/json" for JSON mode and structured output
+	ResponseSchema   interface{}     `json:"responseSchema,omitempty"`   // OpenAPI subset of JSON Schema, requires ResponseMimeType
+}
+
+// ThinkingConfig controls the thinking behaviour of thinking models
+type ThinkingConfig struct {
+	IncludeThoughts bool `json:"includeThoughts,omitempty"` // return thought summaries as parts with thought=true
+	ThinkingBudget  *int `json:"thinkingBudget,omitempty"`  // thinking token budget, 0 disables thinking
 }
 
 // GeminiChatRequest is the request to the Gemini Chat API
@@ -86,6 +177,8 @@ type GeminiChatRequest struct {
 	Contents          []GeminiContent   `json:"contents"`
 	SystemInstruction *GeminiContent    `json:"system_instruction,omitempty"`
 	GenerationConfig  *GenerationConfig `json:"generationConfig,omitempty"`
+	Tools             []GeminiTool      `json:"tools,omitempty"`
+	ToolConfig        *GeminiToolConfig `json:"toolConfig,omitempty"`
 }
 
 // --- Stream-specific types ---
@@ -106,6 +199,7 @@ type GeminiStreamUsageMetadata struct {
 
 // GeminiStreamResponse is a single chunk in a streaming response
 type GeminiStreamResponse struct {
-	Candidates    []GeminiStreamCandidate    `json:"candidates"`
-	UsageMetadata *GeminiStreamUsageMetadata `json:"usageMetadata,omitempty"`
+	Candidates     []GeminiStreamCandidate    `json:"candidates"`
+	PromptFeedback *PromptFeedback            `json:"promptFeedback,omitempty"`
+	UsageMetadata  *GeminiStreamUsageMetadata `json:"usageMetadata,omitempty"`
 }
@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"time"
+)
+
+// Operation 调用类型
+type Operation string
+
+const (
+	OperationChat       Operation = "chat"        // Chat
+	OperationChatStream Operation = "chat_stream" // ChatStream / ChatStreamEvents
+	OperationEmbeddings Operation = "embeddings"  // CreateEmbeddings
+	OperationImages     Operation = "images"      // GenerateImages
+	OperationTranscribe Operation = "transcribe"  // Transcribe
+)
+
+// CallInfo 单次调用的基本信息，传递给 Hook
+type CallInfo struct {
+	Provider  string    // Provider 名称
+	Model     string    // 请求的模型名
+	Operation Operation // 调用类型
+	StartTime time.Time // 调用开始时间
+}
+
+// Hook 调用钩子，由 Client 在每次调用前后触发，可用于用量统计、计费、配额控制等
+type Hook interface {
+	// Before 在请求发出前调用，返回错误则拒绝本次调用，错误会原样返回给调用方
+	Before(ctx context.Context, info *CallInfo) error
+	// After 在请求结束后调用，usage 为本次调用的 token 用量（失败时通常为零值）
+	After(ctx context.Context, info *CallInfo, usage Usage, err error)
+}
+
+// Use 为 Client 注册钩子，按注册顺序执行 Before，按相反顺序执行 After
+// 需在发起调用前完成注册，不支持与调用并发执行
+func (c *Client) Use(hooks ...Hook) {
+	c.hooks = append(c.hooks, hooks...)
+}
+
+// before 依次执行 Before，某个钩子拒绝时，已执行过 Before 的钩子会收到 After 通知
+func (c *Client) before(ctx context.Context, op Operation, model string) (*CallInfo, error) {
+	info := &CallInfo{
+		Provider:  c.provider.Name(),
+		Model:     model,
+		Operation: op,
+		StartTime: time.Now(),
+	}
+	for i, h := range c.hooks {
+		if err := h.Before(ctx, info); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				c.hooks[j].After(ctx, info, Usage{}, err)
+			}
+			return info, err
+		}
+	}
+	return info, nil
+}
+
+// after 逆序执行 After
+func (c *Client) after(ctx context.Context, info *CallInfo, usage Usage, err error) {
+	for i := len(c.hooks) - 1; i >= 0; i-- {
+		c.hooks[i].After(ctx, info, usage, err)
+	}
+}
@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordHook 记录钩子的调用顺序，reject 不为空时在 Before 中拒绝调用
+type recordHook struct {
+	name   string
+	reject error
+	events *[]string
+	usage  Usage
+	err    error
+}
+
+func (h *recordHook) Before(ctx context.Context, info *CallInfo) error {
+	*h.events = append(*h.events, h.name+".before:"+string(info.Operation)+":"+info.Model)
+	return h.reject
+}
+
+func (h *recordHook) After(ctx context.Context, info *CallInfo, usage Usage, err error) {
+	*h.events = append(*h.events, h.name+".after")
+	h.usage = usage
+	h.err = err
+}
+
+func TestClient_Hooks(t *testing.T) {
+	var events []string
+	first := &recordHook{name: "a", events: &events}
+	second := &recordHook{name: "b", events: &events}
+	chatter := &scriptedChatter{responses: []*ChatResponse{{Content: "hi", Usage: Usage{PromptTokens: 2, CompletionTokens: 3, TotalTokens: 5}}}}
+	client := &Client{provider: chatter}
+	client.Use(first, second)
+
+	_, err := client.Chat(context.Background(), &ChatRequest{Model: "m1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.before:chat:m1", "b.before:chat:m1", "b.after", "a.after"}, events)
+	assert.Equal(t, 5, first.usage.TotalTokens)
+
+	// 被拒绝时不调用 Provider，已执行 Before 的钩子收到拒绝错误
+	events = nil
+	rejectErr := errors.New("rejected")
+	second.reject = rejectErr
+	_, err = client.ChatStreamEvents(context.Background(), &ChatRequest{Model: "m2"}, func(e StreamEvent) error { return nil })
+	assert.ErrorIs(t, err, rejectErr)
+	assert.Equal(t, []string{"a.before:chat_stream:m2", "b.before:chat_stream:m2", "a.after"}, events)
+	assert.ErrorIs(t, first.err, rejectErr)
+	assert.Len(t, chatter.requests, 1)
+}
@@ -0,0 +1,102 @@
+package llm
+
+import "context"
+
+// Call 拦截器处理的一次调用
+type Call struct {
+	Operation     Operation             // 调用类型
+	Provider      string                // Provider 名称
+	Chat          *ChatRequest          // 对话请求，Operation 为 chat / chat_stream 时有效
+	Stream        StreamHandler         // 流式事件回调，Operation 为 chat_stream 时有效，ChatStream 的回调会被转换为只处理 text_delta 的 StreamHandler
+	Embedding     *EmbeddingRequest     // 向量化请求，Operation 为 embeddings 时有效
+	Image         *ImageRequest         // 图片生成请求，Operation 为 images 时有效
+	Transcription *TranscriptionRequest // 语音转写请求，Operation 为 transcribe 时有效
+}
+
+// Model 返回本次调用请求的模型名
+func (c *Call) Model() string {
+	if c.Embedding != nil {
+		return c.Embedding.Model
+	}
+	if c.Chat != nil {
+		return c.Chat.Model
+	}
+	if c.Image != nil {
+		return c.Image.Model
+	}
+	if c.Transcription != nil {
+		return c.Transcription.Model
+	}
+	return ""
+}
+
+// Result 调用结果，按 Operation 填充对应字段
+type Result struct {
+	Chat          *ChatResponse
+	Embedding     *EmbeddingResponse
+	Image         *ImageResponse
+	Transcription *TranscriptionResponse
+}
+
+// Handler 处理一次调用
+type Handler func(ctx context.Context, call *Call) (*Result, error)
+
+// Interceptor 拦截器，包装下一个 Handler，可在调用前后执行逻辑、改写请求与结果或重试
+// 拦截器不应修改传入的 Call 及其请求，需要改写时复制一份再传给 next
+type Interceptor func(next Handler) Handler
+
+// Intercept 为 Client 注册拦截器，先注册的在外层
+// 拦截器包在钩子外层，重试等拦截器每次调用 next 都会触发一次钩子
+// 需在发起调用前完成注册，不支持与调用并发执行
+func (c *Client) Intercept(interceptors ...Interceptor) {
+	c.interceptors = append(c.interceptors, interceptors...)
+}
+
+// handle 依次经过拦截器后执行调用
+func (c *Client) handle(ctx context.Context, call *Call) (*Result, error) {
+	h := c.invoke
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		h = c.interceptors[i](h)
+	}
+	return h(ctx, call)
+}
+
+// invoke 拦截器链的末端：触发钩子并调用 Provider
+func (c *Client) invoke(ctx context.Context, call *Call) (*Result, error) {
+	info, err := c.before(ctx, call.Operation, call.Model())
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{}
+	var usage Usage
+	switch call.Operation {
+	case OperationEmbeddings:
+		result.Embedding, err = c.provider.(Embedder).CreateEmbeddings(ctx, call.Embedding)
+		if result.Embedding != nil {
+			usage = result.Embedding.Usage
+		}
+	case OperationImages:
+		result.Image, err = c.provider.(ImageGenerator).GenerateImages(ctx, call.Image)
+		if result.Image != nil {
+			usage = result.Image.Usage
+		}
+	case OperationTranscribe:
+		result.Transcription, err = c.provider.(Transcriber).Transcribe(ctx, call.Transcription)
+		if result.Transcription != nil {
+			usage = result.Transcription.Usage
+		}
+	case OperationChatStream:
+		result.Chat, err = c.chatStreamEvents(ctx, call.Chat, call.Stream)
+		usage = usageOf(result.Chat)
+	default:
+		result.Chat, err = c.provider.(Chatter).Chat(ctx, call.Chat)
+		usage = usageOf(result.Chat)
+	}
+	// Provider 未包装的错误（如网络错误、自定义 Provider 的错误）统一包装为 *Error
+	err = WrapError(call.Provider, err)
+	c.after(ctx, info, usage, err)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,239 @@
+package interceptor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jessewkun/gocommon/constant"
+	"github.com/jessewkun/gocommon/llm"
+	_ "github.com/jessewkun/gocommon/llm/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statusError 模拟携带 HTTP 状态码的 Provider 错误
+type statusError struct {
+	code       int
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string             { return fmt.Sprintf("status %d", e.code) }
+func (e *statusError) StatusCode() int           { return e.code }
+func (e *statusError) RetryAfter() time.Duration { return e.retryAfter }
+
+// fakeProvider 按顺序返回预设错误，错误耗尽后返回成功，并记录收到的请求
+type fakeProvider struct {
+	errs     []error
+	chunks   []string
+	requests []*llm.ChatRequest
+	inputs   [][]string
+}
+
+func (f *fakeProvider) Name() string { return "interceptor-fake" }
+
+func (f *fakeProvider) nextErr() error {
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func (f *fakeProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	f.requests = append(f.requests, req)
+	if err := f.nextErr(); err != nil {
+		return nil, err
+	}
+	return &llm.ChatResponse{Content: "ok", FinishReason: "stop"}, nil
+}
+
+func (f *fakeProvider) ChatStream(ctx context.Context, req *llm.ChatRequest, callback func(chunk string) error) (*llm.ChatResponse, error) {
+	f.requests = append(f.requests, req)
+	for _, c := range f.chunks {
+		if err := callback(c); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.nextErr(); err != nil {
+		return nil, err
+	}
+	return &llm.ChatResponse{Content: "ok", FinishReason: "stop"}, nil
+}
+
+func (f *fakeProvider) CreateEmbeddings(ctx context.Context, req *llm.EmbeddingRequest) (*llm.EmbeddingResponse, error) {
+	f.inputs = append(f.inputs, req.Input)
+	return &llm.EmbeddingResponse{Model: req.Model}, nil
+}
+
+func newTestClient(t *testing.T, f *fakeProvider) *llm.Client {
+	name := fmt.Sprintf("interceptor-test-%s", t.Name())
+	llm.Register(name, func(config interface{}) (llm.Provider, error) { return f, nil })
+	client, err := llm.NewClient(name, nil)
+	require.NoError(t, err)
+	return client
+}
+
+func TestRetry(t *testing.T) {
+	f := &fakeProvider{errs: []error{
+		&statusError{code: http.StatusTooManyRequests},
+		fmt.Errorf("wrapped: %w", &statusError{code: http.StatusTooManyRequests, retryAfter: 5 * time.Millisecond}),
+	}}
+	client := newTestClient(t, f)
+	client.Intercept(Retry(RetryConfig{BaseDelay: time.Millisecond}))
+
+	resp, err := client.Chat(context.Background(), &llm.ChatRequest{Model: "m"})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Content)
+	assert.Len(t, f.requests, 3)
+
+	// 非限流错误不重试
+	f.requests = nil
+	f.errs = []error{&statusError{code: http.StatusBadRequest}}
+	_, err = client.Chat(context.Background(), &llm.ChatRequest{Model: "m"})
+	assert.Error(t, err)
+	assert.Len(t, f.requests, 1)
+
+	// 重试次数耗尽后返回最后一次的错误
+	f.requests = nil
+	f.errs = []error{&statusError{code: 429}, &statusError{code: 429}, &statusError{code: 429}}
+	limited, err := llm.NewClient(fmt.Sprintf("interceptor-test-%s", t.Name()), nil)
+	require.NoError(t, err)
+	limited.Intercept(Retry(RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond}))
+	_, err = limited.Chat(context.Background(), &llm.ChatRequest{Model: "m"})
+	assert.True(t, llm.IsRateLimited(err))
+	assert.Len(t, f.requests, 3)
+}
+
+func TestRetry_StreamAfterOutput(t *testing.T) {
+	f := &fakeProvider{chunks: []string{"partial"}, errs: []error{&statusError{code: 429}}}
+	client := newTestClient(t, f)
+	client.Intercept(Retry(RetryConfig{BaseDelay: time.Millisecond}))
+
+	var chunks []string
+	_, err := client.ChatStream(context.Background(), &llm.ChatRequest{Model: "m"}, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"partial"}, chunks)
+	assert.Len(t, f.requests, 1)
+
+	// 尚未输出时可以重试
+	f.chunks = nil
+	f.requests = nil
+	f.errs = []error{&statusError{code: 429}}
+	_, err = client.ChatStream(context.Background(), &llm.ChatRequest{Model: "m"}, func(chunk string) error { return nil })
+	assert.NoError(t, err)
+	assert.Len(t, f.requests, 2)
+}
+
+func TestRetry_ContextCanceled(t *testing.T) {
+	f := &fakeProvider{errs: []error{&statusError{code: 429, retryAfter: time.Hour}}}
+	client := newTestClient(t, f)
+	client.Intercept(Retry(RetryConfig{MaxDelay: time.Hour}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := client.Chat(ctx, &llm.ChatRequest{Model: "m"})
+	assert.True(t, llm.IsRateLimited(err))
+	assert.Len(t, f.requests, 1)
+}
+
+func TestRedact(t *testing.T) {
+	f := &fakeProvider{}
+	client := newTestClient(t, f)
+	client.Intercept(Redact(nil))
+
+	req := &llm.ChatRequest{Model: "m", Messages: []llm.Message{
+		{Role: "user", Content: "我的手机号是13812345678，邮箱 foo.bar@example.com"},
+		{Role: "user", Content: []interface{}{
+			map[string]interface{}{"type": "text", "text": "身份证 11010519491231002X 卡号 6222021234567890123"},
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "https://example.com/a.png"}},
+		}},
+	}}
+	_, err := client.Chat(context.Background(), req)
+	require.NoError(t, err)
+
+	sent := f.requests[0].Messages
+	assert.Equal(t, "我的手机号是[PHONE]，邮箱 [EMAIL]", sent[0].Content)
+	parts := sent[1].Content.([]interface{})
+	assert.Equal(t, "身份证 [ID_CARD] 卡号 [BANK_CARD]", parts[0].(map[string]interface{})["text"])
+	assert.Equal(t, req.Messages[1].Content.([]interface{})[1], parts[1])
+	// 调用方的请求不被修改
+	assert.Contains(t, req.Messages[0].Content, "13812345678")
+	assert.Contains(t, req.Messages[1].Content.([]interface{})[0].(map[string]interface{})["text"], "11010519491231002X")
+
+	_, err = client.CreateEmbeddings(context.Background(), &llm.EmbeddingRequest{Model: "e", Input: []string{"联系 13912345678"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"联系 [PHONE]"}, f.inputs[0])
+}
+
+func TestTrace(t *testing.T) {
+	var traceHeader, authHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceHeader = r.Header.Get("trace_id")
+		authHeader = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]interface{}{"role": "assistant", "content": "hi"}, "finish_reason": "stop"}},
+		})
+	}))
+	defer server.Close()
+
+	client, err := llm.NewClient("openai", map[string]interface{}{"api_url": server.URL, "api_key": "sk-test"})
+	require.NoError(t, err)
+	client.Intercept(Trace(""))
+
+	ctx := context.WithValue(context.Background(), constant.CtxTraceID, "trace-123")
+	_, err = client.Chat(ctx, &llm.ChatRequest{Model: "gpt-4o-mini", Messages: []llm.Message{{Role: "user", Content: "hi"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "trace-123", traceHeader)
+	assert.Equal(t, "Bearer sk-test", authHeader)
+}
+
+func TestIsRateLimited_ProviderErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error": {"message": "Rate limit reached", "type": "requests", "code": "rate_limit_exceeded"}}`))
+	}))
+	defer server.Close()
+
+	client, err := llm.NewClient("openai", map[string]interface{}{"api_url": server.URL, "api_key": "sk-test"})
+	require.NoError(t, err)
+	_, err = client.Chat(context.Background(), &llm.ChatRequest{Model: "gpt-4o-mini"})
+	require.Error(t, err)
+	assert.True(t, llm.IsRateLimited(err))
+	assert.Equal(t, 2*time.Second, llm.RetryAfter(err))
+	assert.Equal(t, "openai: api error: Rate limit reached", err.Error())
+
+	// 流式调用的错误状态由 http.StatusError 携带
+	_, err = client.ChatStream(context.Background(), &llm.ChatRequest{Model: "gpt-4o-mini"}, func(string) error { return nil })
+	require.Error(t, err)
+	assert.True(t, llm.IsRateLimited(err))
+	assert.Equal(t, 2*time.Second, llm.RetryAfter(err))
+	assert.False(t, llm.IsRateLimited(errors.New("boom")))
+}
+
+func TestLoggingConfig_Content(t *testing.T) {
+	cfg := LoggingConfig{MaxContentLength: 10, Redactor: NewRedactor(nil)}
+	assert.Equal(t, "电话 [PHONE]", cfg.content("电话 13812345678"))
+	assert.Equal(t, "一二三四五六七八九十...(truncated)", cfg.content("一二三四五六七八九十百"))
+
+	call := &llm.Call{Chat: &llm.ChatRequest{Messages: []llm.Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: []interface{}{
+			map[string]interface{}{"type": "text", "text": "what is this"},
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "x"}},
+		}},
+	}}}
+	assert.Equal(t, "system: be brief\nuser: what is this [image_url]", promptOf(call))
+}
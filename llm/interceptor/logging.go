@@ -0,0 +1,134 @@
+package interceptor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/jessewkun/gocommon/logger"
+)
+
+// LoggingConfig 日志配置
+type LoggingConfig struct {
+	LogContent       bool          // 是否记录提示词与回复内容，默认只记录模型、耗时与用量
+	MaxContentLength int           // 内容最大长度（按字符），超出部分截断，默认 2000
+	Redactor         *Redactor     // 记录内容前脱敏，nil 表示不脱敏
+	SlowThreshold    time.Duration // 耗时超过该值时以 warn 级别记录，0 表示不区分
+}
+
+// Logging 通过 logger 记录每次调用，失败时以 error 级别记录
+func Logging(cfg LoggingConfig) llm.Interceptor {
+	if cfg.MaxContentLength <= 0 {
+		cfg.MaxContentLength = 2000
+	}
+	return func(next llm.Handler) llm.Handler {
+		return func(ctx context.Context, call *llm.Call) (*llm.Result, error) {
+			start := time.Now()
+			result, err := next(ctx, call)
+			cost := time.Since(start)
+
+			fields := map[string]interface{}{
+				"provider":  call.Provider,
+				"model":     call.Model(),
+				"operation": string(call.Operation),
+				"cost_ms":   cost.Milliseconds(),
+			}
+			if cfg.LogContent {
+				fields["prompt"] = cfg.content(promptOf(call))
+			}
+			if err != nil {
+				fields["err"] = err.Error()
+				logger.ErrorWithField(ctx, TAG, "llm call failed", fields)
+				return result, err
+			}
+
+			if result.Chat != nil {
+				fields["usage"] = result.Chat.Usage
+				fields["finish_reason"] = result.Chat.FinishReason
+				if result.Chat.Backend != "" {
+					fields["backend"] = result.Chat.Backend
+				}
+				if len(result.Chat.ToolCalls) > 0 {
+					fields["tool_calls"] = len(result.Chat.ToolCalls)
+				}
+				if cfg.LogContent {
+					fields["response"] = cfg.content(result.Chat.Content)
+				}
+			}
+			if result.Embedding != nil {
+				fields["usage"] = result.Embedding.Usage
+				fields["vectors"] = len(result.Embedding.Data)
+			}
+			if result.Image != nil {
+				fields["usage"] = result.Image.Usage
+				fields["images"] = len(result.Image.Images)
+			}
+			if result.Transcription != nil {
+				fields["usage"] = result.Transcription.Usage
+				if cfg.LogContent {
+					fields["response"] = cfg.content(result.Transcription.Text)
+				}
+			}
+
+			if cfg.SlowThreshold > 0 && cost >= cfg.SlowThreshold {
+				logger.WarnWithField(ctx, TAG, "llm call slow", fields)
+			} else {
+				logger.InfoWithField(ctx, TAG, "llm call", fields)
+			}
+			return result, nil
+		}
+	}
+}
+
+func (cfg LoggingConfig) content(s string) string {
+	if cfg.Redactor != nil {
+		s = cfg.Redactor.Redact(s)
+	}
+	if r := []rune(s); len(r) > cfg.MaxContentLength {
+		return string(r[:cfg.MaxContentLength]) + "...(truncated)"
+	}
+	return s
+}
+
+// promptOf 将请求内容拼接为便于阅读的文本，每条消息一行
+func promptOf(call *llm.Call) string {
+	var b strings.Builder
+	if call.Chat != nil {
+		for _, m := range call.Chat.Messages {
+			b.WriteString(m.Role)
+			b.WriteString(": ")
+			b.WriteString(textOf(m.Content))
+			b.WriteString("\n")
+		}
+	}
+	if call.Embedding != nil {
+		b.WriteString(strings.Join(call.Embedding.Input, "\n"))
+	}
+	if call.Image != nil {
+		b.WriteString(call.Image.Prompt)
+	}
+	if call.Transcription != nil {
+		fmt.Fprintf(&b, "[audio %s, %d bytes] %s", call.Transcription.FileName, len(call.Transcription.File), call.Transcription.Prompt)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// textOf 提取消息中的文本，多模态消息的非文本部分以 [type] 占位
+func textOf(content interface{}) string {
+	parts, ok := content.([]interface{})
+	if !ok {
+		return llm.ContentString(content)
+	}
+	texts := make([]string, 0, len(parts))
+	for _, p := range parts {
+		part, _ := p.(map[string]interface{})
+		if text, ok := part["text"].(string); ok {
+			texts = append(texts, text)
+		} else if typ, ok := part["type"].(string); ok {
+			texts = append(texts, "["+typ+"]")
+		}
+	}
+	return strings.Join(texts, " ")
+}
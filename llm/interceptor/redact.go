@@ -0,0 +1,109 @@
+package interceptor
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/jessewkun/gocommon/llm"
+)
+
+// RedactRule 脱敏规则
+type RedactRule struct {
+	Name        string         // 规则名
+	Pattern     *regexp.Regexp // 匹配需要脱敏的内容
+	Replacement string         // 替换文本，支持 regexp 的 $1 等引用
+}
+
+// DefaultRedactRules 默认脱敏规则：身份证号、银行卡号、手机号、邮箱
+// 规则按顺序执行，身份证号需要在银行卡号之前匹配
+func DefaultRedactRules() []RedactRule {
+	return []RedactRule{
+		{Name: "id_card", Pattern: regexp.MustCompile(`\b\d{17}[\dXx]\b`), Replacement: "[ID_CARD]"},
+		{Name: "bank_card", Pattern: regexp.MustCompile(`\b\d{16,19}\b`), Replacement: "[BANK_CARD]"},
+		{Name: "phone", Pattern: regexp.MustCompile(`\b1[3-9]\d{9}\b`), Replacement: "[PHONE]"},
+		{Name: "email", Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), Replacement: "[EMAIL]"},
+	}
+}
+
+// Redactor 按规则对文本脱敏
+type Redactor struct {
+	rules []RedactRule
+}
+
+// NewRedactor 创建脱敏器，rules 为空时使用 DefaultRedactRules
+func NewRedactor(rules []RedactRule) *Redactor {
+	if len(rules) == 0 {
+		rules = DefaultRedactRules()
+	}
+	return &Redactor{rules: rules}
+}
+
+// Redact 对文本脱敏
+func (r *Redactor) Redact(s string) string {
+	for _, rule := range r.rules {
+		s = rule.Pattern.ReplaceAllString(s, rule.Replacement)
+	}
+	return s
+}
+
+// RedactMessages 返回脱敏后的消息副本，多模态消息只处理其中的 text 部分，不修改原消息
+func (r *Redactor) RedactMessages(msgs []llm.Message) []llm.Message {
+	out := make([]llm.Message, len(msgs))
+	for i, m := range msgs {
+		switch content := m.Content.(type) {
+		case string:
+			m.Content = r.Redact(content)
+		case []interface{}:
+			parts := make([]interface{}, len(content))
+			for j, p := range content {
+				part, ok := p.(map[string]interface{})
+				text, isText := part["text"].(string)
+				if !ok || part["type"] != "text" || !isText {
+					parts[j] = p
+					continue
+				}
+				cp := make(map[string]interface{}, len(part))
+				for k, v := range part {
+					cp[k] = v
+				}
+				cp["text"] = r.Redact(text)
+				parts[j] = cp
+			}
+			m.Content = parts
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// Redact 在请求发送给 Provider 前对消息、向量化输入与图片描述脱敏，不修改调用方传入的请求
+// 只处理请求，模型回复中的内容不做脱敏
+func Redact(r *Redactor) llm.Interceptor {
+	if r == nil {
+		r = NewRedactor(nil)
+	}
+	return func(next llm.Handler) llm.Handler {
+		return func(ctx context.Context, call *llm.Call) (*llm.Result, error) {
+			redacted := *call
+			if call.Chat != nil {
+				req := *call.Chat
+				req.Messages = r.RedactMessages(call.Chat.Messages)
+				redacted.Chat = &req
+			}
+			if call.Embedding != nil {
+				req := *call.Embedding
+				req.Input = make([]string, len(call.Embedding.Input))
+				for i, s := range call.Embedding.Input {
+					req.Input[i] = r.Redact(s)
+				}
+				redacted.Embedding = &req
+			}
+			if call.Image != nil {
+				req := *call.Image
+				req.Prompt = r.Redact(call.Image.Prompt)
+				redacted.Image = &req
+			}
+			return next(ctx, &redacted)
+		}
+	}
+}
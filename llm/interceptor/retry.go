@@ -0,0 +1,96 @@
+// Package interceptor 提供 llm.Client 常用的拦截器：日志、脱敏、限流重试与 trace 透传
+package interceptor
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/jessewkun/gocommon/logger"
+)
+
+const TAG = "LLM_INTERCEPTOR"
+
+// RetryConfig 重试配置
+type RetryConfig struct {
+	MaxRetries  int                  // 最大重试次数，默认 3，小于 0 表示不重试
+	BaseDelay   time.Duration        // 首次重试的等待时间，之后按指数增长，默认 1s
+	MaxDelay    time.Duration        // 单次等待时间上限，默认 30s
+	ShouldRetry func(err error) bool // 判断错误是否需要重试，默认 llm.IsRateLimited
+}
+
+// Retry 在限流等可重试错误时按指数退避重试
+// 服务端返回 Retry-After 时优先按其等待（不超过 MaxDelay）
+// 流式调用在已经向调用方推送过事件后不再重试，避免输出重复
+func Retry(cfg RetryConfig) llm.Interceptor {
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = time.Second
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = 30 * time.Second
+	}
+	if cfg.ShouldRetry == nil {
+		cfg.ShouldRetry = llm.IsRateLimited
+	}
+
+	return func(next llm.Handler) llm.Handler {
+		return func(ctx context.Context, call *llm.Call) (*llm.Result, error) {
+			attempt := *call
+			emitted := false
+			if call.Stream != nil {
+				attempt.Stream = func(event llm.StreamEvent) error {
+					if event.Type != llm.StreamEventError {
+						emitted = true
+					}
+					return call.Stream(event)
+				}
+			}
+
+			for i := 0; ; i++ {
+				result, err := next(ctx, &attempt)
+				if err == nil || i >= cfg.MaxRetries || emitted || !cfg.ShouldRetry(err) {
+					return result, err
+				}
+
+				delay := retryDelay(cfg, i, err)
+				logger.WarnWithField(ctx, TAG, "llm call failed, retrying", map[string]interface{}{
+					"provider":  call.Provider,
+					"model":     call.Model(),
+					"operation": string(call.Operation),
+					"attempt":   i + 1,
+					"delay_ms":  delay.Milliseconds(),
+					"err":       err.Error(),
+				})
+
+				timer := time.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, err
+				case <-timer.C:
+				}
+			}
+		}
+	}
+}
+
+// retryDelay 计算第 i 次重试前的等待时间
+func retryDelay(cfg RetryConfig, i int, err error) time.Duration {
+	if d := llm.RetryAfter(err); d > 0 {
+		if d > cfg.MaxDelay {
+			return cfg.MaxDelay
+		}
+		return d
+	}
+	d := cfg.BaseDelay << uint(i)
+	if d <= 0 || d > cfg.MaxDelay {
+		d = cfg.MaxDelay
+	}
+	// 在 [d/2, d) 之间随机，避免多个调用方同时重试
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
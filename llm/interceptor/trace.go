@@ -0,0 +1,25 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/jessewkun/gocommon/constant"
+	xhttp "github.com/jessewkun/gocommon/http"
+	"github.com/jessewkun/gocommon/llm"
+)
+
+// Trace 将 context 中的 trace_id（constant.CtxTraceID）作为请求头透传给 Provider
+// header 为空时使用 trace_id，与 middleware.Trace 读取的请求头一致
+func Trace(header string) llm.Interceptor {
+	if header == "" {
+		header = string(constant.CtxTraceID)
+	}
+	return func(next llm.Handler) llm.Handler {
+		return func(ctx context.Context, call *llm.Call) (*llm.Result, error) {
+			if traceID, ok := ctx.Value(constant.CtxTraceID).(string); ok && traceID != "" {
+				ctx = xhttp.WithHeaders(ctx, map[string]string{header: traceID})
+			}
+			return next(ctx, call)
+		}
+	}
+}
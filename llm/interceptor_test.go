@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Intercept(t *testing.T) {
+	var events []string
+	record := func(name string) Interceptor {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, call *Call) (*Result, error) {
+				events = append(events, name+".in:"+string(call.Operation))
+				result, err := next(ctx, call)
+				events = append(events, name+".out")
+				return result, err
+			}
+		}
+	}
+	// 改写请求的拦截器复制一份请求再传给 next
+	rewrite := func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (*Result, error) {
+			req := *call.Chat
+			req.Model = "rewritten"
+			cp := *call
+			cp.Chat = &req
+			return next(ctx, &cp)
+		}
+	}
+
+	chatter := &scriptedChatter{responses: []*ChatResponse{{Content: "a"}, {Content: "b"}}}
+	client := &Client{provider: chatter}
+	client.Use(&recordHook{name: "hook", events: &events})
+	client.Intercept(record("outer"), record("inner"), rewrite)
+
+	req := &ChatRequest{Model: "m"}
+	resp, err := client.Chat(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "a", resp.Content)
+	assert.Equal(t, []string{"outer.in:chat", "inner.in:chat", "hook.before:chat:rewritten", "hook.after", "inner.out", "outer.out"}, events)
+	assert.Equal(t, "rewritten", chatter.requests[0].Model)
+	assert.Equal(t, "m", req.Model)
+
+	// ChatStream 同样经过拦截器，回调被转换为 StreamHandler
+	events = nil
+	var chunks []string
+	_, err = client.ChatStream(context.Background(), req, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "outer.in:chat_stream", events[0])
+}
@@ -0,0 +1,195 @@
+// Package ollama an provider for ollama (local models)
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	xhttp "github.com/jessewkun/gocommon/http"
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProvider(serverURL string) *Provider {
+	cfg := Config{
+		APIURL:    serverURL,
+		KeepAlive: "10m",
+		Timeout:   5 * time.Second,
+	}
+	client := xhttp.NewClient(xhttp.Option{})
+	return NewProvider(client, cfg)
+}
+
+func TestOllamaProvider_Chat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/api/chat", r.URL.Path)
+
+		var reqBody OllamaChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		assert.False(t, reqBody.Stream)
+		assert.Equal(t, "json", reqBody.Format)
+		assert.Equal(t, "10m", reqBody.KeepAlive)
+		assert.Equal(t, 0.2, reqBody.Options["temperature"])
+		assert.Equal(t, float64(40), reqBody.Options["top_k"])
+		assert.Equal(t, float64(42), reqBody.Options["seed"])
+		assert.Equal(t, []interface{}{"\n\n"}, reqBody.Options["stop"])
+		assert.Equal(t, float64(128), reqBody.Options["num_predict"])
+
+		require.Len(t, reqBody.Messages, 2)
+		assert.Equal(t, "system", reqBody.Messages[0].Role)
+		assert.Equal(t, "Describe this", reqBody.Messages[1].Content)
+		assert.Equal(t, []string{"iVBORw0KGgo="}, reqBody.Messages[1].Images)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model":"llama3.2","message":{"role":"assistant","content":"{\"color\":\"red\"}"},` +
+			`"done":true,"done_reason":"stop","prompt_eval_count":26,"eval_count":9}`))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	topK, seed, maxTokens := 40, 42, 128
+	resp, err := provider.Chat(context.Background(), &llm.ChatRequest{
+		Model:          "llama3.2",
+		Temperature:    0.2,
+		TopK:           &topK,
+		Seed:           &seed,
+		MaxTokens:      &maxTokens,
+		Stop:           []string{"\n\n"},
+		ResponseFormat: "json_object",
+		Messages: []llm.Message{
+			{Role: "system", Content: "Reply in JSON."},
+			{Role: "user", Content: []interface{}{
+				map[string]interface{}{"type": "text", "text": "Describe this"},
+				map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "data:image/png;base64,iVBORw0KGgo="}},
+			}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `{"color":"red"}`, resp.Content)
+	assert.Equal(t, "stop", resp.FinishReason)
+	assert.Equal(t, llm.Usage{PromptTokens: 26, CompletionTokens: 9, TotalTokens: 35}, resp.Usage)
+}
+
+func TestOllamaProvider_Chat_WithToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody OllamaChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		require.Len(t, reqBody.Tools, 1)
+		require.Len(t, reqBody.Messages, 3)
+		assert.Equal(t, "get_weather", reqBody.Messages[1].ToolCalls[0].Function.Name)
+		assert.Equal(t, "Paris", reqBody.Messages[1].ToolCalls[0].Function.Arguments["city"])
+		assert.Equal(t, "tool", reqBody.Messages[2].Role)
+		assert.Equal(t, "get_weather", reqBody.Messages[2].ToolName)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model":"qwen3","message":{"role":"assistant","content":"",` +
+			`"tool_calls":[{"function":{"name":"get_weather","arguments":{"city":"Rome"}}}]},"done":true,"done_reason":"stop"}`))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	resp, err := provider.Chat(context.Background(), &llm.ChatRequest{
+		Model: "qwen3",
+		Messages: []llm.Message{
+			{Role: "user", Content: "Weather in Paris and Rome?"},
+			{Role: "assistant", ToolCalls: []llm.ToolCall{{ID: "call_0_get_weather", Type: "function", Function: llm.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}}}},
+			{Role: "tool", ToolCallID: "call_0_get_weather", Content: "sunny"},
+		},
+		Tools: []map[string]interface{}{{
+			"type":     "function",
+			"function": map[string]interface{}{"name": "get_weather", "parameters": map[string]interface{}{"type": "object"}},
+		}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "tool_calls", resp.FinishReason)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "call_0_get_weather", resp.ToolCalls[0].ID)
+	assert.JSONEq(t, `{"city":"Rome"}`, resp.ToolCalls[0].Function.Arguments)
+}
+
+func TestOllamaProvider_ChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody OllamaChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		assert.True(t, reqBody.Stream)
+		require.NotNil(t, reqBody.Think)
+		assert.True(t, *reqBody.Think)
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"","thinking":"Greeting."},"done":false}` + "\n"))
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"Hello"},"done":false}` + "\n"))
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":" World"},"done":false}` + "\n"))
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":10,"eval_count":5}` + "\n"))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	var chunks []string
+	var types []llm.StreamEventType
+	resp, err := provider.ChatStreamEvents(context.Background(), &llm.ChatRequest{
+		Model:     "qwen3",
+		Messages:  []llm.Message{{Role: "user", Content: "Say Hello World"}},
+		Reasoning: &llm.ReasoningConfig{Effort: "low"},
+	}, func(event llm.StreamEvent) error {
+		types = append(types, event.Type)
+		if event.Type == llm.StreamEventTextDelta {
+			chunks = append(chunks, event.Text)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hello", " World"}, chunks)
+	assert.Equal(t, []llm.StreamEventType{
+		llm.StreamEventReasoningDelta, llm.StreamEventTextDelta, llm.StreamEventTextDelta, llm.StreamEventUsage, llm.StreamEventDone,
+	}, types)
+	assert.Equal(t, "Hello World", resp.Content)
+	assert.Equal(t, "Greeting.", resp.Reasoning)
+	assert.Equal(t, "length", resp.FinishReason)
+	assert.Equal(t, 15, resp.Usage.TotalTokens)
+}
+
+func TestOllamaProvider_ChatStream_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte(`{"error":"model \"missing\" not found, try pulling it first"}` + "\n"))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	_, err := provider.ChatStream(context.Background(), &llm.ChatRequest{
+		Model:    "missing",
+		Messages: []llm.Message{{Role: "user", Content: "Hello"}},
+	}, func(chunk string) error { return nil })
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestOllamaProvider_CreateEmbeddings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/embed", r.URL.Path)
+		var reqBody OllamaEmbedRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		assert.Equal(t, []string{"hello", "world"}, reqBody.Input)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model":"nomic-embed-text","embeddings":[[0.1,0.2],[0.3,0.4]],"prompt_eval_count":4}`))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	resp, err := provider.CreateEmbeddings(context.Background(), &llm.EmbeddingRequest{
+		Model: "nomic-embed-text",
+		Input: []string{"hello", "world"},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 2)
+	assert.Equal(t, 1, resp.Data[1].Index)
+	assert.Equal(t, []float32{0.3, 0.4}, resp.Data[1].Vector)
+	assert.Equal(t, 4, resp.Usage.PromptTokens)
+}
@@ -0,0 +1,415 @@
+// Package ollama an provider for ollama (local models)
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	xhttp "github.com/jessewkun/gocommon/http"
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/jessewkun/gocommon/logger"
+)
+
+const logTag = "LLM_OLLAMA"
+
+// Provider implements llm.Chatter, llm.EventStreamer and llm.Embedder for Ollama
+type Provider struct {
+	client *xhttp.Client
+	cfg    Config
+}
+
+// NewProvider creates a new Ollama Provider
+func NewProvider(client *xhttp.Client, cfg Config) *Provider {
+	if cfg.APIURL == "" {
+		cfg.APIURL = defaultAPIURL
+	}
+	cfg.APIURL = strings.TrimRight(cfg.APIURL, "/")
+	return &Provider{client: client, cfg: cfg}
+}
+
+// Name implements llm.Provider
+func (p *Provider) Name() string {
+	return providerName
+}
+
+// --- Chatter Implementation ---
+
+// Chat implements llm.Chatter for non-streaming chat
+func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	ollamaReq, err := p.toOllamaChatRequest(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	bodyBytes, err := json.Marshal(ollamaReq)
+	if err != nil {
+		return nil, fmt.Errorf("ollama: marshalling chat request: %w", err)
+	}
+
+	resp, err := p.client.Post(ctx, xhttp.RequestPost{
+		URL:     p.cfg.APIURL + "/api/chat",
+		Payload: bodyBytes,
+		Headers: p.buildHeaders(),
+		Timeout: p.cfg.Timeout,
+	})
+	if err != nil {
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("ollama: chat api call failed: %w", err))
+	}
+	if resp == nil || len(resp.Body) == 0 {
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("ollama: empty response"))
+	}
+
+	var ollamaResp OllamaChatResponse
+	if err := json.Unmarshal(resp.Body, &ollamaResp); err != nil {
+		return nil, llm.NewError(providerName, resp.StatusCode, fmt.Errorf("ollama: unmarshalling chat response: %w (body: %s)", err, string(resp.Body)))
+	}
+	if ollamaResp.Error != "" {
+		return nil, llm.NewError(providerName, resp.StatusCode, fmt.Errorf("ollama: api error: %s", ollamaResp.Error))
+	}
+
+	toolCalls := make([]llm.ToolCall, 0, len(ollamaResp.Message.ToolCalls))
+	for i, tc := range ollamaResp.Message.ToolCalls {
+		toolCalls = append(toolCalls, toLLMToolCall(tc, i))
+	}
+	if len(toolCalls) == 0 {
+		toolCalls = nil
+	}
+	return &llm.ChatResponse{
+		Content:      ollamaResp.Message.Content,
+		Reasoning:    ollamaResp.Message.Thinking,
+		ToolCalls:    toolCalls,
+		FinishReason: toFinishReason(ollamaResp.DoneReason, len(toolCalls) > 0),
+		Usage:        toLLMUsage(&ollamaResp),
+		RawResponse:  resp.Body,
+	}, nil
+}
+
+// ChatStream implements llm.Chatter for streaming chat
+func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, callback func(chunk string) error) (*llm.ChatResponse, error) {
+	return p.ChatStreamEvents(ctx, req, llm.TextCallback(callback))
+}
+
+// ChatStreamEvents implements llm.EventStreamer for streaming chat with structured events.
+// Ollama streams newline delimited JSON objects instead of server-sent events.
+func (p *Provider) ChatStreamEvents(ctx context.Context, req *llm.ChatRequest, handler llm.StreamHandler) (*llm.ChatResponse, error) {
+	ollamaReq, err := p.toOllamaChatRequest(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	bodyBytes, err := json.Marshal(ollamaReq)
+	if err != nil {
+		return nil, fmt.Errorf("ollama: marshalling stream request: %w", err)
+	}
+
+	timeout := p.cfg.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Minute
+	}
+
+	acc := llm.NewStreamAccumulator(handler)
+	toolCallCount := 0
+
+	err = p.client.PostStream(ctx, xhttp.RequestPost{
+		URL:     p.cfg.APIURL + "/api/chat",
+		Payload: bodyBytes,
+		Headers: p.buildHeaders(),
+		Timeout: timeout,
+	}, func(line []byte) error {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			return nil
+		}
+
+		var chunk OllamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			logger.WarnWithField(ctx, logTag, "failed to parse ollama stream line", map[string]interface{}{
+				"line":  string(line),
+				"error": err.Error(),
+			})
+			return nil // Continue to next line
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("api error: %s", chunk.Error)
+		}
+
+		if err := acc.Reasoning(chunk.Message.Thinking); err != nil {
+			return err
+		}
+		if err := acc.Text(chunk.Message.Content); err != nil {
+			return err
+		}
+		// Tool calls are not split across chunks, each one arrives complete
+		for _, tc := range chunk.Message.ToolCalls {
+			call := toLLMToolCall(tc, toolCallCount)
+			if err := acc.ToolCall(llm.ToolCallDelta{
+				Index:     toolCallCount,
+				ID:        call.ID,
+				Type:      call.Type,
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			}); err != nil {
+				return err
+			}
+			toolCallCount++
+		}
+
+		if chunk.Done {
+			acc.Finish(toFinishReason(chunk.DoneReason, toolCallCount > 0))
+			return acc.Usage(toLLMUsage(&chunk))
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, acc.Fail(llm.NewError(providerName, 0, fmt.Errorf("ollama: stream failed: %w", err)))
+	}
+
+	return acc.Done()
+}
+
+// --- Embedder Implementation ---
+
+// CreateEmbeddings implements llm.Embedder
+func (p *Provider) CreateEmbeddings(ctx context.Context, req *llm.EmbeddingRequest) (*llm.EmbeddingResponse, error) {
+	if len(req.Input) == 0 {
+		return nil, fmt.Errorf("ollama: embedding input cannot be empty")
+	}
+	bodyBytes, err := json.Marshal(OllamaEmbedRequest{
+		Model:     req.Model,
+		Input:     req.Input,
+		KeepAlive: p.cfg.KeepAlive,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ollama: marshalling embedding request: %w", err)
+	}
+
+	resp, err := p.client.Post(ctx, xhttp.RequestPost{
+		URL:     p.cfg.APIURL + "/api/embed",
+		Payload: bodyBytes,
+		Headers: p.buildHeaders(),
+		Timeout: p.cfg.Timeout,
+	})
+	if err != nil {
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("ollama: embedding api call failed: %w", err))
+	}
+	if resp == nil || len(resp.Body) == 0 {
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("ollama: empty response"))
+	}
+
+	var ollamaResp OllamaEmbedResponse
+	if err := json.Unmarshal(resp.Body, &ollamaResp); err != nil {
+		return nil, llm.NewError(providerName, resp.StatusCode, fmt.Errorf("ollama: unmarshalling embedding response: %w (body: %s)", err, string(resp.Body)))
+	}
+	if ollamaResp.Error != "" {
+		return nil, llm.NewError(providerName, resp.StatusCode, fmt.Errorf("ollama: api error: %s", ollamaResp.Error))
+	}
+
+	data := make([]llm.Embedding, len(ollamaResp.Embeddings))
+	for i, vec := range ollamaResp.Embeddings {
+		data[i] = llm.Embedding{Index: i, Vector: vec, Object: "embedding"}
+	}
+	return &llm.EmbeddingResponse{
+		Data:  data,
+		Model: req.Model,
+		Usage: llm.Usage{
+			PromptTokens: ollamaResp.PromptEvalCount,
+			TotalTokens:  ollamaResp.PromptEvalCount,
+		},
+		RawResponse: resp.Body,
+	}, nil
+}
+
+// --- Helper Functions ---
+
+func (p *Provider) buildHeaders() map[string]string {
+	headers := map[string]string{"Content-Type": "application/json"}
+	if p.cfg.APIKey != "" {
+		headers["Authorization"] = "Bearer " + p.cfg.APIKey
+	}
+	return headers
+}
+
+func (p *Provider) toOllamaChatRequest(ctx context.Context, req *llm.ChatRequest, stream bool) (*OllamaChatRequest, error) {
+	ollamaReq := &OllamaChatRequest{
+		Model:     req.Model,
+		Stream:    stream,
+		Tools:     req.Tools,
+		Format:    toOllamaFormat(req),
+		Options:   toOllamaOptions(req),
+		KeepAlive: p.cfg.KeepAlive,
+	}
+	if req.Reasoning != nil {
+		think := !(req.Reasoning.Enabled != nil && !*req.Reasoning.Enabled) && req.Reasoning.Effort != "none"
+		ollamaReq.Think = &think
+	}
+
+	// Ollama identifies tool results by name, so remember the name of every tool call id
+	callNames := make(map[string]string)
+	messages := make([]OllamaMessage, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		content, images := splitContent(ctx, msg.Content)
+		om := OllamaMessage{
+			Role:    msg.Role,
+			Content: content,
+			Images:  images,
+		}
+		for _, tc := range msg.ToolCalls {
+			callNames[tc.ID] = tc.Function.Name
+			var args map[string]interface{}
+			if tc.Function.Arguments != "" {
+				if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+					return nil, fmt.Errorf("ollama: invalid arguments for tool call %s: %w", tc.ID, err)
+				}
+			}
+			om.ToolCalls = append(om.ToolCalls, OllamaToolCall{Function: OllamaFunctionCall{
+				Name:      tc.Function.Name,
+				Arguments: args,
+			}})
+		}
+		if msg.Role == "tool" {
+			om.ToolName = msg.Name
+			if om.ToolName == "" {
+				om.ToolName = callNames[msg.ToolCallID]
+			}
+		}
+		messages = append(messages, om)
+	}
+	ollamaReq.Messages = messages
+	return ollamaReq, nil
+}
+
+// toOllamaOptions maps the sampling parameters of ChatRequest to Ollama model options
+func toOllamaOptions(req *llm.ChatRequest) map[string]interface{} {
+	options := map[string]interface{}{
+		"temperature": req.Temperature,
+	}
+	if req.TopP != nil {
+		options["top_p"] = *req.TopP
+	}
+	if req.TopK != nil {
+		options["top_k"] = *req.TopK
+	}
+	if req.Seed != nil {
+		options["seed"] = *req.Seed
+	}
+	if len(req.Stop) > 0 {
+		options["stop"] = req.Stop
+	}
+	if req.MaxTokens != nil {
+		options["num_predict"] = *req.MaxTokens
+	}
+	if req.PresencePenalty != nil {
+		options["presence_penalty"] = *req.PresencePenalty
+	}
+	if req.FrequencyPenalty != nil {
+		options["frequency_penalty"] = *req.FrequencyPenalty
+	}
+	return options
+}
+
+// toOllamaFormat maps JSONSchema and ResponseFormat to the Ollama format parameter.
+// A JSON schema is passed as-is for structured output, "json_object" and "json" enable JSON mode.
+func toOllamaFormat(req *llm.ChatRequest) interface{} {
+	if req.JSONSchema != nil {
+		return req.JSONSchema.Schema
+	}
+	switch req.ResponseFormat {
+	case "json_object", "json":
+		return "json"
+	default:
+		return nil
+	}
+}
+
+// splitContent converts llm.Message.Content to Ollama's text content and base64 images
+// Supports:
+// - string: simple text content
+// - []interface{}: multimodal content array with format:
+//   - {"type": "text", "text": "..."}
+//   - {"type": "image_url", "image_url": {"url": "data:<mime>;base64,<data>"}}
+//
+// Ollama only accepts inline images, remote image URLs are skipped.
+func splitContent(ctx context.Context, content interface{}) (string, []string) {
+	if s, ok := content.(string); ok {
+		return s, nil
+	}
+	arr, ok := content.([]interface{})
+	if !ok {
+		if content == nil {
+			return "", nil
+		}
+		return llm.ContentString(content), nil
+	}
+
+	var texts []string
+	var images []string
+	for _, item := range arr {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		itemType, _ := m["type"].(string)
+		switch itemType {
+		case "text":
+			if text, ok := m["text"].(string); ok {
+				texts = append(texts, text)
+			}
+		case "image_url":
+			imageURL, ok := m["image_url"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			url, _ := imageURL["url"].(string)
+			idx := strings.Index(url, ";base64,")
+			if !strings.HasPrefix(url, "data:") || idx == -1 {
+				logger.Warn(ctx, logTag, "Ollama only supports base64 data URI images, remote image url is ignored.")
+				continue
+			}
+			images = append(images, url[idx+len(";base64,"):])
+		}
+	}
+	return strings.Join(texts, "\n"), images
+}
+
+// toLLMToolCall converts an Ollama tool call into llm.ToolCall.
+// Ollama does not return call ids, so a stable id is generated from the position.
+func toLLMToolCall(tc OllamaToolCall, index int) llm.ToolCall {
+	args := "{}"
+	if len(tc.Function.Arguments) > 0 {
+		if b, err := json.Marshal(tc.Function.Arguments); err == nil {
+			args = string(b)
+		}
+	}
+	return llm.ToolCall{
+		ID:   fmt.Sprintf("call_%d_%s", index, tc.Function.Name),
+		Type: "function",
+		Function: llm.FunctionCall{
+			Name:      tc.Function.Name,
+			Arguments: args,
+		},
+	}
+}
+
+// toFinishReason returns "tool_calls" when the model called tools, otherwise Ollama's done_reason ("stop", "length", ...)
+func toFinishReason(doneReason string, hasToolCalls bool) string {
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	return doneReason
+}
+
+func toLLMUsage(resp *OllamaChatResponse) llm.Usage {
+	return llm.Usage{
+		PromptTokens:     resp.PromptEvalCount,
+		CompletionTokens: resp.EvalCount,
+		TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+	}
+}
+
+// Ensure *Provider implements the interfaces
+var _ llm.Chatter = (*Provider)(nil)
+var _ llm.EventStreamer = (*Provider)(nil)
+var _ llm.Embedder = (*Provider)(nil)
@@ -0,0 +1,58 @@
+// Package ollama an provider for ollama (local models)
+package ollama
+
+import (
+	"fmt"
+	"time"
+
+	xhttp "github.com/jessewkun/gocommon/http"
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/spf13/cast"
+)
+
+const providerName = "ollama"
+
+const defaultAPIURL = "http://localhost:11434"
+
+func init() {
+	llm.Register(providerName, func(config interface{}) (llm.Provider, error) {
+		cfg, err := parseConfig(config)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ollama config: %w", err)
+		}
+		// The client timeout will be overridden by the per-request timeout from cfg.Timeout
+		client := xhttp.NewClient(xhttp.Option{})
+		return NewProvider(client, cfg), nil
+	})
+}
+
+// parseConfig supports Config or map for flexibility, nil uses the local default server
+func parseConfig(config interface{}) (Config, error) {
+	if config == nil {
+		return Config{APIURL: defaultAPIURL}, nil
+	}
+	if c, ok := config.(Config); ok {
+		if c.APIURL == "" {
+			c.APIURL = defaultAPIURL
+		}
+		return c, nil
+	}
+	m, ok := config.(map[string]interface{})
+	if !ok {
+		return Config{}, fmt.Errorf("config must be of type ollama.Config or map[string]interface{}")
+	}
+	cfg := Config{
+		APIURL:    cast.ToString(m["api_url"]),
+		APIKey:    cast.ToString(m["api_key"]),
+		KeepAlive: cast.ToString(m["keep_alive"]),
+	}
+	if cfg.APIURL == "" {
+		cfg.APIURL = defaultAPIURL
+	}
+	if v, has := m["timeout"]; has {
+		if d, ok := v.(time.Duration); ok {
+			cfg.Timeout = d
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,75 @@
+// Package ollama an provider for ollama (local models)
+package ollama
+
+import (
+	"time"
+)
+
+// Config for the Ollama provider
+type Config struct {
+	APIURL    string        `mapstructure:"api_url"`    // Ollama server address, defaults to "http://localhost:11434"
+	APIKey    string        `mapstructure:"api_key"`    // Optional, sent as a Bearer token when Ollama runs behind an authenticating proxy
+	KeepAlive string        `mapstructure:"keep_alive"` // Optional, how long the model stays loaded after the request, e.g. "5m", "-1"
+	Timeout   time.Duration `mapstructure:"timeout"`
+}
+
+// OllamaToolCall is a tool call in an Ollama message, arguments are a JSON object instead of a string
+type OllamaToolCall struct {
+	Function OllamaFunctionCall `json:"function"`
+}
+
+// OllamaFunctionCall is the function of a tool call
+type OllamaFunctionCall struct {
+	Index     int                    `json:"index,omitempty"`
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+// OllamaMessage is a single chat message
+type OllamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    []string         `json:"images,omitempty"` // base64 encoded images without the data URI prefix
+	ToolCalls []OllamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"` // name of the tool that produced a "tool" message
+}
+
+// OllamaChatRequest is the request body of POST /api/chat
+type OllamaChatRequest struct {
+	Model     string                   `json:"model"`
+	Messages  []OllamaMessage          `json:"messages"`
+	Stream    bool                     `json:"stream"`
+	Format    interface{}              `json:"format,omitempty"` // "json" or a JSON schema
+	Options   map[string]interface{}   `json:"options,omitempty"`
+	Tools     []map[string]interface{} `json:"tools,omitempty"` // OpenAI compatible tool definitions
+	Think     *bool                    `json:"think,omitempty"`
+	KeepAlive string                   `json:"keep_alive,omitempty"`
+}
+
+// OllamaChatResponse is the response of POST /api/chat, one per line when streaming
+type OllamaChatResponse struct {
+	Model           string        `json:"model"`
+	CreatedAt       string        `json:"created_at"`
+	Message         OllamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason,omitempty"`
+	PromptEvalCount int           `json:"prompt_eval_count,omitempty"`
+	EvalCount       int           `json:"eval_count,omitempty"`
+	Error           string        `json:"error,omitempty"`
+}
+
+// OllamaEmbedRequest is the request body of POST /api/embed
+type OllamaEmbedRequest struct {
+	Model     string   `json:"model"`
+	Input     []string `json:"input"`
+	KeepAlive string   `json:"keep_alive,omitempty"`
+}
+
+// OllamaEmbedResponse is the response of POST /api/embed
+type OllamaEmbedResponse struct {
+	Model           string      `json:"model"`
+	Embeddings      [][]float32 `json:"embeddings"`
+	PromptEvalCount int         `json:"prompt_eval_count,omitempty"`
+	Error           string      `json:"error,omitempty"`
+}
@@ -137,3 +137,211 @@ func TestOpenAIProvider_CreateEmbeddings(t *testing.T) {
 	require.Len(t, resp.Data, 1)
 	assert.Equal(t, []float32{0.1, 0.2, 0.3}, resp.Data[0].Vector)
 }
+
+func TestOpenAIProvider_Chat_WithToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody map[string]interface{}
+		err := json.NewDecoder(r.Body).Decode(&reqBody)
+		require.NoError(t, err)
+
+		// Assert tool definitions and tool result messages are forwarded
+		require.Len(t, reqBody["tools"], 1)
+		msgs := reqBody["messages"].([]interface{})
+		require.Len(t, msgs, 3)
+		assistant := msgs[1].(map[string]interface{})
+		require.Len(t, assistant["tool_calls"], 1)
+		tool := msgs[2].(map[string]interface{})
+		assert.Equal(t, "tool", tool["role"])
+		assert.Equal(t, "call_1", tool["tool_call_id"])
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"index":0,"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_2","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},"finish_reason":"tool_calls"}]}`))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	req := &llm.ChatRequest{
+		Model: "gpt-4o",
+		Messages: []llm.Message{
+			{Role: "user", Content: "What's the weather in Beijing and Paris?"},
+			{Role: "assistant", ToolCalls: []llm.ToolCall{{ID: "call_1", Type: "function", Function: llm.FunctionCall{Name: "get_weather", Arguments: `{"city":"Beijing"}`}}}},
+			{Role: "tool", ToolCallID: "call_1", Content: "sunny"},
+		},
+		Tools: []map[string]interface{}{
+			{"type": "function", "function": map[string]interface{}{"name": "get_weather", "parameters": map[string]interface{}{"type": "object"}}},
+		},
+	}
+
+	resp, err := provider.Chat(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "tool_calls", resp.FinishReason)
+	assert.Empty(t, resp.Content)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "call_2", resp.ToolCalls[0].ID)
+	assert.Equal(t, "get_weather", resp.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"city":"Paris"}`, resp.ToolCalls[0].Function.Arguments)
+}
+
+func TestOpenAIProvider_ChatStream_WithToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"call_1\",\"type\":\"function\",\"function\":{\"name\":\"get_weather\",\"arguments\":\"\"}}]}}]}\n\n"))
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"{\\\"city\\\":\"}}]}}]}\n\n"))
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"\\\"Paris\\\"}\"}}]}}]}\n\n"))
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"tool_calls\"}]}\n\n"))
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	req := &llm.ChatRequest{
+		Model:    "gpt-4o",
+		Messages: []llm.Message{{Role: "user", Content: "What's the weather in Paris?"}},
+	}
+
+	fullResp, err := provider.ChatStream(context.Background(), req, func(chunk string) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, "tool_calls", fullResp.FinishReason)
+	require.Len(t, fullResp.ToolCalls, 1)
+	assert.Equal(t, "call_1", fullResp.ToolCalls[0].ID)
+	assert.Equal(t, "get_weather", fullResp.ToolCalls[0].Function.Name)
+	assert.Equal(t, `{"city":"Paris"}`, fullResp.ToolCalls[0].Function.Arguments)
+}
+
+func TestOpenAIProvider_ChatStreamEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody map[string]interface{}
+		err := json.NewDecoder(r.Body).Decode(&reqBody)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"include_usage": true}, reqBody["stream_options"])
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"reasoning_content\":\"Thinking\"}}]}\n\n"))
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hi\"}}]}\n\n"))
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n"))
+		_, _ = w.Write([]byte("data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":2,\"total_tokens\":5}}\n\n"))
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	req := &llm.ChatRequest{
+		Model:    "gpt-4o",
+		Messages: []llm.Message{{Role: "user", Content: "Hi"}},
+	}
+
+	var types []llm.StreamEventType
+	fullResp, err := provider.ChatStreamEvents(context.Background(), req, func(event llm.StreamEvent) error {
+		types = append(types, event.Type)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []llm.StreamEventType{llm.StreamEventReasoningDelta, llm.StreamEventTextDelta, llm.StreamEventUsage, llm.StreamEventDone}, types)
+	assert.Equal(t, "Hi", fullResp.Content)
+	assert.Equal(t, "Thinking", fullResp.Reasoning)
+	assert.Equal(t, 5, fullResp.Usage.TotalTokens)
+}
+
+func TestOpenAIProvider_Chat_WithJSONSchema(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody map[string]interface{}
+		err := json.NewDecoder(r.Body).Decode(&reqBody)
+		require.NoError(t, err)
+
+		format := reqBody["response_format"].(map[string]interface{})
+		assert.Equal(t, "json_schema", format["type"])
+		jsonSchema := format["json_schema"].(map[string]interface{})
+		assert.Equal(t, "city", jsonSchema["name"])
+		assert.Equal(t, "object", jsonSchema["schema"].(map[string]interface{})["type"])
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"index":0,"message":{"role":"assistant","content":"{\"name\":\"Paris\"}"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	resp, err := provider.Chat(context.Background(), &llm.ChatRequest{
+		Model:          "gpt-4o",
+		Messages:       []llm.Message{{Role: "user", Content: "Capital of France?"}},
+		ResponseFormat: "json_object",
+		JSONSchema: &llm.JSONSchemaFormat{
+			Name: "city",
+			Schema: llm.JSONSchemaOf(struct {
+				Name string `json:"name"`
+			}{}),
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"Paris"}`, resp.Content)
+}
+
+func TestOpenAIProvider_GenerateImages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/images/generations", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]interface{}{"model": "gpt-image-1", "prompt": "a cat", "n": 2.0, "size": "1024x1024"}, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"created":1713833628,"data":[{"b64_json":"aaa"},{"b64_json":"bbb","revised_prompt":"a cute cat"}],"usage":{"input_tokens":5,"output_tokens":100,"total_tokens":105}}`))
+	}))
+	defer server.Close()
+
+	resp, err := newTestProvider(server.URL).GenerateImages(context.Background(), &llm.ImageRequest{Model: "gpt-image-1", Prompt: "a cat", N: 2, Size: "1024x1024"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1713833628), resp.Created)
+	assert.Equal(t, []llm.Image{{B64JSON: "aaa"}, {B64JSON: "bbb", RevisedPrompt: "a cute cat"}}, resp.Images)
+	assert.Equal(t, llm.Usage{PromptTokens: 5, CompletionTokens: 100, TotalTokens: 105}, resp.Usage)
+}
+
+func TestOpenAIProvider_Transcribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/audio/transcriptions", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		file, header, err := r.FormFile("file")
+		require.NoError(t, err)
+		defer file.Close()
+		assert.Equal(t, "hello.wav", header.Filename)
+		assert.Equal(t, "whisper-1", r.FormValue("model"))
+
+		switch r.FormValue("response_format") {
+		case "srt":
+			_, _ = w.Write([]byte("1\n00:00:00,000 --> 00:00:01,000\n你好\n"))
+		case "verbose_json":
+			assert.Equal(t, "zh", r.FormValue("language"))
+			assert.Equal(t, "0.2", r.FormValue("temperature"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"text":"你好","language":"chinese","duration":1.5,"segments":[{"id":0,"start":0,"end":1.5,"text":"你好"}]}`))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"Invalid file format.","type":"invalid_request_error"}}`))
+		}
+	}))
+	defer server.Close()
+	provider := newTestProvider(server.URL)
+	temperature := 0.2
+
+	resp, err := provider.Transcribe(context.Background(), &llm.TranscriptionRequest{
+		Model: "whisper-1", File: []byte("RIFF"), FileName: "hello.wav", Language: "zh", ResponseFormat: "verbose_json", Temperature: &temperature,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "你好", resp.Text)
+	assert.Equal(t, 1.5, resp.Duration)
+	assert.Equal(t, []llm.TranscriptionSegment{{ID: 0, Start: 0, End: 1.5, Text: "你好"}}, resp.Segments)
+
+	resp, err = provider.Transcribe(context.Background(), &llm.TranscriptionRequest{Model: "whisper-1", File: []byte("RIFF"), FileName: "hello.wav", ResponseFormat: "srt"})
+	require.NoError(t, err)
+	assert.Contains(t, resp.Text, "00:00:00,000 --> 00:00:01,000")
+
+	_, err = provider.Transcribe(context.Background(), &llm.TranscriptionRequest{Model: "whisper-1", File: []byte("RIFF"), FileName: "hello.wav"})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode())
+	assert.Equal(t, "Invalid file format.", apiErr.Message)
+
+	_, err = provider.Transcribe(context.Background(), &llm.TranscriptionRequest{Model: "whisper-1"})
+	assert.EqualError(t, err, "openai: transcription file cannot be empty")
+}
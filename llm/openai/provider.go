@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,7 +17,7 @@ import (
 
 const logTag = "LLM_OPENAI"
 
-// Provider implements llm.Chatter and llm.Embedder for OpenAI
+// Provider implements llm.Chatter, llm.Embedder, llm.ImageGenerator and llm.Transcriber for OpenAI
 type Provider struct {
 	client *xhttp.Client
 	cfg    Config
@@ -55,15 +56,15 @@ func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRes
 		Timeout: p.cfg.Timeout,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("openai: chat api call failed: %w", err)
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("openai: chat api call failed: %w", err))
 	}
 
 	var openAIResp OpenAIResponse
 	if err := json.Unmarshal(resp.Body, &openAIResp); err != nil {
-		return nil, fmt.Errorf("openai: unmarshalling chat response: %w (body: %s)", err, string(resp.Body))
+		return nil, llm.NewError(providerName, resp.StatusCode, fmt.Errorf("openai: unmarshalling chat response: %w (body: %s)", err, string(resp.Body)))
 	}
 	if openAIResp.Error != nil {
-		return nil, fmt.Errorf("openai: api error: %s", openAIResp.Error.Message)
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("openai: api error: %w", openAIResp.Error.withResponse(resp)))
 	}
 	if len(openAIResp.Choices) == 0 {
 		return nil, fmt.Errorf("openai: no choices returned")
@@ -75,6 +76,7 @@ func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRes
 	}
 	return &llm.ChatResponse{
 		Content:      strings.TrimSpace(contentStr),
+		ToolCalls:    openAIResp.Choices[0].Message.ToolCalls,
 		FinishReason: openAIResp.Choices[0].FinishReason,
 		Usage:        openAIResp.Usage,
 		RawResponse:  resp.Body,
@@ -83,10 +85,17 @@ func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRes
 
 // ChatStream implements llm.Chatter for streaming chat
 func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, callback func(chunk string) error) (*llm.ChatResponse, error) {
+	return p.ChatStreamEvents(ctx, req, llm.TextCallback(callback))
+}
+
+// ChatStreamEvents implements llm.EventStreamer for streaming chat with structured events
+func (p *Provider) ChatStreamEvents(ctx context.Context, req *llm.ChatRequest, handler llm.StreamHandler) (*llm.ChatResponse, error) {
 	body, err := p.buildChatBody(req, true)
 	if err != nil {
 		return nil, fmt.Errorf("openai: building stream request: %w", err)
 	}
+	// Ask for a final chunk carrying the token usage of the whole stream
+	body["stream_options"] = map[string]interface{}{"include_usage": true}
 	bodyBytes, err := json.Marshal(body)
 	if err != nil {
 		return nil, fmt.Errorf("openai: marshalling stream request: %w", err)
@@ -96,8 +105,7 @@ func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, callbac
 	headers := p.buildAuthHeaders()
 	headers["Accept"] = "text/event-stream"
 
-	var fullContent strings.Builder
-	var finalResponse llm.ChatResponse
+	acc := llm.NewStreamAccumulator(handler)
 
 	timeout := p.cfg.Timeout
 	if timeout == 0 {
@@ -124,30 +132,42 @@ func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, callbac
 			return nil
 		}
 		if streamResp.Error != nil {
-			return fmt.Errorf("openai: stream error: %s", streamResp.Error.Message)
+			return fmt.Errorf("openai: stream error: %w", streamResp.Error)
 		}
 		if len(streamResp.Choices) > 0 {
 			choice := streamResp.Choices[0]
-			if choice.Delta.Content != "" {
-				chunk := choice.Delta.Content
-				fullContent.WriteString(chunk)
-				if err := callback(chunk); err != nil {
-					return fmt.Errorf("callback error: %w", err)
+			if err := acc.Reasoning(choice.Delta.ReasoningContent); err != nil {
+				return err
+			}
+			if err := acc.Text(choice.Delta.Content); err != nil {
+				return err
+			}
+			for _, tc := range choice.Delta.ToolCalls {
+				if err := acc.ToolCall(llm.ToolCallDelta{
+					Index:     tc.Index,
+					ID:        tc.ID,
+					Type:      tc.Type,
+					Name:      tc.Function.Name,
+					Arguments: tc.Function.Arguments,
+				}); err != nil {
+					return err
 				}
 			}
-			if choice.FinishReason != "" {
-				finalResponse.FinishReason = choice.FinishReason
+			acc.Finish(choice.FinishReason)
+		}
+		if streamResp.Usage != nil {
+			if err := acc.Usage(*streamResp.Usage); err != nil {
+				return err
 			}
 		}
 		return nil
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("openai: stream failed: %w", err)
+		return nil, acc.Fail(llm.NewError(providerName, 0, fmt.Errorf("openai: stream failed: %w", err)))
 	}
 
-	finalResponse.Content = fullContent.String()
-	return &finalResponse, nil
+	return acc.Done()
 }
 
 // --- Embedder Implementation ---
@@ -174,15 +194,15 @@ func (p *Provider) CreateEmbeddings(ctx context.Context, req *llm.EmbeddingReque
 		Timeout: p.cfg.Timeout,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("openai: embedding api call failed: %w", err)
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("openai: embedding api call failed: %w", err))
 	}
 
 	var openAIResp OpenAIEmbeddingResponse
 	if err := json.Unmarshal(resp.Body, &openAIResp); err != nil {
-		return nil, fmt.Errorf("openai: unmarshalling embedding response: %w (body: %s)", err, string(resp.Body))
+		return nil, llm.NewError(providerName, resp.StatusCode, fmt.Errorf("openai: unmarshalling embedding response: %w (body: %s)", err, string(resp.Body)))
 	}
 	if openAIResp.Error != nil {
-		return nil, fmt.Errorf("openai: embedding api error: %s", openAIResp.Error.Message)
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("openai: embedding api error: %w", openAIResp.Error.withResponse(resp)))
 	}
 
 	embeddings := make([]llm.Embedding, len(openAIResp.Data))
@@ -202,12 +222,135 @@ func (p *Provider) CreateEmbeddings(ctx context.Context, req *llm.EmbeddingReque
 	}, nil
 }
 
+// --- ImageGenerator Implementation ---
+
+// GenerateImages implements llm.ImageGenerator
+func (p *Provider) GenerateImages(ctx context.Context, req *llm.ImageRequest) (*llm.ImageResponse, error) {
+	body := map[string]interface{}{
+		"model":  req.Model,
+		"prompt": req.Prompt,
+	}
+	if req.N > 0 {
+		body["n"] = req.N
+	}
+	if req.Size != "" {
+		body["size"] = req.Size
+	}
+	if req.Quality != "" {
+		body["quality"] = req.Quality
+	}
+	if req.Style != "" {
+		body["style"] = req.Style
+	}
+	if req.ResponseFormat != "" {
+		body["response_format"] = req.ResponseFormat
+	}
+	if req.User != "" {
+		body["user"] = req.User
+	}
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("openai: marshalling image request: %w", err)
+	}
+
+	resp, err := p.client.Post(ctx, xhttp.RequestPost{
+		URL:     p.cfg.APIURL + "/images/generations",
+		Payload: bodyBytes,
+		Headers: p.buildAuthHeaders(),
+		Timeout: p.cfg.Timeout,
+	})
+	if err != nil {
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("openai: image api call failed: %w", err))
+	}
+
+	var openAIResp OpenAIImageResponse
+	if err := json.Unmarshal(resp.Body, &openAIResp); err != nil {
+		return nil, llm.NewError(providerName, resp.StatusCode, fmt.Errorf("openai: unmarshalling image response: %w (body: %s)", err, string(resp.Body)))
+	}
+	if openAIResp.Error != nil {
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("openai: image api error: %w", openAIResp.Error.withResponse(resp)))
+	}
+
+	return &llm.ImageResponse{
+		Created:     openAIResp.Created,
+		Images:      openAIResp.Data,
+		Usage:       openAIResp.Usage.toUsage(),
+		RawResponse: resp.Body,
+	}, nil
+}
+
+// --- Transcriber Implementation ---
+
+// Transcribe implements llm.Transcriber, the audio file is sent as multipart/form-data
+func (p *Provider) Transcribe(ctx context.Context, req *llm.TranscriptionRequest) (*llm.TranscriptionResponse, error) {
+	if len(req.File) == 0 {
+		return nil, fmt.Errorf("openai: transcription file cannot be empty")
+	}
+	fileName := req.FileName
+	if fileName == "" {
+		fileName = "audio.mp3"
+	}
+	format := req.ResponseFormat
+	if format == "" {
+		format = "json"
+	}
+	data := map[string]string{
+		"model":           req.Model,
+		"response_format": format,
+	}
+	if req.Language != "" {
+		data["language"] = req.Language
+	}
+	if req.Prompt != "" {
+		data["prompt"] = req.Prompt
+	}
+	if req.Temperature != nil {
+		data["temperature"] = strconv.FormatFloat(*req.Temperature, 'f', -1, 64)
+	}
+
+	// Content-Type is set by the multipart writer
+	resp, err := p.client.Upload(ctx, xhttp.RequestUpload{
+		URL:       p.cfg.APIURL + "/audio/transcriptions",
+		FileBytes: req.File,
+		Param:     "file",
+		FileName:  fileName,
+		Data:      data,
+		Headers:   map[string]string{"Authorization": "Bearer " + p.cfg.APIKey},
+		Timeout:   p.cfg.Timeout,
+	})
+	if err != nil {
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("openai: transcription api call failed: %w", err))
+	}
+
+	// text, srt and vtt formats return the content as is, errors are always JSON
+	if format != "json" && format != "verbose_json" && resp.StatusCode < 400 {
+		return &llm.TranscriptionResponse{Text: string(resp.Body), RawResponse: resp.Body}, nil
+	}
+
+	var openAIResp OpenAITranscriptionResponse
+	if err := json.Unmarshal(resp.Body, &openAIResp); err != nil {
+		return nil, llm.NewError(providerName, resp.StatusCode, fmt.Errorf("openai: unmarshalling transcription response: %w (body: %s)", err, string(resp.Body)))
+	}
+	if openAIResp.Error != nil {
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("openai: transcription api error: %w", openAIResp.Error.withResponse(resp)))
+	}
+
+	return &llm.TranscriptionResponse{
+		Text:        openAIResp.Text,
+		Language:    openAIResp.Language,
+		Duration:    openAIResp.Duration,
+		Segments:    openAIResp.Segments,
+		Usage:       openAIResp.Usage.toUsage(),
+		RawResponse: resp.Body,
+	}, nil
+}
+
 // --- Helper Functions ---
 
 func (p *Provider) buildChatBody(req *llm.ChatRequest, stream bool) (map[string]interface{}, error) {
 	msgs := make([]map[string]interface{}, len(req.Messages))
 	for i, m := range req.Messages {
-		msgs[i] = map[string]interface{}{"role": m.Role, "content": m.Content}
+		msgs[i] = buildMessage(m)
 	}
 	body := map[string]interface{}{
 		"model":       req.Model,
@@ -248,12 +391,29 @@ func (p *Provider) buildChatBody(req *llm.ChatRequest, stream bool) (map[string]
 	if req.ToolChoice != nil {
 		body["tool_choice"] = req.ToolChoice
 	}
-	if req.ResponseFormat != "" {
+	if req.JSONSchema != nil {
+		body["response_format"] = map[string]interface{}{"type": "json_schema", "json_schema": req.JSONSchema}
+	} else if req.ResponseFormat != "" {
 		body["response_format"] = map[string]interface{}{"type": req.ResponseFormat}
 	}
 	return body, nil
 }
 
+// buildMessage converts llm.Message to the OpenAI message format, including tool calls and tool results
+func buildMessage(m llm.Message) map[string]interface{} {
+	msg := map[string]interface{}{"role": m.Role, "content": m.Content}
+	if m.Name != "" {
+		msg["name"] = m.Name
+	}
+	if len(m.ToolCalls) > 0 {
+		msg["tool_calls"] = m.ToolCalls
+	}
+	if m.ToolCallID != "" {
+		msg["tool_call_id"] = m.ToolCallID
+	}
+	return msg
+}
+
 func (p *Provider) buildAuthHeaders() map[string]string {
 	return map[string]string{
 		"Content-Type":  "application/json",
@@ -263,4 +423,7 @@ func (p *Provider) buildAuthHeaders() map[string]string {
 
 // Ensure *Provider implements the interfaces
 var _ llm.Chatter = (*Provider)(nil)
+var _ llm.EventStreamer = (*Provider)(nil)
 var _ llm.Embedder = (*Provider)(nil)
+var _ llm.ImageGenerator = (*Provider)(nil)
+var _ llm.Transcriber = (*Provider)(nil)
@@ -2,8 +2,10 @@
 package openai
 
 import (
+	"net/http"
 	"time"
 
+	xhttp "github.com/jessewkun/gocommon/http"
 	"github.com/jessewkun/gocommon/llm"
 )
 
@@ -38,11 +40,24 @@ type OpenAIResponse struct {
 type OpenAIStreamChoice struct {
 	Index int `json:"index"`
 	Delta struct {
-		Content string `json:"content"`
+		Content          string                `json:"content"`
+		ReasoningContent string                `json:"reasoning_content"` // returned by some OpenAI compatible reasoning models
+		ToolCalls        []OpenAIToolCallDelta `json:"tool_calls"`
 	} `json:"delta"`
 	FinishReason string `json:"finish_reason"`
 }
 
+// OpenAIToolCallDelta is a fragment of a tool call in a streaming response
+type OpenAIToolCallDelta struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
+}
+
 // OpenAIStreamResponse is the structure of a single data chunk in a streaming response
 type OpenAIStreamResponse struct {
 	ID      string               `json:"id"`
@@ -50,6 +65,7 @@ type OpenAIStreamResponse struct {
 	Created int64                `json:"created"`
 	Model   string               `json:"model"`
 	Choices []OpenAIStreamChoice `json:"choices"`
+	Usage   *llm.Usage           `json:"usage,omitempty"` // only present in the final chunk when stream_options.include_usage is set
 	Error   *APIError            `json:"error,omitempty"`
 }
 
@@ -59,6 +75,35 @@ type APIError struct {
 	Type    string `json:"type"`
 	Param   string `json:"param"`
 	Code    string `json:"code"`
+
+	status     int           // HTTP status code of the response carrying the error
+	retryAfter time.Duration // parsed from the Retry-After response header
+}
+
+// Error implements error
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// StatusCode returns the HTTP status code of the response, 0 if unknown
+func (e *APIError) StatusCode() int {
+	return e.status
+}
+
+// RetryAfter returns the delay suggested by the Retry-After response header, 0 if absent
+func (e *APIError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// RateLimited reports whether the error is caused by rate limiting
+func (e *APIError) RateLimited() bool {
+	return e.status == http.StatusTooManyRequests || e.Code == "rate_limit_exceeded"
+}
+
+func (e *APIError) withResponse(resp *xhttp.Response) *APIError {
+	e.status = resp.StatusCode
+	e.retryAfter = xhttp.ParseRetryAfter(resp.Header)
+	return e
 }
 
 // OpenAIEmbeddingData holds the data for a single embedding
@@ -76,3 +121,35 @@ type OpenAIEmbeddingResponse struct {
 	Usage  llm.Usage             `json:"usage"`
 	Error  *APIError             `json:"error,omitempty"`
 }
+
+// OpenAIMediaUsage is the token usage returned by the image and audio APIs
+type OpenAIMediaUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+func (u *OpenAIMediaUsage) toUsage() llm.Usage {
+	if u == nil {
+		return llm.Usage{}
+	}
+	return llm.Usage{PromptTokens: u.InputTokens, CompletionTokens: u.OutputTokens, TotalTokens: u.TotalTokens}
+}
+
+// OpenAIImageResponse is the response from the OpenAI Image API
+type OpenAIImageResponse struct {
+	Created int64             `json:"created"`
+	Data    []llm.Image       `json:"data"`
+	Usage   *OpenAIMediaUsage `json:"usage,omitempty"` // only returned by gpt-image models
+	Error   *APIError         `json:"error,omitempty"`
+}
+
+// OpenAITranscriptionResponse is the response from the OpenAI Audio Transcription API in json or verbose_json format
+type OpenAITranscriptionResponse struct {
+	Text     string                     `json:"text"`
+	Language string                     `json:"language,omitempty"`
+	Duration float64                    `json:"duration,omitempty"`
+	Segments []llm.TranscriptionSegment `json:"segments,omitempty"`
+	Usage    *OpenAIMediaUsage          `json:"usage,omitempty"` // only returned by token-billed models such as gpt-4o-transcribe
+	Error    *APIError                  `json:"error,omitempty"`
+}
@@ -137,3 +137,57 @@ func TestOpenRouterProvider_CreateEmbeddings(t *testing.T) {
 	require.Len(t, resp.Data, 1)
 	assert.Equal(t, []float32{0.1, 0.2, 0.3}, resp.Data[0].Vector)
 }
+
+func TestOpenRouterProvider_ChatStream_WithParallelToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"call_a\",\"type\":\"function\",\"function\":{\"name\":\"get_weather\",\"arguments\":\"{\\\"city\\\":\"}}]}}]}\n\n"))
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"index\":1,\"id\":\"call_b\",\"type\":\"function\",\"function\":{\"name\":\"get_time\",\"arguments\":\"{}\"}}]}}]}\n\n"))
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"\\\"Paris\\\"}\"}}]}}]}\n\n"))
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"tool_calls\"}]}\n\n"))
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	req := &llm.ChatRequest{
+		Model:    "openai/gpt-4o",
+		Messages: []llm.Message{{Role: "user", Content: "Weather and time in Paris?"}},
+	}
+
+	fullResp, err := provider.ChatStream(context.Background(), req, func(chunk string) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, "tool_calls", fullResp.FinishReason)
+	require.Len(t, fullResp.ToolCalls, 2)
+	assert.Equal(t, "call_a", fullResp.ToolCalls[0].ID)
+	assert.Equal(t, `{"city":"Paris"}`, fullResp.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, "call_b", fullResp.ToolCalls[1].ID)
+	assert.Equal(t, "get_time", fullResp.ToolCalls[1].Function.Name)
+}
+
+func TestOpenRouterProvider_ChatStreamEvents_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"reasoning\":\"Let me think\"}}]}\n\n"))
+		_, _ = w.Write([]byte("data: {\"error\":{\"message\":\"provider overloaded\"}}\n\n"))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL)
+	req := &llm.ChatRequest{
+		Model:    "deepseek/deepseek-r1",
+		Messages: []llm.Message{{Role: "user", Content: "Hi"}},
+	}
+
+	var events []llm.StreamEvent
+	_, err := provider.ChatStreamEvents(context.Background(), req, func(event llm.StreamEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	require.Error(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, llm.StreamEventReasoningDelta, events[0].Type)
+	assert.Equal(t, "Let me think", events[0].Text)
+	assert.Equal(t, llm.StreamEventError, events[1].Type)
+	assert.Contains(t, events[1].Err.Error(), "provider overloaded")
+}
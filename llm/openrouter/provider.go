@@ -51,18 +51,18 @@ func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRes
 		Timeout: p.cfg.Timeout,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("%s: chat api call failed: %w", providerName, err)
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("%s: chat api call failed: %w", providerName, err))
 	}
 	if resp == nil || len(resp.Body) == 0 {
-		return nil, fmt.Errorf("%s: empty response", providerName)
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("%s: empty response", providerName))
 	}
 
 	var apiResp OpenAIResponse
 	if err := json.Unmarshal(resp.Body, &apiResp); err != nil {
-		return nil, fmt.Errorf("%s: unmarshalling chat response: %w (body: %s)", providerName, err, string(resp.Body))
+		return nil, llm.NewError(providerName, resp.StatusCode, fmt.Errorf("%s: unmarshalling chat response: %w (body: %s)", providerName, err, string(resp.Body)))
 	}
 	if apiResp.Error != nil {
-		return nil, fmt.Errorf("%s: api error: %s", providerName, apiResp.Error.Message)
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("%s: api error: %w", providerName, apiResp.Error.withResponse(resp)))
 	}
 	if len(apiResp.Choices) == 0 {
 		return nil, fmt.Errorf("%s: no choices returned", providerName)
@@ -74,6 +74,7 @@ func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRes
 	}
 	return &llm.ChatResponse{
 		Content:      strings.TrimSpace(contentStr),
+		ToolCalls:    apiResp.Choices[0].Message.ToolCalls,
 		FinishReason: apiResp.Choices[0].FinishReason,
 		Usage:        apiResp.Usage,
 		RawResponse:  resp.Body,
@@ -82,6 +83,11 @@ func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRes
 
 // ChatStream implements llm.Chatter for streaming chat
 func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, callback func(chunk string) error) (*llm.ChatResponse, error) {
+	return p.ChatStreamEvents(ctx, req, llm.TextCallback(callback))
+}
+
+// ChatStreamEvents implements llm.EventStreamer for streaming chat with structured events
+func (p *Provider) ChatStreamEvents(ctx context.Context, req *llm.ChatRequest, handler llm.StreamHandler) (*llm.ChatResponse, error) {
 	body, err := p.buildChatBody(req, true)
 	if err != nil {
 		return nil, fmt.Errorf("%s: building stream request: %w", providerName, err)
@@ -95,8 +101,7 @@ func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, callbac
 	headers := p.buildAuthHeaders()
 	headers["Accept"] = "text/event-stream"
 
-	var fullContent strings.Builder
-	var finalResponse *llm.ChatResponse
+	acc := llm.NewStreamAccumulator(handler)
 
 	timeout := p.cfg.Timeout
 	if timeout == 0 {
@@ -124,37 +129,47 @@ func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, callbac
 		}
 
 		if streamResp.Error != nil {
-			return fmt.Errorf("%s: stream error: %s", providerName, streamResp.Error.Message)
+			return fmt.Errorf("%s: stream error: %w", providerName, streamResp.Error)
 		}
 
 		if len(streamResp.Choices) > 0 {
 			choice := streamResp.Choices[0]
-			if choice.Delta.Content != "" {
-				chunk := choice.Delta.Content
-				fullContent.WriteString(chunk)
-				if err := callback(chunk); err != nil {
-					return fmt.Errorf("callback error: %w", err)
-				}
+			if err := acc.Reasoning(choice.Delta.Reasoning); err != nil {
+				return err
 			}
-			if choice.FinishReason != "" {
-				if finalResponse == nil {
-					finalResponse = &llm.ChatResponse{}
+			if err := acc.Text(choice.Delta.Content); err != nil {
+				return err
+			}
+			for _, tc := range choice.Delta.ToolCalls {
+				if err := acc.ToolCall(llm.ToolCallDelta{
+					Index:     tc.Index,
+					ID:        tc.ID,
+					Type:      tc.Type,
+					Name:      tc.Function.Name,
+					Arguments: tc.Function.Arguments,
+				}); err != nil {
+					return err
 				}
-				finalResponse.FinishReason = choice.FinishReason
+			}
+			acc.Finish(choice.FinishReason)
+		}
+		// OpenRouter 在最后一个 data 块中返回整个流的 token 用量
+		if streamResp.Usage != nil {
+			if err := acc.Usage(*streamResp.Usage); err != nil {
+				return err
 			}
 		}
 		return nil
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("%s: stream failed: %w", providerName, err)
+		return nil, acc.Fail(llm.NewError(providerName, 0, fmt.Errorf("%s: stream failed: %w", providerName, err)))
 	}
 
-	if finalResponse == nil {
-		finalResponse = &llm.ChatResponse{FinishReason: "unknown"}
+	if acc.FinishReason() == "" {
+		acc.Finish("unknown")
 	}
-	finalResponse.Content = fullContent.String()
-	return finalResponse, nil
+	return acc.Done()
 }
 
 // CreateEmbeddings implements llm.Embedder
@@ -178,15 +193,15 @@ func (p *Provider) CreateEmbeddings(ctx context.Context, req *llm.EmbeddingReque
 		Timeout: p.cfg.Timeout,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("openrouter: embedding api call failed: %w", err)
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("openrouter: embedding api call failed: %w", err))
 	}
 
 	var openAIResp OpenAIEmbeddingResponse
 	if err := json.Unmarshal(resp.Body, &openAIResp); err != nil {
-		return nil, fmt.Errorf("openrouter: unmarshalling embedding response: %w (body: %s)", err, string(resp.Body))
+		return nil, llm.NewError(providerName, resp.StatusCode, fmt.Errorf("openrouter: unmarshalling embedding response: %w (body: %s)", err, string(resp.Body)))
 	}
 	if openAIResp.Error != nil {
-		return nil, fmt.Errorf("openrouter: embedding api error: %s", openAIResp.Error.Message)
+		return nil, llm.NewError(providerName, 0, fmt.Errorf("openrouter: embedding api error: %w", openAIResp.Error.withResponse(resp)))
 	}
 
 	embeddings := make([]llm.Embedding, len(openAIResp.Data))
@@ -212,7 +227,7 @@ func (p *Provider) buildChatBody(req *llm.ChatRequest, stream bool) (map[string]
 	}
 	msgs := make([]map[string]interface{}, len(req.Messages))
 	for i, m := range req.Messages {
-		msgs[i] = map[string]interface{}{"role": m.Role, "content": m.Content}
+		msgs[i] = buildMessage(m)
 	}
 	body := map[string]interface{}{
 		"model":       req.Model,
@@ -259,12 +274,29 @@ func (p *Provider) buildChatBody(req *llm.ChatRequest, stream bool) (map[string]
 	if req.Reasoning != nil {
 		body["reasoning"] = req.Reasoning
 	}
-	if req.ResponseFormat != "" {
+	if req.JSONSchema != nil {
+		body["response_format"] = map[string]interface{}{"type": "json_schema", "json_schema": req.JSONSchema}
+	} else if req.ResponseFormat != "" {
 		body["response_format"] = map[string]interface{}{"type": req.ResponseFormat}
 	}
 	return body, nil
 }
 
+// buildMessage converts llm.Message to the OpenAI message format, including tool calls and tool results
+func buildMessage(m llm.Message) map[string]interface{} {
+	msg := map[string]interface{}{"role": m.Role, "content": m.Content}
+	if m.Name != "" {
+		msg["name"] = m.Name
+	}
+	if len(m.ToolCalls) > 0 {
+		msg["tool_calls"] = m.ToolCalls
+	}
+	if m.ToolCallID != "" {
+		msg["tool_call_id"] = m.ToolCallID
+	}
+	return msg
+}
+
 func (p *Provider) buildAuthHeaders() map[string]string {
 	return map[string]string{
 		"Content-Type":  "application/json",
@@ -274,4 +306,5 @@ func (p *Provider) buildAuthHeaders() map[string]string {
 
 // Ensure *Provider implements the interfaces
 var _ llm.Chatter = (*Provider)(nil)
+var _ llm.EventStreamer = (*Provider)(nil)
 var _ llm.Embedder = (*Provider)(nil)
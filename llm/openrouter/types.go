@@ -1,8 +1,10 @@
 package openrouter
 
 import (
+	"net/http"
 	"time"
 
+	xhttp "github.com/jessewkun/gocommon/http"
 	"github.com/jessewkun/gocommon/llm"
 )
 
@@ -37,11 +39,24 @@ type OpenAIResponse struct {
 type OpenAIStreamChoice struct {
 	Index int `json:"index"`
 	Delta struct {
-		Content string `json:"content"`
+		Content   string                `json:"content"`
+		Reasoning string                `json:"reasoning"` // reasoning tokens，需模型支持且未设置 reasoning.exclude
+		ToolCalls []OpenAIToolCallDelta `json:"tool_calls"`
 	} `json:"delta"`
 	FinishReason string `json:"finish_reason"`
 }
 
+// OpenAIToolCallDelta 是流式响应中工具调用的片段
+type OpenAIToolCallDelta struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
+}
+
 // OpenAIStreamResponse 是流式响应中单个 data 块的结构
 type OpenAIStreamResponse struct {
 	ID      string               `json:"id"`
@@ -49,15 +64,54 @@ type OpenAIStreamResponse struct {
 	Created int64                `json:"created"`
 	Model   string               `json:"model"`
 	Choices []OpenAIStreamChoice `json:"choices"`
+	Usage   *llm.Usage           `json:"usage,omitempty"` // 仅在最后一个 data 块中出现
 	Error   *APIError            `json:"error,omitempty"`
 }
 
 // APIError 封装了 API 可能返回的错误信息
 type APIError struct {
-	Message string `json:"message"`
-	Type    string `json:"type"`
-	Param   string `json:"param"`
-	Code    string `json:"code"`
+	Message string      `json:"message"`
+	Type    string      `json:"type"`
+	Param   string      `json:"param"`
+	Code    interface{} `json:"code"` // OpenRouter 返回数字状态码，其他 OpenAI 兼容服务可能返回字符串
+
+	status     int           // 携带该错误的响应的 HTTP 状态码
+	retryAfter time.Duration // 由 Retry-After 响应头解析
+}
+
+// Error 实现 error 接口
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// StatusCode 返回响应的 HTTP 状态码，未知时返回 0
+func (e *APIError) StatusCode() int {
+	return e.status
+}
+
+// RetryAfter 返回 Retry-After 响应头建议的等待时间，未设置时返回 0
+func (e *APIError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// RateLimited 判断是否为限流错误，流式响应中的错误没有 HTTP 状态码，以 code 为准
+func (e *APIError) RateLimited() bool {
+	if e.status == http.StatusTooManyRequests {
+		return true
+	}
+	switch code := e.Code.(type) {
+	case float64:
+		return int(code) == http.StatusTooManyRequests
+	case string:
+		return code == "rate_limit_exceeded" || code == "429"
+	}
+	return false
+}
+
+func (e *APIError) withResponse(resp *xhttp.Response) *APIError {
+	e.status = resp.StatusCode
+	e.retryAfter = xhttp.ParseRetryAfter(resp.Header)
+	return e
 }
 
 // OpenAIEmbeddingData holds the data for a single embedding
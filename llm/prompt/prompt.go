@@ -0,0 +1,54 @@
+package prompt
+
+import "github.com/jessewkun/gocommon/llm"
+
+// Prompt 绑定了变量类型的模板，在代码中声明一次，渲染时由编译器检查变量类型
+//
+//	var summarize = prompt.New[SummarizeVars]("summarize")
+//	msgs, err := summarize.Render(SummarizeVars{Text: text})
+type Prompt[T any] struct {
+	Registry *Registry // 模板注册表，为 nil 时使用默认注册表
+	Name     string    // 模板名
+	Version  string    // 版本号，为空时使用默认版本
+}
+
+// New 创建使用默认注册表与默认版本的模板
+func New[T any](name string) *Prompt[T] {
+	return &Prompt[T]{Name: name}
+}
+
+// WithVersion 返回固定使用指定版本的副本
+func (p *Prompt[T]) WithVersion(version string) *Prompt[T] {
+	cp := *p
+	cp.Version = version
+	return &cp
+}
+
+// Render 渲染模板
+func (p *Prompt[T]) Render(vars T) ([]llm.Message, error) {
+	return p.registry().RenderVersion(p.Name, p.Version, vars)
+}
+
+// Check 使用零值变量试渲染，用于启动时检查模板是否存在、引用的变量与 T 是否匹配
+func (p *Prompt[T]) Check() error {
+	var zero T
+	_, err := p.Render(zero)
+	return err
+}
+
+func (p *Prompt[T]) registry() *Registry {
+	if p.Registry != nil {
+		return p.Registry
+	}
+	return defaultRegistry
+}
+
+// Render 使用默认注册表的默认版本渲染模板
+func Render(name string, vars interface{}) ([]llm.Message, error) {
+	return defaultRegistry.Render(name, vars)
+}
+
+// RenderVersion 使用默认注册表渲染指定版本的模板
+func RenderVersion(name, version string, vars interface{}) ([]llm.Message, error) {
+	return defaultRegistry.RenderVersion(name, version, vars)
+}
@@ -0,0 +1,130 @@
+package prompt
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type summarizeVars struct {
+	Lang     string
+	Text     string
+	Keywords []string
+}
+
+// fakeSource 模拟 nacos 配置源，保存监听回调以便触发变更
+type fakeSource struct {
+	content  string
+	onChange func(namespace, group, data string)
+}
+
+func (f *fakeSource) GetConfig(dataID string) (string, error) {
+	return f.content, nil
+}
+
+func (f *fakeSource) ListenConfig(dataID string, onChange func(namespace, group, data string)) error {
+	f.onChange = onChange
+	return nil
+}
+
+func TestRegistry_Render(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.SetTemplates("config", []Template{
+		{Name: "summarize", Version: "v1", System: "你是摘要助手", User: "总结：{{.Text}}"},
+		{
+			Name:    "summarize",
+			Version: "v2",
+			System:  "你是摘要助手，使用{{.Lang}}回答。",
+			Messages: []MessageTemplate{
+				{Role: "user", Content: "总结：今天天气很好"},
+				{Role: "assistant", Content: "天气好"},
+			},
+			User: "{{if .Keywords}}关键词：{{join .Keywords \"、\"}}\n{{end}}总结：{{.Text}}",
+		},
+	}))
+
+	// New 使用默认注册表，模板只加载到了 r 中
+	_, err := New[summarizeVars]("summarize").Render(summarizeVars{})
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	// 未显式指定默认版本时，最后定义的版本为默认版本
+	p := &Prompt[summarizeVars]{Registry: r, Name: "summarize"}
+	msgs, err := p.Render(summarizeVars{Lang: "中文", Text: "长文本", Keywords: []string{"a", "b"}})
+	require.NoError(t, err)
+	assert.Equal(t, []llm.Message{
+		{Role: "system", Content: "你是摘要助手，使用中文回答。"},
+		{Role: "user", Content: "总结：今天天气很好"},
+		{Role: "assistant", Content: "天气好"},
+		{Role: "user", Content: "关键词：a、b\n总结：长文本"},
+	}, msgs)
+
+	msgs, err = p.WithVersion("v1").Render(summarizeVars{Text: "长文本"})
+	require.NoError(t, err)
+	assert.Equal(t, []llm.Message{{Role: "system", Content: "你是摘要助手"}, {Role: "user", Content: "总结：长文本"}}, msgs)
+
+	// map 变量缺少字段时报错
+	_, err = r.Render("summarize", map[string]interface{}{"Text": "x"})
+	assert.Error(t, err)
+
+	_, err = r.RenderVersion("summarize", "v3", nil)
+	assert.True(t, errors.Is(err, ErrTemplateNotFound))
+	assert.ElementsMatch(t, []string{"v1", "v2"}, r.Versions("summarize"))
+}
+
+func TestRegistry_SetTemplates_Invalid(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.SetTemplates("config", []Template{{Name: "greet", User: "hi {{.Name}}"}}))
+
+	// 编译失败时保留原模板
+	err := r.SetTemplates("config", []Template{{Name: "greet", User: "hi {{.Name"}})
+	assert.Error(t, err)
+	err = r.SetTemplates("config", []Template{{Name: "greet"}, {Name: "greet"}})
+	assert.Error(t, err)
+
+	msgs, err := r.Render("greet", map[string]string{"Name": "Tom"})
+	require.NoError(t, err)
+	assert.Equal(t, []llm.Message{{Role: "user", Content: "hi Tom"}}, msgs)
+
+	// 类型不匹配的变量在 Check 时发现
+	type wrongVars struct{ Title string }
+	assert.Error(t, (&Prompt[wrongVars]{Registry: r, Name: "greet"}).Check())
+	type greetVars struct{ Name string }
+	assert.NoError(t, (&Prompt[greetVars]{Registry: r, Name: "greet"}).Check())
+}
+
+func TestRegistry_Watch(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.SetTemplates("config", []Template{
+		{Name: "qa", Version: "v1", User: "config v1"},
+		{Name: "qa", Version: "v2", User: "config v2"},
+	}))
+
+	src := &fakeSource{content: `{"templates": [{"name": "qa", "version": "v2", "user": "nacos v2"}, {"name": "qa", "version": "v3", "user": "nacos v3"}, {"name": "qa", "version": "v1", "default": true, "user": "nacos v1"}]}`}
+	require.NoError(t, r.Watch(src, "prompts.json"))
+
+	// 后加载的来源覆盖同名同版本的模板，显式标记的默认版本优先
+	msgs, err := r.Render("qa", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "nacos v1", msgs[0].Content)
+	msgs, err = r.RenderVersion("qa", "v2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "nacos v2", msgs[0].Content)
+
+	// 热更新
+	src.onChange("public", "DEFAULT_GROUP", `{"templates": [{"name": "qa", "version": "v4", "user": "nacos v4"}]}`)
+	msgs, err = r.Render("qa", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "nacos v4", msgs[0].Content)
+	msgs, err = r.RenderVersion("qa", "v2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "config v2", msgs[0].Content)
+
+	// 非法内容不影响已加载的模板
+	src.onChange("public", "DEFAULT_GROUP", `{"templates": [`)
+	msgs, err = r.Render("qa", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "nacos v4", msgs[0].Content)
+}
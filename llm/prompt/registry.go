@@ -0,0 +1,280 @@
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/jessewkun/gocommon/logger"
+)
+
+// ErrTemplateNotFound 模板或指定版本不存在
+var ErrTemplateNotFound = errors.New("prompt: template not found")
+
+// ConfigSource 远程配置源，*nacos.Client 实现了该接口
+type ConfigSource interface {
+	// GetConfig 获取配置内容
+	GetConfig(dataID string) (string, error)
+	// ListenConfig 监听配置变化
+	ListenConfig(dataID string, onChange func(namespace, group, data string)) error
+}
+
+// compiled 编译后的模板
+type compiled struct {
+	Template
+	system   *template.Template
+	messages []*template.Template
+	user     *template.Template
+}
+
+// entry 同名模板的所有版本
+type entry struct {
+	versions map[string]*compiled
+	def      string // 默认版本
+	pinned   bool   // 默认版本是否由 Default 显式指定
+}
+
+// Registry 模板注册表，模板按来源分组，后设置的来源覆盖先设置来源中同名同版本的模板
+type Registry struct {
+	mu        sync.RWMutex
+	sources   map[string][]*compiled
+	order     []string
+	templates map[string]*entry
+}
+
+var defaultRegistry = NewRegistry()
+
+// NewRegistry 创建模板注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		sources:   make(map[string][]*compiled),
+		templates: make(map[string]*entry),
+	}
+}
+
+// DefaultRegistry 返回由 config 模块加载的默认注册表
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// SetTemplates 设置来源 source 的全部模板，替换该来源之前的模板
+// 所有模板编译成功后才会生效，任一模板出错时保留原模板并返回错误
+func (r *Registry) SetTemplates(source string, templates []Template) error {
+	list := make([]*compiled, 0, len(templates))
+	seen := make(map[string]bool, len(templates))
+	for _, t := range templates {
+		if t.Name == "" {
+			return fmt.Errorf("prompt: template name is required (source: %s)", source)
+		}
+		key := t.Name + "@" + t.Version
+		if seen[key] {
+			return fmt.Errorf("prompt: duplicate template %s (source: %s)", key, source)
+		}
+		seen[key] = true
+		c, err := compile(t)
+		if err != nil {
+			return err
+		}
+		list = append(list, c)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.sources[source]; !ok {
+		r.order = append(r.order, source)
+	}
+	r.sources[source] = list
+	r.rebuild()
+	return nil
+}
+
+// rebuild 按来源顺序合并模板，需持有写锁
+func (r *Registry) rebuild() {
+	templates := make(map[string]*entry)
+	for _, source := range r.order {
+		for _, c := range r.sources[source] {
+			e, ok := templates[c.Name]
+			if !ok {
+				e = &entry{versions: make(map[string]*compiled)}
+				templates[c.Name] = e
+			}
+			e.versions[c.Version] = c
+			// 显式标记的默认版本优先，否则取最后定义的版本
+			if c.Default {
+				e.def = c.Version
+				e.pinned = true
+			} else if !e.pinned {
+				e.def = c.Version
+			}
+		}
+	}
+	r.templates = templates
+}
+
+// Watch 从远程配置源加载模板并监听变化，配置内容为 Document 格式的 JSON
+// 变更后的内容解析或编译失败时保留原模板并记录错误日志
+func (r *Registry) Watch(src ConfigSource, dataID string) error {
+	source := "remote:" + dataID
+	content, err := src.GetConfig(dataID)
+	if err != nil {
+		return fmt.Errorf("prompt: get config %s: %w", dataID, err)
+	}
+	if err := r.setDocument(source, content); err != nil {
+		return err
+	}
+	return src.ListenConfig(dataID, func(namespace, group, data string) {
+		if err := r.setDocument(source, data); err != nil {
+			logger.ErrorWithField(context.Background(), TAG, "reload prompt templates failed", map[string]interface{}{
+				"data_id": dataID,
+				"err":     err.Error(),
+			})
+			return
+		}
+		logger.Info(context.Background(), TAG, "prompt templates reloaded from %s", dataID)
+	})
+}
+
+func (r *Registry) setDocument(source, content string) error {
+	var doc Document
+	if strings.TrimSpace(content) != "" {
+		if err := json.Unmarshal([]byte(content), &doc); err != nil {
+			return fmt.Errorf("prompt: parse %s: %w", source, err)
+		}
+	}
+	return r.SetTemplates(source, doc.Templates)
+}
+
+// Get 获取模板，version 为空时返回默认版本
+func (r *Registry) Get(name, version string) (Template, error) {
+	c, err := r.lookup(name, version)
+	if err != nil {
+		return Template{}, err
+	}
+	return c.Template, nil
+}
+
+// Versions 返回模板的所有版本号
+func (r *Registry) Versions(name string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	e, ok := r.templates[name]
+	if !ok {
+		return nil
+	}
+	versions := make([]string, 0, len(e.versions))
+	for v := range e.versions {
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+// Render 使用默认版本渲染模板，vars 为模板变量（结构体或 map）
+func (r *Registry) Render(name string, vars interface{}) ([]llm.Message, error) {
+	return r.RenderVersion(name, "", vars)
+}
+
+// RenderVersion 渲染指定版本的模板，version 为空时使用默认版本
+func (r *Registry) RenderVersion(name, version string, vars interface{}) ([]llm.Message, error) {
+	c, err := r.lookup(name, version)
+	if err != nil {
+		return nil, err
+	}
+	return c.render(vars)
+}
+
+func (r *Registry) lookup(name, version string) (*compiled, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	e, ok := r.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	if version == "" {
+		version = e.def
+	}
+	c, ok := e.versions[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s@%s", ErrTemplateNotFound, name, version)
+	}
+	return c, nil
+}
+
+// funcs 模板中可用的函数
+var funcs = template.FuncMap{
+	"join": strings.Join,
+	"trim": strings.TrimSpace,
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func compile(t Template) (*compiled, error) {
+	id := t.Name + "@" + t.Version
+	parse := func(part, text string) (*template.Template, error) {
+		if text == "" {
+			return nil, nil
+		}
+		tpl, err := template.New(id + "/" + part).Funcs(funcs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("prompt: parse template %s: %w", id, err)
+		}
+		return tpl, nil
+	}
+
+	c := &compiled{Template: t}
+	var err error
+	if c.system, err = parse("system", t.System); err != nil {
+		return nil, err
+	}
+	for i, m := range t.Messages {
+		if m.Role == "" {
+			return nil, fmt.Errorf("prompt: template %s messages[%d] role is required", id, i)
+		}
+		tpl, err := parse(fmt.Sprintf("messages[%d]", i), m.Content)
+		if err != nil {
+			return nil, err
+		}
+		c.messages = append(c.messages, tpl)
+	}
+	if c.user, err = parse("user", t.User); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *compiled) render(vars interface{}) ([]llm.Message, error) {
+	msgs := make([]llm.Message, 0, len(c.messages)+2)
+	add := func(role string, tpl *template.Template) error {
+		if tpl == nil {
+			return nil
+		}
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, vars); err != nil {
+			return fmt.Errorf("prompt: render %s: %w", tpl.Name(), err)
+		}
+		if content := strings.TrimSpace(buf.String()); content != "" {
+			msgs = append(msgs, llm.Message{Role: role, Content: content})
+		}
+		return nil
+	}
+
+	if err := add("system", c.system); err != nil {
+		return nil, err
+	}
+	for i, tpl := range c.messages {
+		if err := add(c.Messages[i].Role, tpl); err != nil {
+			return nil, err
+		}
+	}
+	if err := add("user", c.user); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
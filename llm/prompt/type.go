@@ -0,0 +1,91 @@
+// Package prompt 管理带版本的提示词模板，模板来自 config 模块或 nacos，支持热更新，渲染为 []llm.Message
+package prompt
+
+import (
+	"context"
+
+	"github.com/jessewkun/gocommon/config"
+	"github.com/jessewkun/gocommon/logger"
+	"github.com/jessewkun/gocommon/nacos"
+	"github.com/spf13/viper"
+)
+
+const TAG = "LLM_PROMPT"
+
+// configSource config 模块中模板的来源名
+const configSource = "config"
+
+// Config 提示词模块配置，对应配置文件中的 llm_prompt
+type Config struct {
+	Templates []Template    `mapstructure:"templates" json:"templates"` // 模板列表
+	Nacos     []NacosSource `mapstructure:"nacos" json:"nacos"`         // 从 nacos 加载的模板，配置内容格式见 Document
+}
+
+// NacosSource nacos 中的模板配置
+type NacosSource struct {
+	Client string `mapstructure:"client" json:"client"`   // nacos 实例名，对应 nacos 模块的配置 key
+	DataID string `mapstructure:"data_id" json:"data_id"` // 配置的 data id
+}
+
+// Template 提示词模板，System、Messages、User 均为 text/template 模板
+// 渲染顺序为 System、Messages、User，渲染结果为空的消息会被忽略
+type Template struct {
+	Name     string            `mapstructure:"name" json:"name"`         // 模板名
+	Version  string            `mapstructure:"version" json:"version"`   // 版本号
+	Default  bool              `mapstructure:"default" json:"default"`   // 是否为默认版本，未指定时同名模板中最后定义的版本为默认版本
+	System   string            `mapstructure:"system" json:"system"`     // system 消息模板
+	Messages []MessageTemplate `mapstructure:"messages" json:"messages"` // 位于 system 与 user 之间的消息，可用于 few-shot 示例
+	User     string            `mapstructure:"user" json:"user"`         // user 消息模板
+}
+
+// MessageTemplate 单条消息模板
+type MessageTemplate struct {
+	Role    string `mapstructure:"role" json:"role"`       // system / user / assistant
+	Content string `mapstructure:"content" json:"content"` // 消息内容模板
+}
+
+// Document nacos 中模板配置的内容格式（JSON）
+type Document struct {
+	Templates []Template `json:"templates"`
+}
+
+var Cfg = &Config{}
+
+func init() {
+	config.Register("llm_prompt", Cfg)
+	config.RegisterCallback("llm_prompt", Init, "config", "log", "nacos")
+}
+
+// Init 加载配置中的模板，并监听配置的 nacos 数据
+func Init() error {
+	if err := defaultRegistry.SetTemplates(configSource, Cfg.Templates); err != nil {
+		return err
+	}
+	for _, s := range Cfg.Nacos {
+		client, err := nacos.GetClient(s.Client)
+		if err != nil {
+			return err
+		}
+		if err := defaultRegistry.Watch(client, s.DataID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reload 重新加载配置中的模板，模板编译失败时保留原模板
+// nacos 数据源的变更由 nacos 监听处理，修改 nacos 配置项需要重启
+func (c *Config) Reload(v *viper.Viper) error {
+	newCfg := &Config{}
+	if err := v.UnmarshalKey("llm_prompt", newCfg); err != nil {
+		logger.ErrorWithMsg(context.Background(), TAG, "failed to reload llm_prompt config: %v", err)
+		return err
+	}
+	if err := defaultRegistry.SetTemplates(configSource, newCfg.Templates); err != nil {
+		logger.ErrorWithMsg(context.Background(), TAG, "failed to reload llm_prompt templates: %v", err)
+		return err
+	}
+	c.Templates = newCfg.Templates
+	logger.Info(context.Background(), TAG, "llm_prompt config reload success, templates: %d", len(c.Templates))
+	return nil
+}
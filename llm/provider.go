@@ -15,11 +15,27 @@ type Chatter interface {
 	ChatStream(ctx context.Context, req *ChatRequest, callback func(chunk string) error) (*ChatResponse, error)
 }
 
+// EventStreamer 接口定义了结构化事件的流式聊天功能
+// 实现该接口的 Provider 会推送文本、推理、工具调用、用量等事件；未实现时 Client 会退化为仅推送文本与结束事件
+type EventStreamer interface {
+	Provider
+	ChatStreamEvents(ctx context.Context, req *ChatRequest, handler StreamHandler) (*ChatResponse, error)
+}
+
 // Embedder 接口定义了向量化功能
 type Embedder interface {
 	Provider
 	CreateEmbeddings(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error)
 }
 
-// 未来可以继续扩展
-// type ImageGenerator interface { ... }
+// ImageGenerator 接口定义了图片生成功能
+type ImageGenerator interface {
+	Provider
+	GenerateImages(ctx context.Context, req *ImageRequest) (*ImageResponse, error)
+}
+
+// Transcriber 接口定义了语音转写功能
+type Transcriber interface {
+	Provider
+	Transcribe(ctx context.Context, req *TranscriptionRequest) (*TranscriptionResponse, error)
+}
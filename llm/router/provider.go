@@ -0,0 +1,383 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/jessewkun/gocommon/logger"
+)
+
+const logTag = "LLM_ROUTER"
+
+// ErrNoAvailableBackend 所有后端均处于熔断状态或不支持所需能力
+var ErrNoAvailableBackend = errors.New("router: no available backend")
+
+// statusCoder 携带 HTTP 状态码的错误，Provider 返回的错误实现该接口时用于判断是否切换后端
+type statusCoder interface {
+	StatusCode() int
+}
+
+// DefaultShouldFailover 默认的切换判断：
+// 调用方 ctx 已取消时不切换；
+// 错误为 *llm.Error 时按分类判断：请求参数错误、上下文超长、内容安全拦截换后端也无法成功，不切换，鉴权失败只与当前后端的 Key 有关，切换；
+// 限流错误切换；错误携带状态码时仅在 408 与 5xx 时切换；其余错误（网络错误、超时等）均切换
+func DefaultShouldFailover(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	switch llm.CategoryOf(err) {
+	case llm.ErrorInvalidRequest, llm.ErrorContextLength, llm.ErrorSafetyBlocked:
+		return false
+	case llm.ErrorRateLimited, llm.ErrorAuth, llm.ErrorServer, llm.ErrorTimeout:
+		return true
+	}
+	if llm.IsRateLimited(err) {
+		return true
+	}
+	// 流式响应体中的错误没有状态码（为 0），按未知错误处理
+	var sc statusCoder
+	if errors.As(err, &sc) && sc.StatusCode() > 0 {
+		code := sc.StatusCode()
+		return code == http.StatusRequestTimeout || code >= http.StatusInternalServerError
+	}
+	return true
+}
+
+// backend 运行时的后端，包含底层 Provider 与熔断状态
+type backend struct {
+	Backend
+	provider llm.Provider
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	lastErr   string
+}
+
+// available 熔断器关闭或冷却期已过（半开）时返回 true
+func (b *backend) available(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !now.Before(b.openUntil)
+}
+
+func (b *backend) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.lastErr = ""
+}
+
+func (b *backend) failure(err error, threshold int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.lastErr = err.Error()
+	if b.failures >= threshold {
+		b.openUntil = time.Now().Add(cooldown)
+	}
+}
+
+// Provider 路由 Provider，实现 llm.Chatter、llm.EventStreamer 与 llm.Embedder
+type Provider struct {
+	cfg      Config
+	backends []*backend
+}
+
+var (
+	_ llm.Chatter       = (*Provider)(nil)
+	_ llm.EventStreamer = (*Provider)(nil)
+	_ llm.Embedder      = (*Provider)(nil)
+)
+
+// NewProvider 根据配置创建路由 Provider，底层 Provider 通过 llm.NewProvider 创建
+func NewProvider(cfg Config) (*Provider, error) {
+	if len(cfg.Backends) == 0 {
+		return nil, fmt.Errorf("router: at least one backend is required")
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	if cfg.ShouldFailover == nil {
+		cfg.ShouldFailover = DefaultShouldFailover
+	}
+
+	p := &Provider{cfg: cfg}
+	names := make(map[string]struct{}, len(cfg.Backends))
+	for i, b := range cfg.Backends {
+		if b.Provider == "" {
+			return nil, fmt.Errorf("router: backends[%d] provider is required", i)
+		}
+		if b.Provider == providerName {
+			return nil, fmt.Errorf("router: backends[%d] cannot be a router", i)
+		}
+		provider, err := llm.NewProvider(b.Provider, b.Config)
+		if err != nil {
+			return nil, fmt.Errorf("router: creating backend %d (%s): %w", i, b.Provider, err)
+		}
+		if b.Name == "" {
+			b.Name = b.Provider
+			if b.Model != "" {
+				b.Name += "/" + b.Model
+			}
+		}
+		if _, exists := names[b.Name]; exists {
+			return nil, fmt.Errorf("router: duplicate backend name %q", b.Name)
+		}
+		names[b.Name] = struct{}{}
+		if b.Weight <= 0 {
+			b.Weight = 1
+		}
+		p.backends = append(p.backends, &backend{Backend: b, provider: provider})
+	}
+	return p, nil
+}
+
+// Name implements llm.Provider
+func (p *Provider) Name() string { return providerName }
+
+// Status 返回各后端的熔断状态，顺序与配置一致
+func (p *Provider) Status() []BackendStatus {
+	now := time.Now()
+	statuses := make([]BackendStatus, 0, len(p.backends))
+	for _, b := range p.backends {
+		b.mu.Lock()
+		statuses = append(statuses, BackendStatus{
+			Name:                b.Name,
+			ConsecutiveFailures: b.failures,
+			Open:                now.Before(b.openUntil),
+			OpenUntil:           b.openUntil,
+			LastError:           b.lastErr,
+		})
+		b.mu.Unlock()
+	}
+	return statuses
+}
+
+// candidates 返回本次调用依次尝试的后端：按 Priority 升序，同优先级内按权重随机排序，跳过熔断中与不支持 supports 的后端
+func (p *Provider) candidates(supports func(llm.Provider) bool) []*backend {
+	now := time.Now()
+	groups := make(map[int][]*backend)
+	var priorities []int
+	for _, b := range p.backends {
+		if !supports(b.provider) || !b.available(now) {
+			continue
+		}
+		if _, ok := groups[b.Priority]; !ok {
+			priorities = append(priorities, b.Priority)
+		}
+		groups[b.Priority] = append(groups[b.Priority], b)
+	}
+	sort.Ints(priorities)
+
+	var ordered []*backend
+	for _, prio := range priorities {
+		ordered = append(ordered, weightedShuffle(groups[prio])...)
+	}
+	if p.cfg.MaxAttempts > 0 && len(ordered) > p.cfg.MaxAttempts {
+		ordered = ordered[:p.cfg.MaxAttempts]
+	}
+	return ordered
+}
+
+// weightedShuffle 按权重做不放回随机抽样，权重越大越靠前的概率越高
+func weightedShuffle(items []*backend) []*backend {
+	if len(items) <= 1 {
+		return items
+	}
+	rest := append([]*backend(nil), items...)
+	result := make([]*backend, 0, len(items))
+	for len(rest) > 0 {
+		total := 0
+		for _, b := range rest {
+			total += b.Weight
+		}
+		n := rand.IntN(total)
+		for i, b := range rest {
+			if n < b.Weight {
+				result = append(result, b)
+				rest = append(rest[:i], rest[i+1:]...)
+				break
+			}
+			n -= b.Weight
+		}
+	}
+	return result
+}
+
+// route 依次在候选后端上执行 call，直到成功、遇到不可切换的错误或候选耗尽
+// canFailover 为 false 时（如流式调用已向调用方推送过数据）不再切换
+func (p *Provider) route(ctx context.Context, supports func(llm.Provider) bool, call func(b *backend) error, canFailover func() bool) (*backend, error) {
+	candidates := p.candidates(supports)
+	if len(candidates) == 0 {
+		return nil, ErrNoAvailableBackend
+	}
+
+	var lastErr error
+	for i, b := range candidates {
+		err := call(b)
+		if err == nil {
+			b.success()
+			return b, nil
+		}
+		if ctx.Err() != nil {
+			// 调用方取消或超时，不计入后端失败
+			return b, fmt.Errorf("router: backend %q: %w", b.Name, err)
+		}
+
+		// 已向调用方推送过数据时，错误可能来自调用方回调，既不切换也不计入熔断
+		if canFailover != nil && !canFailover() {
+			return b, fmt.Errorf("router: backend %q: %w", b.Name, err)
+		}
+		if !p.cfg.ShouldFailover(err) {
+			return b, fmt.Errorf("router: backend %q: %w", b.Name, err)
+		}
+		b.failure(err, p.cfg.FailureThreshold, p.cfg.Cooldown)
+		lastErr = fmt.Errorf("router: backend %q: %w", b.Name, err)
+		if i < len(candidates)-1 {
+			logger.WarnWithField(ctx, logTag, "backend failed, failing over", map[string]interface{}{
+				"backend": b.Name,
+				"next":    candidates[i+1].Name,
+				"error":   err.Error(),
+			})
+		}
+	}
+	return nil, fmt.Errorf("router: all backends failed: %w", lastErr)
+}
+
+func supportsChat(provider llm.Provider) bool {
+	_, ok := provider.(llm.Chatter)
+	return ok
+}
+
+func supportsEmbeddings(provider llm.Provider) bool {
+	_, ok := provider.(llm.Embedder)
+	return ok
+}
+
+// chatRequest 返回应用了后端模型覆盖的请求副本
+func (b *backend) chatRequest(req *llm.ChatRequest) *llm.ChatRequest {
+	if b.Model == "" {
+		return req
+	}
+	cp := *req
+	cp.Model = b.Model
+	return &cp
+}
+
+// Chat implements llm.Chatter
+func (p *Provider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	var resp *llm.ChatResponse
+	b, err := p.route(ctx, supportsChat, func(b *backend) error {
+		var err error
+		resp, err = b.provider.(llm.Chatter).Chat(ctx, b.chatRequest(req))
+		return err
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Backend = b.Name
+	return resp, nil
+}
+
+// ChatStream implements llm.Chatter
+// 仅在尚未向 callback 推送任何内容时切换后端，避免调用方收到两个后端拼接的内容
+func (p *Provider) ChatStream(ctx context.Context, req *llm.ChatRequest, callback func(chunk string) error) (*llm.ChatResponse, error) {
+	var (
+		resp    *llm.ChatResponse
+		emitted bool
+	)
+	b, err := p.route(ctx, supportsChat, func(b *backend) error {
+		var err error
+		resp, err = b.provider.(llm.Chatter).ChatStream(ctx, b.chatRequest(req), func(chunk string) error {
+			emitted = true
+			return callback(chunk)
+		})
+		return err
+	}, func() bool { return !emitted })
+	if err != nil {
+		return nil, err
+	}
+	resp.Backend = b.Name
+	return resp, nil
+}
+
+// ChatStreamEvents implements llm.EventStreamer
+// 与 ChatStream 一样，仅在尚未推送任何事件时切换后端；失败后端的 error 事件不会透传给 handler
+func (p *Provider) ChatStreamEvents(ctx context.Context, req *llm.ChatRequest, handler llm.StreamHandler) (*llm.ChatResponse, error) {
+	var (
+		resp    *llm.ChatResponse
+		emitted bool
+		current string
+	)
+	forward := func(event llm.StreamEvent) error {
+		switch event.Type {
+		case llm.StreamEventError:
+			return nil
+		case llm.StreamEventDone:
+			if event.Response != nil {
+				event.Response.Backend = current
+			}
+		}
+		emitted = true
+		return handler(event)
+	}
+	b, err := p.route(ctx, supportsChat, func(b *backend) error {
+		var err error
+		current = b.Name
+		breq := b.chatRequest(req)
+		if streamer, ok := b.provider.(llm.EventStreamer); ok {
+			resp, err = streamer.ChatStreamEvents(ctx, breq, forward)
+			return err
+		}
+		resp, err = b.provider.(llm.Chatter).ChatStream(ctx, breq, func(chunk string) error {
+			return forward(llm.StreamEvent{Type: llm.StreamEventTextDelta, Text: chunk})
+		})
+		if err != nil {
+			return err
+		}
+		resp.Backend = b.Name
+		return forward(llm.StreamEvent{Type: llm.StreamEventDone, FinishReason: resp.FinishReason, Response: resp})
+	}, func() bool { return !emitted })
+	if err != nil {
+		_ = handler(llm.StreamEvent{Type: llm.StreamEventError, Err: err})
+		return nil, err
+	}
+	resp.Backend = b.Name
+	return resp, nil
+}
+
+// CreateEmbeddings implements llm.Embedder
+func (p *Provider) CreateEmbeddings(ctx context.Context, req *llm.EmbeddingRequest) (*llm.EmbeddingResponse, error) {
+	var resp *llm.EmbeddingResponse
+	b, err := p.route(ctx, supportsEmbeddings, func(b *backend) error {
+		breq := req
+		if b.EmbeddingModel != "" {
+			cp := *req
+			cp.Model = b.EmbeddingModel
+			breq = &cp
+		}
+		var err error
+		resp, err = b.provider.(llm.Embedder).CreateEmbeddings(ctx, breq)
+		return err
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Backend = b.Name
+	return resp, nil
+}
@@ -0,0 +1,75 @@
+package router
+
+import (
+	"fmt"
+
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/spf13/cast"
+)
+
+const providerName = "router"
+
+func init() {
+	llm.Register(providerName, func(config interface{}) (llm.Provider, error) {
+		cfg, err := parseConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		return NewProvider(cfg)
+	})
+}
+
+// parseConfig 支持 Config 或 map（业务无需引用 router 即可用 map 配置）
+//
+// map 形式示例：
+//
+//	map[string]interface{}{
+//	    "failure_threshold": 3,
+//	    "cooldown":          "30s",
+//	    "backends": []interface{}{
+//	        map[string]interface{}{"provider": "openrouter", "priority": 0, "weight": 3, "config": map[string]interface{}{"api_key": "..."}},
+//	        map[string]interface{}{"provider": "gemini", "model": "gemini-2.0-flash", "priority": 1, "config": map[string]interface{}{"api_key": "..."}},
+//	    },
+//	}
+func parseConfig(config interface{}) (Config, error) {
+	if c, ok := config.(Config); ok {
+		return c, nil
+	}
+	if c, ok := config.(*Config); ok && c != nil {
+		return *c, nil
+	}
+	m, ok := config.(map[string]interface{})
+	if !ok {
+		return Config{}, fmt.Errorf("router: config must be of type router.Config or map[string]interface{}")
+	}
+
+	cfg := Config{
+		FailureThreshold: cast.ToInt(m["failure_threshold"]),
+		Cooldown:         cast.ToDuration(m["cooldown"]),
+		MaxAttempts:      cast.ToInt(m["max_attempts"]),
+	}
+	items, ok := m["backends"].([]interface{})
+	if !ok {
+		if typed, isTyped := m["backends"].([]map[string]interface{}); isTyped {
+			for _, t := range typed {
+				items = append(items, t)
+			}
+		}
+	}
+	for i, item := range items {
+		bm, err := cast.ToStringMapE(item)
+		if err != nil {
+			return Config{}, fmt.Errorf("router: backends[%d] must be a map: %w", i, err)
+		}
+		cfg.Backends = append(cfg.Backends, Backend{
+			Name:           cast.ToString(bm["name"]),
+			Provider:       cast.ToString(bm["provider"]),
+			Config:         bm["config"],
+			Model:          cast.ToString(bm["model"]),
+			EmbeddingModel: cast.ToString(bm["embedding_model"]),
+			Priority:       cast.ToInt(bm["priority"]),
+			Weight:         cast.ToInt(bm["weight"]),
+		})
+	}
+	return cfg, nil
+}
@@ -0,0 +1,229 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statusError 模拟携带 HTTP 状态码的 Provider 错误
+type statusError struct{ code int }
+
+func (e *statusError) Error() string   { return fmt.Sprintf("status %d", e.code) }
+func (e *statusError) StatusCode() int { return e.code }
+
+// fakeProvider 按配置返回固定结果或错误，并记录调用次数与收到的模型
+type fakeProvider struct {
+	name   string
+	err    error
+	chunks []string
+	calls  int
+	models []string
+}
+
+func (f *fakeProvider) Name() string { return f.name }
+
+func (f *fakeProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	f.calls++
+	f.models = append(f.models, req.Model)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &llm.ChatResponse{Content: "from " + f.name, FinishReason: "stop"}, nil
+}
+
+func (f *fakeProvider) ChatStream(ctx context.Context, req *llm.ChatRequest, callback func(chunk string) error) (*llm.ChatResponse, error) {
+	f.calls++
+	f.models = append(f.models, req.Model)
+	for _, c := range f.chunks {
+		if err := callback(c); err != nil {
+			return nil, err
+		}
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &llm.ChatResponse{Content: "from " + f.name, FinishReason: "stop"}, nil
+}
+
+func (f *fakeProvider) CreateEmbeddings(ctx context.Context, req *llm.EmbeddingRequest) (*llm.EmbeddingResponse, error) {
+	f.calls++
+	f.models = append(f.models, req.Model)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &llm.EmbeddingResponse{Model: req.Model}, nil
+}
+
+// registerFake 以唯一名称注册 fake Provider，返回注册名
+func registerFake(t *testing.T, f *fakeProvider) string {
+	name := fmt.Sprintf("router-test-%s-%s", t.Name(), f.name)
+	llm.Register(name, func(config interface{}) (llm.Provider, error) { return f, nil })
+	return name
+}
+
+func TestDefaultShouldFailover(t *testing.T) {
+	assert.True(t, DefaultShouldFailover(&statusError{429}))
+	assert.True(t, DefaultShouldFailover(fmt.Errorf("wrapped: %w", &statusError{503})))
+	assert.True(t, DefaultShouldFailover(&statusError{408}))
+	assert.False(t, DefaultShouldFailover(&statusError{400}))
+	assert.False(t, DefaultShouldFailover(context.Canceled))
+	assert.True(t, DefaultShouldFailover(context.DeadlineExceeded))
+	assert.True(t, DefaultShouldFailover(errors.New("connection reset")))
+
+	// *llm.Error 按分类判断
+	assert.True(t, DefaultShouldFailover(llm.NewError("openai", 401, errors.New("invalid api key"))))
+	assert.False(t, DefaultShouldFailover(llm.NewError("openai", 400, errors.New("This model's maximum context length is 8192 tokens"))))
+	assert.False(t, DefaultShouldFailover(&llm.Error{Category: llm.ErrorSafetyBlocked, Provider: "gemini", Status: 200}))
+	assert.True(t, DefaultShouldFailover(fmt.Errorf("router: backend %q: %w", "a", llm.NewError("anthropic", 529, errors.New("overloaded")))))
+}
+
+func TestRouter_Chat_FailoverByPriority(t *testing.T) {
+	primary := &fakeProvider{name: "primary", err: &statusError{429}}
+	secondary := &fakeProvider{name: "secondary"}
+	client, err := llm.NewClient(providerName, map[string]interface{}{
+		"backends": []interface{}{
+			map[string]interface{}{"name": "b", "provider": registerFake(t, secondary), "priority": 1, "model": "model-b"},
+			map[string]interface{}{"name": "a", "provider": registerFake(t, primary), "priority": 0},
+		},
+	})
+	require.NoError(t, err)
+
+	resp, err := client.Chat(context.Background(), &llm.ChatRequest{Model: "default"})
+	require.NoError(t, err)
+	assert.Equal(t, "from secondary", resp.Content)
+	assert.Equal(t, "b", resp.Backend)
+	assert.Equal(t, []string{"default"}, primary.models)
+	assert.Equal(t, []string{"model-b"}, secondary.models)
+}
+
+func TestRouter_Chat_NoFailoverOnClientError(t *testing.T) {
+	primary := &fakeProvider{name: "primary", err: &statusError{400}}
+	secondary := &fakeProvider{name: "secondary"}
+	p, err := NewProvider(Config{Backends: []Backend{
+		{Name: "a", Provider: registerFake(t, primary)},
+		{Name: "b", Provider: registerFake(t, secondary), Priority: 1},
+	}})
+	require.NoError(t, err)
+
+	_, err = p.Chat(context.Background(), &llm.ChatRequest{})
+	require.Error(t, err)
+	var sc *statusError
+	assert.True(t, errors.As(err, &sc))
+	assert.Equal(t, 0, secondary.calls)
+	assert.Equal(t, 0, p.Status()[0].ConsecutiveFailures)
+}
+
+func TestRouter_CircuitBreaker(t *testing.T) {
+	primary := &fakeProvider{name: "primary", err: errors.New("boom")}
+	secondary := &fakeProvider{name: "secondary"}
+	p, err := NewProvider(Config{
+		FailureThreshold: 2,
+		Cooldown:         50 * time.Millisecond,
+		Backends: []Backend{
+			{Name: "a", Provider: registerFake(t, primary)},
+			{Name: "b", Provider: registerFake(t, secondary), Priority: 1},
+		},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		resp, err := p.Chat(context.Background(), &llm.ChatRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, "b", resp.Backend)
+	}
+	// 第 2 次失败后熔断，第 3 次不再请求 primary
+	assert.Equal(t, 2, primary.calls)
+	assert.True(t, p.Status()[0].Open)
+
+	// 冷却期结束后半开，成功即恢复
+	time.Sleep(60 * time.Millisecond)
+	primary.err = nil
+	resp, err := p.Chat(context.Background(), &llm.ChatRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "a", resp.Backend)
+	assert.False(t, p.Status()[0].Open)
+	assert.Equal(t, 0, p.Status()[0].ConsecutiveFailures)
+
+	// 所有后端熔断
+	primary.err = errors.New("boom")
+	secondary.err = errors.New("boom")
+	for i := 0; i < 2; i++ {
+		_, err = p.Chat(context.Background(), &llm.ChatRequest{})
+		require.Error(t, err)
+	}
+	_, err = p.Chat(context.Background(), &llm.ChatRequest{})
+	assert.ErrorIs(t, err, ErrNoAvailableBackend)
+}
+
+func TestRouter_ChatStream_NoFailoverAfterOutput(t *testing.T) {
+	primary := &fakeProvider{name: "primary", chunks: []string{"partial"}, err: errors.New("stream broken")}
+	secondary := &fakeProvider{name: "secondary", chunks: []string{"full"}}
+	p, err := NewProvider(Config{Backends: []Backend{
+		{Name: "a", Provider: registerFake(t, primary)},
+		{Name: "b", Provider: registerFake(t, secondary), Priority: 1},
+	}})
+	require.NoError(t, err)
+
+	var got []string
+	_, err = p.ChatStream(context.Background(), &llm.ChatRequest{}, func(chunk string) error {
+		got = append(got, chunk)
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"partial"}, got)
+	assert.Equal(t, 0, secondary.calls)
+
+	// 首个后端未推送内容即失败时切换
+	primary.chunks = nil
+	var events []llm.StreamEvent
+	resp, err := p.ChatStreamEvents(context.Background(), &llm.ChatRequest{}, func(event llm.StreamEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "b", resp.Backend)
+	require.Len(t, events, 2)
+	assert.Equal(t, llm.StreamEventTextDelta, events[0].Type)
+	assert.Equal(t, "full", events[0].Text)
+	assert.Equal(t, llm.StreamEventDone, events[1].Type)
+	assert.Equal(t, "b", events[1].Response.Backend)
+}
+
+func TestRouter_CreateEmbeddings_WeightedBalance(t *testing.T) {
+	heavy := &fakeProvider{name: "heavy"}
+	light := &fakeProvider{name: "light"}
+	p, err := NewProvider(Config{Backends: []Backend{
+		{Name: "heavy", Provider: registerFake(t, heavy), Weight: 9, EmbeddingModel: "embed-heavy"},
+		{Name: "light", Provider: registerFake(t, light), Weight: 1},
+	}})
+	require.NoError(t, err)
+
+	for i := 0; i < 200; i++ {
+		resp, err := p.CreateEmbeddings(context.Background(), &llm.EmbeddingRequest{Model: "embed"})
+		require.NoError(t, err)
+		if resp.Backend == "heavy" {
+			assert.Equal(t, "embed-heavy", resp.Model)
+		} else {
+			assert.Equal(t, "embed", resp.Model)
+		}
+	}
+	assert.Equal(t, 200, heavy.calls+light.calls)
+	assert.Greater(t, heavy.calls, light.calls)
+	assert.Greater(t, light.calls, 0)
+}
+
+func TestNewProvider_Validation(t *testing.T) {
+	_, err := NewProvider(Config{})
+	assert.Error(t, err)
+	_, err = NewProvider(Config{Backends: []Backend{{Provider: "not-registered"}}})
+	assert.Error(t, err)
+	_, err = NewProvider(Config{Backends: []Backend{{Provider: providerName}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,35 @@
+// Package router 实现一个元 Provider：按优先级与权重把请求路由到多个底层 Provider，并在失败时自动切换
+package router
+
+import (
+	"time"
+)
+
+// Config router Provider 的配置
+type Config struct {
+	Backends         []Backend            `mapstructure:"backends"`          // 后端列表，至少一个
+	FailureThreshold int                  `mapstructure:"failure_threshold"` // 连续失败多少次触发熔断，默认 5
+	Cooldown         time.Duration        `mapstructure:"cooldown"`          // 熔断持续时间，到期后放行请求试探恢复，默认 30s
+	MaxAttempts      int                  `mapstructure:"max_attempts"`      // 单次调用最多尝试的后端数，0 表示尝试全部后端
+	ShouldFailover   func(err error) bool `mapstructure:"-"`                 // 判断错误是否需要切换后端，默认 DefaultShouldFailover
+}
+
+// Backend 单个后端配置
+type Backend struct {
+	Name           string      `mapstructure:"name"`            // 后端标识，会写入 ChatResponse.Backend，默认为 "provider/model"
+	Provider       string      `mapstructure:"provider"`        // 底层 Provider 名称，如 "openai"、"gemini"，需已注册
+	Config         interface{} `mapstructure:"config"`          // 底层 Provider 的配置，原样传给其工厂函数
+	Model          string      `mapstructure:"model"`           // 覆盖 ChatRequest.Model，为空时使用请求中的模型
+	EmbeddingModel string      `mapstructure:"embedding_model"` // 覆盖 EmbeddingRequest.Model，为空时使用请求中的模型
+	Priority       int         `mapstructure:"priority"`        // 优先级，数值越小越优先；同优先级内按权重随机
+	Weight         int         `mapstructure:"weight"`          // 同优先级内的权重，默认 1
+}
+
+// BackendStatus 后端的熔断状态
+type BackendStatus struct {
+	Name                string    `json:"name"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Open                bool      `json:"open"`                 // 熔断器是否处于打开状态
+	OpenUntil           time.Time `json:"open_until,omitempty"` // 熔断结束时间
+	LastError           string    `json:"last_error,omitempty"`
+}
@@ -0,0 +1,162 @@
+package llm
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// JSONSchemaOf 根据 v 的 Go 类型生成 JSON Schema（map 形式），可直接用作工具参数或结构化输出的 schema
+//
+// 生成规则：
+//   - 字段名取 json tag，json:"-" 的字段会被忽略，未导出字段会被忽略
+//   - 没有 omitempty 的字段视为必填（required）
+//   - description tag 作为字段描述，enum tag 以逗号分隔作为枚举值
+//   - time.Time 生成 {"type":"string","format":"date-time"}，interface{} 不做类型约束
+//   - 指针、切片、map 类型的字段可能序列化为 null，类型生成为 ["T","null"]，带 enum 时枚举值也会包含 null
+//
+// 示例：
+//
+//	type WeatherArgs struct {
+//	    City string `json:"city" description:"城市名"`
+//	    Unit string `json:"unit,omitempty" enum:"celsius,fahrenheit"`
+//	}
+//	schema := llm.JSONSchemaOf(WeatherArgs{})
+func JSONSchemaOf(v interface{}) map[string]interface{} {
+	if v == nil {
+		return map[string]interface{}{}
+	}
+	return schemaForType(reflect.TypeOf(v), make(map[reflect.Type]bool))
+}
+
+// schemaForType 递归生成 t 对应的 schema，visiting 用于防止递归类型导致无限展开
+func schemaForType(t reflect.Type, visiting map[reflect.Type]bool) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]interface{}{}
+	case t.Kind() != reflect.Struct && t.Implements(jsonMarshalerType):
+		// 自定义序列化的类型无法推断结构，不做约束
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte 在 JSON 中是 base64 字符串
+			return map[string]interface{}{"type": "string"}
+		}
+		return map[string]interface{}{"type": "array", "items": valueSchema(t.Elem(), visiting)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": valueSchema(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			return map[string]interface{}{"type": "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		return structSchema(t, visiting)
+	default:
+		// interface{} 等无法确定类型的字段不做约束
+		return map[string]interface{}{}
+	}
+}
+
+// valueSchema 生成字段、数组元素、map 值的 schema，nil 值会被序列化为 null 的类型允许 null
+func valueSchema(t reflect.Type, visiting map[reflect.Type]bool) map[string]interface{} {
+	schema := schemaForType(t, visiting)
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		if typ, ok := schema["type"].(string); ok {
+			schema["type"] = []string{typ, "null"}
+		}
+	}
+	return schema
+}
+
+// structSchema 生成结构体的 object schema，匿名嵌入的结构体字段会被展开到当前层级
+func structSchema(t reflect.Type, visiting map[reflect.Type]bool) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+	collectFields(t, visiting, properties, &required)
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func collectFields(t reflect.Type, visiting map[reflect.Type]bool, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				collectFields(ft, visiting, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := valueSchema(field.Type, visiting)
+		if desc := field.Tag.Get("description"); desc != "" {
+			prop["description"] = desc
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			values := strings.Split(enum, ",")
+			items := make([]interface{}, 0, len(values)+1)
+			for _, v := range values {
+				items = append(items, strings.TrimSpace(v))
+			}
+			if _, nullable := prop["type"].([]string); nullable {
+				items = append(items, nil)
+			}
+			prop["enum"] = items
+		}
+		properties[name] = prop
+
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StreamEventType 流式事件类型
+type StreamEventType string
+
+const (
+	StreamEventTextDelta      StreamEventType = "text_delta"      // 文本增量，Text 有效
+	StreamEventReasoningDelta StreamEventType = "reasoning_delta" // 推理（思考）增量，Text 有效
+	StreamEventToolCallDelta  StreamEventType = "tool_call_delta" // 工具调用片段，ToolCall 有效
+	StreamEventUsage          StreamEventType = "usage"           // token 用量更新，Usage 有效
+	StreamEventDone           StreamEventType = "done"            // 流结束，FinishReason 与 Response 有效
+	StreamEventError          StreamEventType = "error"           // 流异常结束，Err 有效
+)
+
+// StreamEvent 流式调用中推送的结构化事件
+type StreamEvent struct {
+	Type         StreamEventType
+	Text         string         // 文本或推理增量
+	ToolCall     *ToolCallDelta // 工具调用片段，同一 Index 的片段需按顺序拼接
+	Usage        *Usage         // 截至当前的 token 用量
+	FinishReason string         // 结束原因，仅 done 事件有效
+	Response     *ChatResponse  // 汇总后的完整响应，仅 done 事件有效
+	Err          error          // 错误信息，仅 error 事件有效
+}
+
+// StreamHandler 流式事件处理函数，返回错误会中断流式调用
+type StreamHandler func(event StreamEvent) error
+
+// TextCallback 将只关心文本的字符串回调适配为 StreamHandler，其余事件会被忽略
+func TextCallback(callback func(chunk string) error) StreamHandler {
+	return func(event StreamEvent) error {
+		if event.Type == StreamEventTextDelta {
+			return callback(event.Text)
+		}
+		return nil
+	}
+}
+
+// StreamAccumulator 供 Provider 实现流式调用使用：向 handler 推送事件，同时把增量汇总为最终的 ChatResponse
+type StreamAccumulator struct {
+	handler   StreamHandler
+	content   strings.Builder
+	reasoning strings.Builder
+	toolCalls ToolCallAccumulator
+	thinking  []ThinkingBlock
+	resp      ChatResponse
+}
+
+// NewStreamAccumulator 创建 StreamAccumulator，handler 为 nil 时只做汇总
+func NewStreamAccumulator(handler StreamHandler) *StreamAccumulator {
+	return &StreamAccumulator{handler: handler}
+}
+
+func (s *StreamAccumulator) emit(event StreamEvent) error {
+	if s.handler == nil {
+		return nil
+	}
+	if err := s.handler(event); err != nil {
+		return fmt.Errorf("callback error: %w", err)
+	}
+	return nil
+}
+
+// Text 追加文本增量，空串会被忽略
+func (s *StreamAccumulator) Text(delta string) error {
+	if delta == "" {
+		return nil
+	}
+	s.content.WriteString(delta)
+	return s.emit(StreamEvent{Type: StreamEventTextDelta, Text: delta})
+}
+
+// Reasoning 追加推理增量，空串会被忽略
+func (s *StreamAccumulator) Reasoning(delta string) error {
+	if delta == "" {
+		return nil
+	}
+	s.reasoning.WriteString(delta)
+	return s.emit(StreamEvent{Type: StreamEventReasoningDelta, Text: delta})
+}
+
+// Thinking 记录一个完整的思考块，只汇总到最终响应，不推送事件（思考文本通过 Reasoning 推送）
+func (s *StreamAccumulator) Thinking(block ThinkingBlock) {
+	s.thinking = append(s.thinking, block)
+}
+
+// ToolCall 追加工具调用片段
+func (s *StreamAccumulator) ToolCall(delta ToolCallDelta) error {
+	s.toolCalls.Add(delta)
+	return s.emit(StreamEvent{Type: StreamEventToolCallDelta, ToolCall: &delta})
+}
+
+// Usage 更新 token 用量
+func (s *StreamAccumulator) Usage(usage Usage) error {
+	s.resp.Usage = usage
+	return s.emit(StreamEvent{Type: StreamEventUsage, Usage: &usage})
+}
+
+// Finish 记录结束原因，可多次调用，以最后一次为准
+func (s *StreamAccumulator) Finish(reason string) {
+	if reason != "" {
+		s.resp.FinishReason = reason
+	}
+}
+
+// FinishReason 返回当前记录的结束原因
+func (s *StreamAccumulator) FinishReason() string {
+	return s.resp.FinishReason
+}
+
+// Done 汇总最终响应并推送 done 事件
+func (s *StreamAccumulator) Done() (*ChatResponse, error) {
+	resp := s.resp
+	resp.Content = s.content.String()
+	resp.Reasoning = s.reasoning.String()
+	resp.ToolCalls = s.toolCalls.ToolCalls()
+	resp.Thinking = s.thinking
+	if err := s.emit(StreamEvent{Type: StreamEventDone, FinishReason: resp.FinishReason, Response: &resp}); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Fail 推送 error 事件并原样返回 err，handler 的返回值会被忽略
+func (s *StreamAccumulator) Fail(err error) error {
+	if s.handler != nil {
+		_ = s.handler(StreamEvent{Type: StreamEventError, Err: err})
+	}
+	return err
+}
@@ -0,0 +1,317 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jessewkun/gocommon/utils"
+)
+
+// ErrInvalidStructuredOutput 模型多次重试后仍未返回符合 schema 的 JSON
+var ErrInvalidStructuredOutput = errors.New("llm: invalid structured output")
+
+var schemaNameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// Validator 结构化输出类型可实现该接口，在 schema 校验通过后做业务校验，返回的错误会反馈给模型重试
+type Validator interface {
+	Validate() error
+}
+
+// StructuredConfig ChatJSON 的配置
+type StructuredConfig struct {
+	MaxRetries  int    // 校验失败后的最大重试次数，默认 2；小于 0 表示不重试
+	Name        string // schema 名称，默认取类型名
+	Description string // schema 描述，可选
+	Strict      bool   // 是否开启 OpenAI strict 模式，要求 T 的所有字段均无 omitempty
+}
+
+// StructuredResult ChatJSON 的返回结果
+type StructuredResult[T any] struct {
+	Value    T             // 解析后的结果
+	Response *ChatResponse // 最后一次模型响应
+	Attempts int           // 实际请求模型的次数
+	Usage    Usage         // 所有请求累计的 token 用量
+}
+
+// ChatJSON 使用默认配置调用 ChatJSONWithConfig
+func ChatJSON[T any](ctx context.Context, client *Client, req *ChatRequest) (*StructuredResult[T], error) {
+	return ChatJSONWithConfig[T](ctx, client, req, StructuredConfig{})
+}
+
+// ChatJSONWithConfig 根据 T 生成 JSON Schema 并以结构化输出格式请求模型，校验返回内容后解析为 T
+// 校验失败时会把模型的回复与错误原因追加到对话中重新请求，最多重试 cfg.MaxRetries 次；req 本身不会被修改
+// 重试耗尽时返回最后一次的结果与 ErrInvalidStructuredOutput
+//
+// 示例：
+//
+//	type Sentiment struct {
+//	    Label string  `json:"label" enum:"positive,negative,neutral"`
+//	    Score float64 `json:"score" description:"0 到 1 之间的置信度"`
+//	}
+//	result, err := llm.ChatJSON[Sentiment](ctx, client, &llm.ChatRequest{Model: "gpt-4o-mini", Messages: msgs})
+//	fmt.Println(result.Value.Label)
+func ChatJSONWithConfig[T any](ctx context.Context, client *Client, req *ChatRequest, cfg StructuredConfig) (*StructuredResult[T], error) {
+	if req == nil {
+		return nil, fmt.Errorf("llm: chat json request cannot be nil")
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 2
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+
+	var zero T
+	schema := JSONSchemaOf(zero)
+	name := cfg.Name
+	if name == "" {
+		name = schemaName(reflect.TypeOf(zero))
+	}
+
+	attemptReq := *req
+	attemptReq.Messages = append([]Message(nil), req.Messages...)
+	attemptReq.JSONSchema = &JSONSchemaFormat{
+		Name:        name,
+		Description: cfg.Description,
+		Schema:      schema,
+		Strict:      cfg.Strict,
+	}
+
+	result := &StructuredResult[T]{}
+	var lastErr error
+	for i := 0; i <= cfg.MaxRetries; i++ {
+		resp, err := client.Chat(ctx, &attemptReq)
+		if err != nil {
+			return result, err
+		}
+		result.Attempts++
+		result.Response = resp
+		result.Usage.PromptTokens += resp.Usage.PromptTokens
+		result.Usage.CompletionTokens += resp.Usage.CompletionTokens
+		result.Usage.TotalTokens += resp.Usage.TotalTokens
+
+		value, err := decodeStructured[T](resp.Content, schema)
+		if err == nil {
+			result.Value = value
+			return result, nil
+		}
+		lastErr = err
+		attemptReq.Messages = append(attemptReq.Messages,
+			Message{Role: "assistant", Content: resp.Content},
+			Message{Role: "user", Content: fmt.Sprintf(
+				"Your previous reply is invalid: %s. Reply again with only a JSON value that conforms to the required schema, without any explanation or markdown.",
+				err.Error())},
+		)
+	}
+	return result, fmt.Errorf("%w after %d attempts: %v", ErrInvalidStructuredOutput, result.Attempts, lastErr)
+}
+
+// decodeStructured 去除 markdown 代码块后按 schema 校验并解析为 T
+func decodeStructured[T any](content string, schema map[string]interface{}) (T, error) {
+	var value T
+	text := utils.TrimMarkdownCodeBlock(content)
+	if text == "" {
+		return value, fmt.Errorf("empty reply")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(text)))
+	decoder.UseNumber()
+	var raw interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return value, fmt.Errorf("reply is not valid JSON: %v", err)
+	}
+	if err := ValidateJSONSchema(schema, raw); err != nil {
+		return value, err
+	}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return value, fmt.Errorf("reply does not match the expected type: %v", err)
+	}
+	if v, ok := any(&value).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return value, err
+		}
+	} else if v, ok := any(value).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return value, err
+		}
+	}
+	return value, nil
+}
+
+// schemaName 根据类型名生成 schema 名称
+func schemaName(t reflect.Type) string {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	name := ""
+	if t != nil {
+		name = schemaNameInvalidChars.ReplaceAllString(t.Name(), "_")
+	}
+	if name == "" {
+		return "response"
+	}
+	return name
+}
+
+// ValidateJSONSchema 按 schema 校验 v（由 encoding/json 解码得到的值），支持 JSONSchemaOf 生成的关键字子集：
+// type、properties、required、additionalProperties、items、enum
+func ValidateJSONSchema(schema map[string]interface{}, v interface{}) error {
+	return validateSchema(schema, v, "$")
+}
+
+func validateSchema(schema map[string]interface{}, v interface{}, path string) error {
+	if len(schema) == 0 {
+		return nil
+	}
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		if err := checkType(types, v, path); err != nil {
+			return err
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		matched := false
+		for _, e := range enum {
+			if fmt.Sprint(e) == fmt.Sprint(v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s must be one of %v, got %v", path, enum, v)
+		}
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		for _, name := range stringList(schema["required"]) {
+			if _, ok := val[name]; !ok {
+				return fmt.Errorf("%s.%s is required", path, name)
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if propSchema, ok := properties[k].(map[string]interface{}); ok {
+				if err := validateSchema(propSchema, val[k], path+"."+k); err != nil {
+					return err
+				}
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra && properties != nil {
+					return fmt.Errorf("%s.%s is not allowed", path, k)
+				}
+			case map[string]interface{}:
+				if err := validateSchema(extra, val[k], path+"."+k); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range val {
+				if err := validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkType 校验 v 是否匹配 types 中的任一类型，types 对应 schema 的 type，可以是单个类型或类型数组（如 ["string","null"]）
+func checkType(types []string, v interface{}, path string) error {
+	for _, typ := range types {
+		if matchType(typ, v) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s must be of type %s, got %s", path, strings.Join(types, " or "), describeJSONValue(v))
+}
+
+func matchType(typ string, v interface{}) bool {
+	ok := false
+	switch typ {
+	case "object":
+		_, ok = v.(map[string]interface{})
+	case "array":
+		_, ok = v.([]interface{})
+	case "string":
+		_, ok = v.(string)
+	case "boolean":
+		_, ok = v.(bool)
+	case "number":
+		switch v.(type) {
+		case json.Number, float64:
+			ok = true
+		}
+	case "integer":
+		switch n := v.(type) {
+		case json.Number:
+			_, err := n.Int64()
+			ok = err == nil
+		case float64:
+			ok = n == float64(int64(n))
+		}
+	case "null":
+		ok = v == nil
+	default:
+		ok = true
+	}
+	return ok
+}
+
+// schemaTypes 返回 schema 的 type，兼容字符串与字符串数组两种写法
+func schemaTypes(v interface{}) []string {
+	if s, ok := v.(string); ok {
+		return []string{s}
+	}
+	return stringList(v)
+}
+
+func stringList(v interface{}) []string {
+	switch r := v.(type) {
+	case []string:
+		return r
+	case []interface{}:
+		out := make([]string, 0, len(r))
+		for _, item := range r {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func describeJSONValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number, float64:
+		return "number " + strings.TrimSpace(fmt.Sprint(val))
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
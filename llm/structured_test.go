@@ -0,0 +1,119 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sentiment struct {
+	Label  string   `json:"label" enum:"positive,negative,neutral"`
+	Score  float64  `json:"score"`
+	Topics []string `json:"topics,omitempty"`
+}
+
+func (s sentiment) Validate() error {
+	if s.Score < 0 || s.Score > 1 {
+		return errors.New("score must be between 0 and 1")
+	}
+	return nil
+}
+
+func TestChatJSON_RetryWithValidationError(t *testing.T) {
+	chatter := &scriptedChatter{responses: []*ChatResponse{
+		{Content: "```json\n{\"label\": \"happy\", \"score\": 0.9}\n```", Usage: Usage{TotalTokens: 3}},
+		{Content: `{"label": "positive", "score": 1.5}`, Usage: Usage{TotalTokens: 3}},
+		{Content: "```json\n{\"label\": \"positive\", \"score\": 0.9, \"topics\": [\"food\"]}\n```", Usage: Usage{TotalTokens: 3}},
+	}}
+	req := &ChatRequest{Model: "test", Messages: []Message{{Role: "user", Content: "I love this place"}}}
+
+	result, err := ChatJSON[sentiment](context.Background(), &Client{provider: chatter}, req)
+	require.NoError(t, err)
+	assert.Equal(t, sentiment{Label: "positive", Score: 0.9, Topics: []string{"food"}}, result.Value)
+	assert.Equal(t, 3, result.Attempts)
+	assert.Equal(t, 9, result.Usage.TotalTokens)
+	assert.Len(t, req.Messages, 1, "original request must not be modified")
+
+	first := chatter.requests[0]
+	require.NotNil(t, first.JSONSchema)
+	assert.Equal(t, "sentiment", first.JSONSchema.Name)
+	assert.Equal(t, []string{"label", "score"}, first.JSONSchema.Schema["required"])
+
+	// 每次重试都会带上上一次的回复与错误原因
+	second := chatter.requests[1].Messages
+	require.Len(t, second, 3)
+	assert.Equal(t, "assistant", second[1].Role)
+	assert.Contains(t, second[2].Content, "$.label must be one of")
+	third := chatter.requests[2].Messages
+	require.Len(t, third, 5)
+	assert.Contains(t, third[4].Content, "score must be between 0 and 1")
+}
+
+func TestChatJSON_MaxRetriesExceeded(t *testing.T) {
+	chatter := &scriptedChatter{responses: []*ChatResponse{
+		{Content: "not json"},
+		{Content: `{"label": "neutral"}`},
+	}}
+	result, err := ChatJSONWithConfig[sentiment](context.Background(), &Client{provider: chatter},
+		&ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}}, StructuredConfig{MaxRetries: 1, Name: "mood"})
+	assert.ErrorIs(t, err, ErrInvalidStructuredOutput)
+	assert.Contains(t, err.Error(), "$.score is required")
+	assert.Equal(t, 2, result.Attempts)
+	assert.Equal(t, "mood", chatter.requests[0].JSONSchema.Name)
+}
+
+func TestValidateJSONSchema(t *testing.T) {
+	schema := JSONSchemaOf(struct {
+		ID    int               `json:"id"`
+		Tags  []string          `json:"tags"`
+		Attrs map[string]string `json:"attrs,omitempty"`
+	}{})
+
+	assert.NoError(t, ValidateJSONSchema(schema, map[string]interface{}{"id": float64(1), "tags": []interface{}{"a"}}))
+	assert.EqualError(t, ValidateJSONSchema(schema, map[string]interface{}{"id": 1.5, "tags": []interface{}{}}),
+		"$.id must be of type integer, got number 1.5")
+	assert.EqualError(t, ValidateJSONSchema(schema, map[string]interface{}{"id": float64(1), "tags": []interface{}{2.0}}),
+		"$.tags[0] must be of type string, got number 2")
+	assert.EqualError(t, ValidateJSONSchema(schema, map[string]interface{}{"id": float64(1), "tags": []interface{}{}, "extra": true}),
+		"$.extra is not allowed")
+	assert.EqualError(t, ValidateJSONSchema(schema, map[string]interface{}{"id": float64(1), "tags": []interface{}{}, "attrs": map[string]interface{}{"k": 1.0}}),
+		"$.attrs.k must be of type string, got number 1")
+}
+
+type shipment struct {
+	ID       string            `json:"id"`
+	Note     *string           `json:"note"`
+	Priority *string           `json:"priority" enum:"low,high"`
+	Items    []string          `json:"items"`
+	Meta     map[string]string `json:"meta"`
+}
+
+func TestChatJSON_NullableFields(t *testing.T) {
+	schema := JSONSchemaOf(shipment{})
+	props := schema["properties"].(map[string]interface{})
+	assert.Equal(t, "string", props["id"].(map[string]interface{})["type"])
+	assert.Equal(t, []string{"string", "null"}, props["note"].(map[string]interface{})["type"])
+	assert.Equal(t, []interface{}{"low", "high", nil}, props["priority"].(map[string]interface{})["enum"])
+	assert.Equal(t, []string{"array", "null"}, props["items"].(map[string]interface{})["type"])
+	assert.Equal(t, []string{"object", "null"}, props["meta"].(map[string]interface{})["type"])
+
+	// 类型数组也可能来自 JSON 解码后的 schema
+	decoded := map[string]interface{}{"type": []interface{}{"integer", "null"}}
+	assert.NoError(t, ValidateJSONSchema(decoded, nil))
+	assert.EqualError(t, ValidateJSONSchema(decoded, "1"), "$ must be of type integer or null, got string")
+
+	// 零值结构体序列化后指针、切片、map 字段均为 null，必须能通过校验
+	chatter := &scriptedChatter{responses: []*ChatResponse{
+		{Content: `{"id": "s1", "note": 1, "priority": null, "items": null, "meta": null}`},
+		{Content: `{"id": "s1", "note": null, "priority": null, "items": null, "meta": null}`},
+	}}
+	result, err := ChatJSON[shipment](context.Background(), &Client{provider: chatter},
+		&ChatRequest{Messages: []Message{{Role: "user", Content: "track s1"}}})
+	require.NoError(t, err)
+	assert.Equal(t, shipment{ID: "s1"}, result.Value)
+	assert.Equal(t, 2, result.Attempts)
+	assert.Contains(t, chatter.requests[1].Messages[2].Content, "$.note must be of type string or null, got number 1")
+}
@@ -0,0 +1,180 @@
+package llm
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer 按模型家族近似估算 token 数
+// 不加载真实词表，按字符类别估算：英文等 ASCII 单词按 CharsPerToken 折算，中日韩字符按 CJKTokensPerChar 折算，
+// 其余符号按 1 个 token 计。误差通常在 10%~20% 以内，用于预算控制时应预留余量
+type Tokenizer struct {
+	Family           string  // 模型家族，如 openai、anthropic、gemini
+	CharsPerToken    float64 // ASCII 单词平均每个 token 的字符数
+	CJKTokensPerChar float64 // 每个中日韩字符的 token 数
+	MessageOverhead  int     // 每条消息的固定开销（角色、分隔符等）
+	ReplyOverhead    int     // 整个对话的固定开销（回复起始标记等）
+	ImageTokens      int     // 每张图片的估算 token 数
+}
+
+// 内置的模型家族
+var (
+	TokenizerOpenAI    = &Tokenizer{Family: "openai", CharsPerToken: 4, CJKTokensPerChar: 1, MessageOverhead: 3, ReplyOverhead: 3, ImageTokens: 765}
+	TokenizerAnthropic = &Tokenizer{Family: "anthropic", CharsPerToken: 3.5, CJKTokensPerChar: 1.3, MessageOverhead: 4, ReplyOverhead: 3, ImageTokens: 1600}
+	TokenizerGemini    = &Tokenizer{Family: "gemini", CharsPerToken: 4, CJKTokensPerChar: 0.8, MessageOverhead: 2, ReplyOverhead: 2, ImageTokens: 258}
+	TokenizerLlama     = &Tokenizer{Family: "llama", CharsPerToken: 4, CJKTokensPerChar: 1.1, MessageOverhead: 4, ReplyOverhead: 3, ImageTokens: 1600}
+	TokenizerQwen      = &Tokenizer{Family: "qwen", CharsPerToken: 4, CJKTokensPerChar: 0.7, MessageOverhead: 4, ReplyOverhead: 3, ImageTokens: 1024}
+	TokenizerDeepSeek  = &Tokenizer{Family: "deepseek", CharsPerToken: 4, CJKTokensPerChar: 0.7, MessageOverhead: 4, ReplyOverhead: 3, ImageTokens: 1024}
+)
+
+// modelFamilies 模型名关键字到模型家族的映射，按顺序匹配
+var modelFamilies = []struct {
+	keywords  []string
+	tokenizer *Tokenizer
+}{
+	{[]string{"claude"}, TokenizerAnthropic},
+	{[]string{"gemini", "gemma"}, TokenizerGemini},
+	{[]string{"qwen", "qwq"}, TokenizerQwen},
+	{[]string{"deepseek"}, TokenizerDeepSeek},
+	{[]string{"llama", "mistral", "mixtral"}, TokenizerLlama},
+	{[]string{"gpt", "o1", "o3", "o4", "text-embedding", "davinci"}, TokenizerOpenAI},
+}
+
+// providerFamilies 模型名无法识别时，按 Provider 名称选择模型家族
+var providerFamilies = map[string]*Tokenizer{
+	"openai":    TokenizerOpenAI,
+	"anthropic": TokenizerAnthropic,
+	"gemini":    TokenizerGemini,
+	"ollama":    TokenizerLlama,
+}
+
+// TokenizerFor 根据 Provider 名称与模型名选择模型家族，优先按模型名识别（支持 openrouter 的 "vendor/model" 形式），都无法识别时使用 openai
+func TokenizerFor(provider, model string) *Tokenizer {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, f := range modelFamilies {
+		for _, kw := range f.keywords {
+			if strings.HasPrefix(name, kw) || strings.Contains(name, "-"+kw) || strings.Contains(name, kw+"-") {
+				return f.tokenizer
+			}
+		}
+	}
+	if t, ok := providerFamilies[provider]; ok {
+		return t
+	}
+	return TokenizerOpenAI
+}
+
+// CountText 估算文本的 token 数
+func (t *Tokenizer) CountText(s string) int {
+	var tokens float64
+	word := 0 // 当前单词的字节数
+	flush := func() {
+		if word > 0 {
+			tokens += math.Max(1, math.Round(float64(word)/t.CharsPerToken))
+			word = 0
+		}
+	}
+	for _, r := range s {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			word++
+		case isCJK(r):
+			flush()
+			tokens += t.CJKTokensPerChar
+		case unicode.IsSpace(r):
+			flush()
+		case r < utf8.RuneSelf:
+			// ASCII 标点与符号
+			flush()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			// 带重音的拉丁字母、西里尔字母等，按 UTF-8 字节数计入单词
+			word += utf8.RuneLen(r)
+		default:
+			// emoji 等符号通常被拆成多个字节级 token
+			flush()
+			tokens += float64(utf8.RuneLen(r)) / 2
+		}
+	}
+	flush()
+	return int(math.Ceil(tokens))
+}
+
+// CountMessages 估算一组消息的 token 数，包含每条消息与整个对话的固定开销
+func (t *Tokenizer) CountMessages(msgs []Message) int {
+	if len(msgs) == 0 {
+		return 0
+	}
+	total := t.ReplyOverhead
+	for _, m := range msgs {
+		total += t.CountMessage(m)
+	}
+	return total
+}
+
+// CountMessage 估算单条消息的 token 数，不含整个对话的固定开销
+func (t *Tokenizer) CountMessage(m Message) int {
+	total := t.MessageOverhead + t.countContent(m.Content)
+	if m.Name != "" {
+		total += t.CountText(m.Name)
+	}
+	for _, tc := range m.ToolCalls {
+		total += t.MessageOverhead + t.CountText(tc.Function.Name) + t.CountText(tc.Function.Arguments)
+	}
+	return total
+}
+
+// CountRequest 估算对话请求的输入 token 数，包含消息与工具定义
+func (t *Tokenizer) CountRequest(req *ChatRequest) int {
+	total := t.CountMessages(req.Messages)
+	if len(req.Tools) > 0 {
+		if b, err := json.Marshal(req.Tools); err == nil {
+			total += t.CountText(string(b))
+		}
+	}
+	return total
+}
+
+func (t *Tokenizer) countContent(content interface{}) int {
+	var parts []map[string]interface{}
+	switch c := content.(type) {
+	case []map[string]interface{}:
+		parts = c
+	case []interface{}:
+		for _, p := range c {
+			if part, ok := p.(map[string]interface{}); ok {
+				parts = append(parts, part)
+			}
+		}
+	default:
+		return t.CountText(ContentString(content))
+	}
+	total := 0
+	for _, part := range parts {
+		switch part["type"] {
+		case "text":
+			text, _ := part["text"].(string)
+			total += t.CountText(text)
+		case "image_url":
+			total += t.ImageTokens
+		}
+	}
+	return total
+}
+
+// CountTokens 使用 TokenizerFor(provider, model) 估算一组消息的 token 数
+func CountTokens(provider, model string, msgs []Message) int {
+	return TokenizerFor(provider, model).CountMessages(msgs)
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) ||
+		(r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF) // 中日韩标点与全角字符
+}
@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenizerFor(t *testing.T) {
+	tests := []struct {
+		provider, model string
+		want            *Tokenizer
+	}{
+		{"openai", "gpt-4o-mini", TokenizerOpenAI},
+		{"openai", "o3-mini", TokenizerOpenAI},
+		{"openrouter", "anthropic/claude-3.5-sonnet", TokenizerAnthropic},
+		{"openrouter", "qwen/qwen-2.5-72b-instruct", TokenizerQwen},
+		{"openai", "deepseek-chat", TokenizerDeepSeek},
+		{"gemini", "gemini-1.5-pro", TokenizerGemini},
+		{"ollama", "llama3", TokenizerLlama},
+		{"anthropic", "unknown-model", TokenizerAnthropic},
+		{"custom", "unknown-model", TokenizerOpenAI},
+	}
+	for _, tt := range tests {
+		assert.Same(t, tt.want, TokenizerFor(tt.provider, tt.model), "%s/%s", tt.provider, tt.model)
+	}
+}
+
+func TestTokenizer_CountText(t *testing.T) {
+	tk := TokenizerOpenAI
+	assert.Equal(t, 0, tk.CountText(""))
+	assert.Equal(t, 2, tk.CountText("hello world"))
+	assert.Equal(t, 5, tk.CountText("internationalization"), "long words are split by CharsPerToken")
+	assert.Equal(t, 3, tk.CountText("hi, there"), "punctuation counts as one token")
+	assert.Equal(t, 4, tk.CountText("你好世界"))
+	assert.Equal(t, 3, TokenizerQwen.CountText("你好世界"), "qwen encodes CJK more compactly")
+}
+
+func TestTokenizer_CountMessages(t *testing.T) {
+	tk := TokenizerOpenAI
+	assert.Equal(t, 0, tk.CountMessages(nil))
+
+	msgs := []Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: []interface{}{
+			map[string]interface{}{"type": "text", "text": "what is this"},
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "https://example.com/a.png"}},
+		}},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "1", Type: "function", Function: FunctionCall{Name: "lookup", Arguments: `{"q":"x"}`}}}},
+	}
+	// reply 3 + system (3+2) + user (3+3+765) + assistant (3 + 3+2+9)
+	assert.Equal(t, 796, tk.CountMessages(msgs))
+	assert.Equal(t, 796, CountTokens("openai", "gpt-4o", msgs))
+
+	req := &ChatRequest{Messages: msgs, Tools: []map[string]interface{}{{"type": "function"}}}
+	assert.Greater(t, tk.CountRequest(req), 796)
+}
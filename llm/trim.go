@@ -0,0 +1,187 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrContextTooLong 裁剪后的对话仍超出 token 预算（通常是系统提示词或最后一轮对话本身过长）
+var ErrContextTooLong = errors.New("llm: context exceeds token budget")
+
+// Summarizer 将被裁剪掉的历史消息压缩为一段摘要
+type Summarizer func(ctx context.Context, msgs []Message) (string, error)
+
+// TrimConfig TrimMessages 的配置
+type TrimConfig struct {
+	MaxTokens     int        // 裁剪后对话的 token 上限，必填
+	Tokenizer     *Tokenizer // token 估算方式，默认 TokenizerOpenAI
+	Summarizer    Summarizer // 可选，设置后被裁剪的历史会被压缩为摘要，追加到系统提示词末尾
+	SummaryPrefix string     // 摘要消息的前缀，默认 "以下是之前对话的摘要："
+}
+
+// TrimMessages 裁剪对话使其估算 token 数不超过 cfg.MaxTokens，msgs 本身不会被修改
+// 裁剪规则：
+//   - 开头连续的 system 消息视为系统提示词，始终保留
+//   - 其余消息以 user 消息为起点划分为轮次，按轮次整体保留或丢弃，避免拆散工具调用与其结果
+//   - 从最新的轮次向前保留，直到预算用尽；最后一轮始终保留
+//   - 设置了 Summarizer 时，被丢弃的轮次会被压缩为摘要；摘要放不下时会继续丢弃更早的轮次并重新生成摘要
+//
+// 裁剪后仍超出预算时返回裁剪结果与 ErrContextTooLong
+func TrimMessages(ctx context.Context, msgs []Message, cfg TrimConfig) ([]Message, error) {
+	if cfg.MaxTokens <= 0 {
+		return nil, fmt.Errorf("llm: trim max tokens must be positive")
+	}
+	if cfg.Tokenizer == nil {
+		cfg.Tokenizer = TokenizerOpenAI
+	}
+	if cfg.SummaryPrefix == "" {
+		cfg.SummaryPrefix = "以下是之前对话的摘要："
+	}
+	tk := cfg.Tokenizer
+	if tk.CountMessages(msgs) <= cfg.MaxTokens {
+		return append([]Message(nil), msgs...), nil
+	}
+
+	// 开头连续的 system 消息视为系统提示词
+	n := 0
+	for n < len(msgs) && msgs[n].Role == "system" {
+		n++
+	}
+	system := msgs[:n:n]
+	turns := splitTurns(msgs[n:])
+
+	budget := cfg.MaxTokens - tk.CountMessages(system)
+	if len(system) == 0 {
+		budget -= tk.ReplyOverhead
+	}
+	// keep 为保留的第一个轮次下标，从最新的轮次向前累加
+	keep := len(turns)
+	for keep > 0 {
+		n := countTurn(tk, turns[keep-1])
+		if keep < len(turns) && n > budget {
+			break
+		}
+		budget -= n
+		keep--
+	}
+
+	kept := flattenTurns(turns[keep:])
+	if keep == 0 || cfg.Summarizer == nil {
+		return assembleTrimmed(tk, cfg.MaxTokens, system, "", kept)
+	}
+
+	// 摘要本身也占预算，放不下时把更早的保留轮次并入待摘要部分，至少保留最后一轮
+	for {
+		text, err := cfg.Summarizer(ctx, flattenTurns(turns[:keep]))
+		if err != nil {
+			return nil, fmt.Errorf("llm: summarize messages: %w", err)
+		}
+		summary := cfg.SummaryPrefix + strings.TrimSpace(text)
+		result := insertSummary(system, summary, kept)
+		if tk.CountMessages(result) <= cfg.MaxTokens || keep >= len(turns)-1 {
+			return assembleTrimmed(tk, cfg.MaxTokens, system, summary, kept)
+		}
+		keep++
+		kept = flattenTurns(turns[keep:])
+	}
+}
+
+// ClientSummarizer 返回使用 client 与指定模型生成摘要的 Summarizer
+func ClientSummarizer(client *Client, model string) Summarizer {
+	return func(ctx context.Context, msgs []Message) (string, error) {
+		var b strings.Builder
+		for _, m := range msgs {
+			text := ContentString(m.Content)
+			for _, tc := range m.ToolCalls {
+				text += fmt.Sprintf("\n[调用工具 %s: %s]", tc.Function.Name, tc.Function.Arguments)
+			}
+			if text == "" {
+				continue
+			}
+			fmt.Fprintf(&b, "%s: %s\n", m.Role, text)
+		}
+		resp, err := client.Chat(ctx, &ChatRequest{
+			Model: model,
+			Messages: []Message{
+				{Role: "system", Content: "请将以下对话压缩为一段简洁的摘要，保留关键事实、用户诉求、已做出的决定与未完成的事项，不要添加对话中没有的信息。"},
+				{Role: "user", Content: b.String()},
+			},
+		})
+		if err != nil {
+			return "", err
+		}
+		return resp.Content, nil
+	}
+}
+
+// splitTurns 以 user 消息为起点将消息划分为轮次，开头不以 user 开始的消息单独成为一轮
+func splitTurns(msgs []Message) [][]Message {
+	var turns [][]Message
+	for _, m := range msgs {
+		if m.Role == "user" || len(turns) == 0 {
+			turns = append(turns, nil)
+		}
+		turns[len(turns)-1] = append(turns[len(turns)-1], m)
+	}
+	return turns
+}
+
+func flattenTurns(turns [][]Message) []Message {
+	var msgs []Message
+	for _, t := range turns {
+		msgs = append(msgs, t...)
+	}
+	return msgs
+}
+
+func countTurn(tk *Tokenizer, turn []Message) int {
+	n := 0
+	for _, m := range turn {
+		n += tk.CountMessage(m)
+	}
+	return n
+}
+
+// insertSummary 按原有顺序拼接系统提示词、摘要与保留的消息
+// 摘要追加到最后一条系统提示词的末尾而不是作为单独的 system 消息，gemini 等 Provider 只使用第一条 system 消息；
+// 没有系统提示词时摘要作为第一条 system 消息
+func insertSummary(system []Message, summary string, kept []Message) []Message {
+	result := make([]Message, 0, len(system)+len(kept)+1)
+	result = append(result, system...)
+	switch {
+	case summary == "":
+	case len(result) == 0:
+		result = append(result, Message{Role: "system", Content: summary})
+	default:
+		last := &result[len(result)-1]
+		last.Content = appendText(last.Content, summary)
+	}
+	return append(result, kept...)
+}
+
+// appendText 在消息内容末尾追加一段文本，多模态内容追加一个 text 部分，不修改原内容
+func appendText(content interface{}, text string) interface{} {
+	switch c := content.(type) {
+	case nil:
+		return text
+	case string:
+		if c == "" {
+			return text
+		}
+		return c + "\n\n" + text
+	case []interface{}:
+		return append(c[:len(c):len(c)], map[string]interface{}{"type": "text", "text": text})
+	default:
+		return ContentString(c) + "\n\n" + text
+	}
+}
+
+func assembleTrimmed(tk *Tokenizer, maxTokens int, system []Message, summary string, kept []Message) ([]Message, error) {
+	result := insertSummary(system, summary, kept)
+	if n := tk.CountMessages(result); n > maxTokens {
+		return result, fmt.Errorf("%w: %d > %d", ErrContextTooLong, n, maxTokens)
+	}
+	return result, nil
+}
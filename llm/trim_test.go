@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wordTokenizer 每个单词计 1 个 token，每条消息额外计 1 个，便于断言
+var wordTokenizer = &Tokenizer{Family: "test", CharsPerToken: 100, CJKTokensPerChar: 1, MessageOverhead: 1}
+
+func trimConversation() []Message {
+	return []Message{
+		{Role: "system", Content: "you are helpful"},  // 4
+		{Role: "user", Content: "one two three"},      // 4
+		{Role: "assistant", Content: "four five six"}, // 4
+		{Role: "user", Content: "weather in paris"},   // 4
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "1", Function: FunctionCall{Name: "weather", Arguments: "paris"}}}}, // 4
+		{Role: "tool", ToolCallID: "1", Content: "sunny"},                                                                  // 2
+		{Role: "assistant", Content: "it is sunny"},                                                                        // 4
+		{Role: "user", Content: "thanks"},                                                                                  // 2
+	}
+}
+
+func TestTrimMessages_Drop(t *testing.T) {
+	msgs := trimConversation()
+	require.Equal(t, 28, wordTokenizer.CountMessages(msgs))
+
+	result, err := TrimMessages(context.Background(), msgs, TrimConfig{MaxTokens: 100, Tokenizer: wordTokenizer})
+	require.NoError(t, err)
+	assert.Equal(t, msgs, result)
+
+	// 工具调用所在的轮次整体保留，第一轮被丢弃
+	result, err = TrimMessages(context.Background(), msgs, TrimConfig{MaxTokens: 20, Tokenizer: wordTokenizer})
+	require.NoError(t, err)
+	assert.Equal(t, append(msgs[:1:1], msgs[3:]...), result)
+	assert.Len(t, msgs, 8, "original messages must not be modified")
+
+	// 预算只够最后一轮
+	result, err = TrimMessages(context.Background(), msgs, TrimConfig{MaxTokens: 10, Tokenizer: wordTokenizer})
+	require.NoError(t, err)
+	assert.Equal(t, []Message{msgs[0], msgs[7]}, result)
+
+	// 系统提示词与最后一轮始终保留
+	result, err = TrimMessages(context.Background(), msgs, TrimConfig{MaxTokens: 5, Tokenizer: wordTokenizer})
+	assert.ErrorIs(t, err, ErrContextTooLong)
+	assert.Equal(t, []Message{msgs[0], msgs[7]}, result)
+}
+
+func TestTrimMessages_Summarize(t *testing.T) {
+	msgs := trimConversation()
+	var summarized [][]Message
+	summarizer := func(ctx context.Context, dropped []Message) (string, error) {
+		summarized = append(summarized, dropped)
+		return strings.Repeat("x ", len(dropped)), nil
+	}
+
+	result, err := TrimMessages(context.Background(), msgs, TrimConfig{MaxTokens: 25, Tokenizer: wordTokenizer, Summarizer: summarizer, SummaryPrefix: "summary: "})
+	require.NoError(t, err)
+	require.Len(t, summarized, 1)
+	assert.Equal(t, msgs[1:3], summarized[0])
+	require.Len(t, result, 6)
+	assert.Equal(t, Message{Role: "system", Content: "you are helpful\n\nsummary: x x"}, result[0])
+	assert.Equal(t, msgs[3:], result[1:])
+	assert.Equal(t, "you are helpful", msgs[0].Content, "original messages must not be modified")
+
+	// 摘要放不下时继续丢弃更早的轮次并重新生成摘要
+	summarized = nil
+	result, err = TrimMessages(context.Background(), msgs, TrimConfig{MaxTokens: 20, Tokenizer: wordTokenizer, Summarizer: summarizer, SummaryPrefix: "summary: "})
+	require.NoError(t, err)
+	require.Len(t, summarized, 2)
+	assert.Equal(t, msgs[1:7], summarized[1])
+	assert.Equal(t, []Message{{Role: "system", Content: "you are helpful\n\nsummary: x x x x x x"}, msgs[7]}, result)
+
+	// 没有系统提示词时摘要作为第一条 system 消息，多模态的系统提示词追加 text 部分
+	result, err = TrimMessages(context.Background(), msgs[1:], TrimConfig{MaxTokens: 20, Tokenizer: wordTokenizer, Summarizer: summarizer, SummaryPrefix: "summary: "})
+	require.NoError(t, err)
+	assert.Equal(t, []Message{{Role: "system", Content: "summary: x x x x x x"}, msgs[7]}, result)
+	multimodal := append([]Message{{Role: "system", Content: []interface{}{map[string]interface{}{"type": "text", "text": "you are helpful"}}}}, msgs[1:]...)
+	result, err = TrimMessages(context.Background(), multimodal, TrimConfig{MaxTokens: 25, Tokenizer: wordTokenizer, Summarizer: summarizer, SummaryPrefix: "summary: "})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"type": "text", "text": "you are helpful"},
+		map[string]interface{}{"type": "text", "text": "summary: x x"},
+	}, result[0].Content)
+	assert.Len(t, multimodal[0].Content, 1)
+}
+
+func TestClientSummarizer(t *testing.T) {
+	chatter := &scriptedChatter{responses: []*ChatResponse{{Content: "用户询问了巴黎天气"}}}
+	summary, err := ClientSummarizer(&Client{provider: chatter}, "mini")(context.Background(), trimConversation()[3:7])
+	require.NoError(t, err)
+	assert.Equal(t, "用户询问了巴黎天气", summary)
+
+	req := chatter.requests[0]
+	assert.Equal(t, "mini", req.Model)
+	assert.Contains(t, req.Messages[1].Content, "user: weather in paris")
+	assert.Contains(t, req.Messages[1].Content, "[调用工具 weather: paris]")
+	assert.Contains(t, req.Messages[1].Content, "tool: sunny")
+}
@@ -16,9 +16,84 @@ func ContentString(c interface{}) string {
 
 // Message 单条对话消息，各 Provider 通用
 // Content 为 string 时表示纯文本；为 []interface{} 时表示多模态（如 [{"type":"text","text":"..."},{"type":"image_url","image_url":{"url":"..."}}]）
+// assistant 消息可携带 ToolCalls；tool 消息通过 ToolCallID 关联对应的工具调用，Content 为工具执行结果
 type Message struct {
-	Role    string      // system / user / assistant
-	Content interface{} // 消息内容：string 或 []interface{}（多模态）
+	Role       string          `json:"role"`                   // system / user / assistant / tool
+	Content    interface{}     `json:"content"`                // 消息内容：string 或 []interface{}（多模态）
+	Name       string          `json:"name,omitempty"`         // tool 消息对应的工具名（部分 Provider 需要，如 gemini）
+	ToolCalls  []ToolCall      `json:"tool_calls,omitempty"`   // assistant 消息中模型发起的工具调用
+	ToolCallID string          `json:"tool_call_id,omitempty"` // tool 消息对应的工具调用 ID
+	Thinking   []ThinkingBlock `json:"thinking,omitempty"`     // assistant 消息的思考块，需原样回传（如 anthropic 开启 extended thinking 后的工具调用续轮）
+}
+
+// ThinkingBlock 模型返回的一段带签名的思考内容
+// anthropic 在开启 extended thinking 时要求工具调用续轮原样回传这些块（含签名），否则请求会被拒绝
+type ThinkingBlock struct {
+	Thinking     string `json:"thinking,omitempty"`      // 思考文本
+	Signature    string `json:"signature,omitempty"`     // 思考内容的签名
+	RedactedData string `json:"redacted_data,omitempty"` // 被加密的思考内容（redacted_thinking），此时 Thinking 与 Signature 为空
+}
+
+// ToolCall 模型发起的一次工具调用
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"` // 目前固定为 "function"
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall 工具调用的函数名及参数
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON 字符串形式的参数
+}
+
+// ToolCallDelta 流式响应中的工具调用片段，同一 Index 的片段需要拼接成完整的 ToolCall
+type ToolCallDelta struct {
+	Index     int    // 工具调用在本次响应中的序号
+	ID        string // 工具调用 ID，通常只在首个片段出现
+	Type      string // 工具类型，通常只在首个片段出现
+	Name      string // 函数名，通常只在首个片段出现
+	Arguments string // 参数片段，需要按顺序拼接
+}
+
+// ToolCallAccumulator 将流式的 ToolCallDelta 按 Index 拼接为完整的 ToolCall
+type ToolCallAccumulator struct {
+	calls []ToolCall
+	index map[int]int
+}
+
+// Add 追加一个工具调用片段
+func (a *ToolCallAccumulator) Add(d ToolCallDelta) {
+	if a.index == nil {
+		a.index = make(map[int]int)
+	}
+	pos, ok := a.index[d.Index]
+	if !ok {
+		pos = len(a.calls)
+		a.index[d.Index] = pos
+		a.calls = append(a.calls, ToolCall{Type: "function"})
+	}
+	call := &a.calls[pos]
+	if d.ID != "" {
+		call.ID = d.ID
+	}
+	if d.Type != "" {
+		call.Type = d.Type
+	}
+	if d.Name != "" {
+		call.Function.Name += d.Name
+	}
+	call.Function.Arguments += d.Arguments
+}
+
+// ToolCalls 返回拼接完成的工具调用，按首次出现的顺序排列；没有工具调用时返回 nil
+func (a *ToolCallAccumulator) ToolCalls() []ToolCall {
+	if len(a.calls) == 0 {
+		return nil
+	}
+	out := make([]ToolCall, len(a.calls))
+	copy(out, a.calls)
+	return out
 }
 
 // Usage 统计 API 调用过程中的 token 使用量
@@ -46,7 +121,17 @@ type ChatRequest struct {
 	Tools            []map[string]interface{} // tools（OpenAI 兼容）
 	ToolChoice       interface{}              // tool_choice（OpenAI 兼容）
 	ResponseFormat   string                   // 如 "json_object" 或 "text"
-	Reasoning        *ReasoningConfig         // OpenRouter reasoning 参数
+	JSONSchema       *JSONSchemaFormat        // 结构化输出 schema，设置后优先于 ResponseFormat，可使用 ChatJSON 自动生成
+	Reasoning        *ReasoningConfig         // 推理参数，OpenRouter 原样透传，Gemini 映射为 thinkingConfig，Anthropic 映射为 extended thinking
+}
+
+// JSONSchemaFormat 结构化输出的 JSON Schema，对应 OpenAI response_format 中的 json_schema
+// 各 Provider 会转换为自身支持的形式（如 Gemini responseSchema、Ollama format），不支持时退化为在提示词中描述 schema
+type JSONSchemaFormat struct {
+	Name        string                 `json:"name"`                  // schema 名称，仅允许字母、数字、下划线和中划线
+	Description string                 `json:"description,omitempty"` // schema 描述
+	Schema      map[string]interface{} `json:"schema"`                // JSON Schema，可由 JSONSchemaOf 生成
+	Strict      bool                   `json:"strict,omitempty"`      // OpenAI strict 模式，要求所有字段必填且禁止额外字段
 }
 
 // ReasoningConfig 对应 OpenRouter reasoning 参数
@@ -61,10 +146,14 @@ type ReasoningConfig struct {
 
 // ChatResponse 统一对话响应
 type ChatResponse struct {
-	Content      string // 模型返回的主要内容
-	FinishReason string // 结束原因，如 "stop", "length"
-	Usage        Usage  // Token 使用情况
-	RawResponse  []byte `json:"-"` // 原始响应体，用于调试或特殊用途
+	Content      string          // 模型返回的主要内容
+	Reasoning    string          // 模型返回的推理（思考）内容，仅部分 Provider 在流式调用时返回
+	ToolCalls    []ToolCall      // 模型发起的工具调用，FinishReason 通常为 "tool_calls"
+	Thinking     []ThinkingBlock // 带签名的思考块，工具调用续轮时应复制到 assistant 消息的 Thinking 中
+	FinishReason string          // 结束原因，如 "stop", "length", "tool_calls"
+	Usage        Usage           // Token 使用情况
+	Backend      string          // 实际处理请求的后端标识，仅由 router 等元 Provider 填充
+	RawResponse  []byte          `json:"-"` // 原始响应体，用于调试或特殊用途
 }
 
 // EmbeddingRequest 统一 Embedding 请求
@@ -85,5 +174,65 @@ type EmbeddingResponse struct {
 	Data        []Embedding `json:"data"`
 	Model       string      `json:"model"`
 	Usage       Usage       `json:"usage"`
-	RawResponse []byte      `json:"-"` // 原始响应体
+	Backend     string      `json:"backend,omitempty"` // 实际处理请求的后端标识，仅由 router 等元 Provider 填充
+	RawResponse []byte      `json:"-"`                 // 原始响应体
+}
+
+// ImageRequest 统一图片生成请求
+type ImageRequest struct {
+	Model          string // 模型名，如 dall-e-3、gpt-image-1
+	Prompt         string // 图片描述
+	N              int    // 生成数量，0 表示使用 Provider 默认值
+	Size           string // 图片尺寸，如 "1024x1024"
+	Quality        string // 图片质量，如 "standard"、"hd"
+	Style          string // 图片风格，如 "vivid"、"natural"（部分模型支持）
+	ResponseFormat string // 返回格式："url" 或 "b64_json"
+	User           string // user
+}
+
+// Image 单张生成的图片，URL 与 B64JSON 按 ResponseFormat 二选一
+type Image struct {
+	URL           string `json:"url,omitempty"`
+	B64JSON       string `json:"b64_json,omitempty"`
+	RevisedPrompt string `json:"revised_prompt,omitempty"` // 模型改写后的提示词（部分模型返回）
+}
+
+// ImageResponse 统一图片生成响应
+type ImageResponse struct {
+	Created     int64   `json:"created"`
+	Images      []Image `json:"data"`
+	Usage       Usage   `json:"usage"`             // token 用量（部分模型返回）
+	Backend     string  `json:"backend,omitempty"` // 实际处理请求的后端标识，仅由 router 等元 Provider 填充
+	RawResponse []byte  `json:"-"`                 // 原始响应体
+}
+
+// TranscriptionRequest 统一语音转写请求
+type TranscriptionRequest struct {
+	Model          string   // 模型名，如 whisper-1
+	File           []byte   // 音频文件内容
+	FileName       string   // 音频文件名，Provider 通常根据扩展名识别格式，如 "audio.mp3"
+	Language       string   // 音频语言（ISO-639-1），如 "zh"，可选
+	Prompt         string   // 提示词，用于提示专有名词或延续上一段内容，可选
+	ResponseFormat string   // 返回格式：json、text、srt、verbose_json、vtt，默认 json
+	Temperature    *float64 // 采样温度，可选
+}
+
+// TranscriptionSegment 转写结果中的一个片段，仅 verbose_json 格式返回
+type TranscriptionSegment struct {
+	ID    int     `json:"id"`
+	Start float64 `json:"start"` // 开始时间，单位秒
+	End   float64 `json:"end"`   // 结束时间，单位秒
+	Text  string  `json:"text"`
+}
+
+// TranscriptionResponse 统一语音转写响应
+// ResponseFormat 为 text、srt、vtt 时，Text 为原样返回的文本内容
+type TranscriptionResponse struct {
+	Text        string                 `json:"text"`
+	Language    string                 `json:"language,omitempty"` // 识别出的语言，仅 verbose_json 格式返回
+	Duration    float64                `json:"duration,omitempty"` // 音频时长，单位秒，仅 verbose_json 格式返回
+	Segments    []TranscriptionSegment `json:"segments,omitempty"`
+	Usage       Usage                  `json:"usage"`             // token 用量（部分模型返回）
+	Backend     string                 `json:"backend,omitempty"` // 实际处理请求的后端标识，仅由 router 等元 Provider 填充
+	RawResponse []byte                 `json:"-"`                 // 原始响应体
 }
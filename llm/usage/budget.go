@@ -0,0 +1,207 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/jessewkun/gocommon/db/redis"
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/jessewkun/gocommon/logger"
+)
+
+// ErrBudgetExceeded 超出每日 token 配额，可通过 errors.Is 判断
+var ErrBudgetExceeded = errors.New("llm: daily token budget exceeded")
+
+// BudgetExceededError 超出每日 token 配额时返回的错误
+type BudgetExceededError struct {
+	Key   string // 配额 Key
+	Date  string // 统计日期，格式 20060102
+	Limit int64  // 每日上限
+	Used  int64  // 当日已使用的 token 数
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("llm: daily token budget exceeded, key: %s, date: %s, used: %d, limit: %d", e.Key, e.Date, e.Used, e.Limit)
+}
+
+// Is 使 errors.Is(err, ErrBudgetExceeded) 成立
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// BudgetStore 配额用量存储
+type BudgetStore interface {
+	// Get 获取 key 当前的用量，key 不存在时返回 0
+	Get(ctx context.Context, key string) (int64, error)
+	// IncrBy 增加 key 的用量并返回增加后的值，ttl 为 key 的过期时间
+	IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error)
+}
+
+// RedisStore 基于 db/redis 的配额存储
+type RedisStore struct {
+	dbIns string
+}
+
+var _ BudgetStore = (*RedisStore)(nil)
+
+// NewRedisStore 创建基于 redis 的配额存储，dbIns 为 redis 模块中配置的实例名
+func NewRedisStore(dbIns string) *RedisStore {
+	return &RedisStore{dbIns: dbIns}
+}
+
+// Get 获取 key 当前的用量
+func (s *RedisStore) Get(ctx context.Context, key string) (int64, error) {
+	conn, err := redis.GetConn(s.dbIns)
+	if err != nil {
+		return 0, err
+	}
+	n, err := conn.Get(ctx, key).Int64()
+	if errors.Is(err, goredis.Nil) {
+		return 0, nil
+	}
+	return n, err
+}
+
+// IncrBy 增加 key 的用量
+func (s *RedisStore) IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	conn, err := redis.GetConn(s.dbIns)
+	if err != nil {
+		return 0, err
+	}
+	pipe := conn.TxPipeline()
+	incr := pipe.IncrBy(ctx, key, n)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// MemoryStore 进程内配额存储，适用于单实例部署与测试，过期的 key 在写入时惰性清理
+type MemoryStore struct {
+	mu    sync.Mutex
+	items map[string]memoryItem
+}
+
+type memoryItem struct {
+	value    int64
+	expireAt time.Time
+}
+
+var _ BudgetStore = (*MemoryStore)(nil)
+
+// NewMemoryStore 创建进程内配额存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: make(map[string]memoryItem)}
+}
+
+// Get 获取 key 当前的用量
+func (s *MemoryStore) Get(ctx context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[key]
+	if !ok || time.Now().After(item.expireAt) {
+		return 0, nil
+	}
+	return item.value, nil
+}
+
+// IncrBy 增加 key 的用量
+func (s *MemoryStore) IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, item := range s.items {
+		if now.After(item.expireAt) {
+			delete(s.items, k)
+		}
+	}
+	item := s.items[key]
+	item.value += n
+	item.expireAt = now.Add(ttl)
+	s.items[key] = item
+	return item.value, nil
+}
+
+// budgetTTL 用量 key 的过期时间，保留前一天的数据便于排查
+const budgetTTL = 48 * time.Hour
+
+// BudgetGuard 每日 token 配额钩子
+// 通过 WithBudgetKey 指定配额 Key，当日用量达到上限后拒绝调用并返回 *BudgetExceededError
+// 用量在调用结束后累加，因此并发请求可能使实际用量略微超过上限
+// 存储异常时放行请求，只记录告警日志
+type BudgetGuard struct {
+	store BudgetStore
+	now   func() time.Time
+}
+
+var _ llm.Hook = (*BudgetGuard)(nil)
+
+// NewBudgetGuard 使用指定存储创建配额钩子
+func NewBudgetGuard(store BudgetStore) *BudgetGuard {
+	return &BudgetGuard{store: store, now: time.Now}
+}
+
+// NewRedisBudgetGuard 使用 llm_usage.budget.redis 配置的 redis 实例创建配额钩子
+func NewRedisBudgetGuard() *BudgetGuard {
+	dbIns := ""
+	indexMux.RLock()
+	if Cfg.Budget != nil {
+		dbIns = Cfg.Budget.Redis
+	}
+	indexMux.RUnlock()
+	return NewBudgetGuard(NewRedisStore(dbIns))
+}
+
+// Before 检查当日用量是否已达上限
+func (g *BudgetGuard) Before(ctx context.Context, info *llm.CallInfo) error {
+	key := BudgetKeyFromContext(ctx)
+	if key == "" {
+		return nil
+	}
+	limit := dailyLimit(key)
+	if limit <= 0 {
+		return nil
+	}
+	date := g.now().Format("20060102")
+	used, err := g.store.Get(ctx, g.storeKey(key, date))
+	if err != nil {
+		logger.WarnWithField(ctx, TAG, "get budget usage failed", map[string]interface{}{"key": key, "err": err.Error()})
+		return nil
+	}
+	if used >= limit {
+		return &BudgetExceededError{Key: key, Date: date, Limit: limit, Used: used}
+	}
+	return nil
+}
+
+// After 累加本次调用的 token 用量
+func (g *BudgetGuard) After(ctx context.Context, info *llm.CallInfo, u llm.Usage, err error) {
+	key := BudgetKeyFromContext(ctx)
+	if key == "" || u.TotalTokens <= 0 {
+		return
+	}
+	storeKey := g.storeKey(key, g.now().Format("20060102"))
+	if _, err := g.store.IncrBy(ctx, storeKey, int64(u.TotalTokens), budgetTTL); err != nil {
+		logger.WarnWithField(ctx, TAG, "incr budget usage failed", map[string]interface{}{"key": key, "tokens": u.TotalTokens, "err": err.Error()})
+	}
+}
+
+// Used 返回 key 当日已使用的 token 数
+func (g *BudgetGuard) Used(ctx context.Context, key string) (int64, error) {
+	return g.store.Get(ctx, g.storeKey(key, g.now().Format("20060102")))
+}
+
+func (g *BudgetGuard) storeKey(key, date string) string {
+	prefix := "llm:budget"
+	indexMux.RLock()
+	if Cfg.Budget != nil && Cfg.Budget.KeyPrefix != "" {
+		prefix = Cfg.Budget.KeyPrefix
+	}
+	indexMux.RUnlock()
+	return prefix + ":" + key + ":" + date
+}
@@ -0,0 +1,45 @@
+package usage
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	RequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "llm_requests_total",
+			Help: "Total number of LLM requests",
+		},
+		[]string{"provider", "model", "operation", "tag", "status"},
+	)
+
+	TokensTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "llm_tokens_total",
+			Help: "Total number of LLM tokens",
+		},
+		[]string{"provider", "model", "tag", "type"},
+	)
+
+	CostTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "llm_cost_total",
+			Help: "Total cost of LLM requests, in the currency of the price table",
+		},
+		[]string{"provider", "model", "tag"},
+	)
+
+	RequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "llm_request_duration_seconds",
+			Help:    "Histogram of LLM request latency",
+			Buckets: []float64{0.25, 0.5, 1, 2, 5, 10, 20, 30, 60, 120},
+		},
+		[]string{"provider", "model", "operation"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(RequestsTotal)
+	prometheus.MustRegister(TokensTotal)
+	prometheus.MustRegister(CostTotal)
+	prometheus.MustRegister(RequestDuration)
+}
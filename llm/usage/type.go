@@ -0,0 +1,150 @@
+// Package usage 为 llm.Client 提供用量统计、成本计算与每日 token 配额控制
+package usage
+
+import (
+	"context"
+	"maps"
+	"sync"
+
+	"github.com/jessewkun/gocommon/config"
+	"github.com/jessewkun/gocommon/logger"
+	"github.com/spf13/viper"
+)
+
+const TAG = "LLM_USAGE"
+
+// Config llm 用量模块配置，对应配置文件中的 llm_usage
+type Config struct {
+	Currency string        `mapstructure:"currency" json:"currency"` // 价格币种，仅用于展示，默认 USD
+	Prices   []Price       `mapstructure:"prices" json:"prices"`     // 价格表
+	Budget   *BudgetConfig `mapstructure:"budget" json:"budget"`     // 每日 token 配额
+}
+
+// Price 单个模型的价格
+// 模型名中常含有 "."，viper 会将 map 的 key 按 "." 拆分，因此价格表使用列表而不是 map
+type Price struct {
+	Provider string  `mapstructure:"provider" json:"provider"` // Provider 名称，为空表示匹配所有 Provider
+	Model    string  `mapstructure:"model" json:"model"`       // 模型名
+	Input    float64 `mapstructure:"input" json:"input"`       // 每百万输入 token 的价格
+	Output   float64 `mapstructure:"output" json:"output"`     // 每百万输出 token 的价格
+}
+
+// BudgetConfig 每日 token 配额配置
+type BudgetConfig struct {
+	Redis       string        `mapstructure:"redis" json:"redis"`               // 存储用量的 redis 实例名，对应 redis 模块的配置 key
+	KeyPrefix   string        `mapstructure:"key_prefix" json:"key_prefix"`     // redis key 前缀，默认 llm:budget
+	DailyTokens int64         `mapstructure:"daily_tokens" json:"daily_tokens"` // 默认每日 token 上限，0 表示不限制
+	Limits      []BudgetLimit `mapstructure:"limits" json:"limits"`             // 按租户/Key 单独设置的上限，优先于 DailyTokens
+}
+
+// BudgetLimit 单个租户/Key 的每日 token 上限
+type BudgetLimit struct {
+	Key         string `mapstructure:"key" json:"key"`
+	DailyTokens int64  `mapstructure:"daily_tokens" json:"daily_tokens"` // 0 表示不限制
+}
+
+var (
+	Cfg = DefaultConfig()
+
+	// prices 价格索引，key 为 "provider/model" 或 "/model"，由配置文件与 SetPrices 的价格合并而成
+	prices       = make(map[string]Price)
+	configPrices = make(map[string]Price)
+	customPrices = make(map[string]Price)
+	limits       = make(map[string]int64)
+	// indexMux 同时保护索引与热更新时对 Cfg 的替换，包内读取 Cfg 需持有读锁
+	indexMux sync.RWMutex
+)
+
+func init() {
+	config.Register("llm_usage", Cfg)
+	config.RegisterCallback("llm_usage", Init, "config", "log")
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		Currency: "USD",
+		Budget:   &BudgetConfig{KeyPrefix: "llm:budget"},
+	}
+}
+
+// Init 根据配置构建价格与配额索引
+func Init() error {
+	buildIndex(Cfg)
+	return nil
+}
+
+// Reload 重新加载 llm_usage 配置，价格表与配额均支持热更新
+func (c *Config) Reload(v *viper.Viper) error {
+	newCfg := DefaultConfig()
+	if err := v.UnmarshalKey("llm_usage", newCfg); err != nil {
+		logger.ErrorWithMsg(context.Background(), TAG, "failed to reload llm_usage config: %v", err)
+		return err
+	}
+	indexMux.Lock()
+	*c = *newCfg
+	indexMux.Unlock()
+	buildIndex(c)
+	logger.Info(context.Background(), TAG, "llm_usage config reload success, prices: %d", len(newCfg.Prices))
+	return nil
+}
+
+func buildIndex(c *Config) {
+	indexMux.Lock()
+	defer indexMux.Unlock()
+	configPrices = make(map[string]Price, len(c.Prices))
+	for _, p := range c.Prices {
+		configPrices[p.Provider+"/"+p.Model] = p
+	}
+	limits = make(map[string]int64)
+	if c.Budget != nil {
+		for _, l := range c.Budget.Limits {
+			limits[l.Key] = l.DailyTokens
+		}
+	}
+	mergePrices()
+}
+
+// mergePrices 合并配置文件与 SetPrices 的价格，同一模型以 SetPrices 为准，调用方需持有 indexMux
+func mergePrices() {
+	merged := make(map[string]Price, len(configPrices)+len(customPrices))
+	maps.Copy(merged, configPrices)
+	maps.Copy(merged, customPrices)
+	prices = merged
+}
+
+// SetPrices 以代码方式设置价格，与配置文件中的价格合并，同一模型以代码设置的为准，配置热更新后依然生效
+// 再次调用会替换上一次设置的价格，传入 nil 表示清除
+func SetPrices(list []Price) {
+	indexMux.Lock()
+	defer indexMux.Unlock()
+	customPrices = make(map[string]Price, len(list))
+	for _, p := range list {
+		customPrices[p.Provider+"/"+p.Model] = p
+	}
+	mergePrices()
+}
+
+// LookupPrice 查找模型价格，优先匹配 provider + model，其次匹配只配置了 model 的价格
+func LookupPrice(provider, model string) (Price, bool) {
+	indexMux.RLock()
+	defer indexMux.RUnlock()
+	if p, ok := prices[provider+"/"+model]; ok {
+		return p, true
+	}
+	p, ok := prices["/"+model]
+	return p, ok
+}
+
+// dailyLimit 返回 key 的每日 token 上限，0 表示不限制
+func dailyLimit(key string) int64 {
+	indexMux.RLock()
+	defer indexMux.RUnlock()
+	if limit, ok := limits[key]; ok {
+		return limit
+	}
+	if Cfg.Budget == nil {
+		return 0
+	}
+	return Cfg.Budget.DailyTokens
+}
@@ -0,0 +1,95 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jessewkun/gocommon/llm"
+)
+
+type ctxKey int
+
+const (
+	tagKey ctxKey = iota
+	budgetKey
+)
+
+// DefaultTag 未设置调用方标签时使用的标签
+const DefaultTag = "default"
+
+// WithTag 在 context 中设置调用方标签，用于按业务维度统计用量
+func WithTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, tagKey, tag)
+}
+
+// TagFromContext 获取 context 中的调用方标签，未设置时返回 DefaultTag
+func TagFromContext(ctx context.Context) string {
+	if tag, ok := ctx.Value(tagKey).(string); ok && tag != "" {
+		return tag
+	}
+	return DefaultTag
+}
+
+// WithBudgetKey 在 context 中设置配额 Key（租户 ID、API Key 等），未设置时不做配额控制
+func WithBudgetKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, budgetKey, key)
+}
+
+// BudgetKeyFromContext 获取 context 中的配额 Key
+func BudgetKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(budgetKey).(string)
+	return key
+}
+
+// Cost 根据价格表计算一次调用的成本，未配置价格时返回 0
+func Cost(provider, model string, u llm.Usage) float64 {
+	p, ok := LookupPrice(provider, model)
+	if !ok {
+		return 0
+	}
+	return (float64(u.PromptTokens)*p.Input + float64(u.CompletionTokens)*p.Output) / 1e6
+}
+
+// Recorder 将每次调用的请求数、token 用量、成本与耗时记录到 Prometheus
+type Recorder struct{}
+
+var _ llm.Hook = (*Recorder)(nil)
+
+// NewRecorder 创建用量记录钩子
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Before 不做任何处理
+func (r *Recorder) Before(ctx context.Context, info *llm.CallInfo) error {
+	return nil
+}
+
+// After 记录本次调用的指标
+func (r *Recorder) After(ctx context.Context, info *llm.CallInfo, u llm.Usage, err error) {
+	tag := TagFromContext(ctx)
+	RequestsTotal.WithLabelValues(info.Provider, info.Model, string(info.Operation), tag, status(err)).Inc()
+	RequestDuration.WithLabelValues(info.Provider, info.Model, string(info.Operation)).Observe(time.Since(info.StartTime).Seconds())
+
+	if u.PromptTokens > 0 {
+		TokensTotal.WithLabelValues(info.Provider, info.Model, tag, "prompt").Add(float64(u.PromptTokens))
+	}
+	if u.CompletionTokens > 0 {
+		TokensTotal.WithLabelValues(info.Provider, info.Model, tag, "completion").Add(float64(u.CompletionTokens))
+	}
+	if cost := Cost(info.Provider, info.Model, u); cost > 0 {
+		CostTotal.WithLabelValues(info.Provider, info.Model, tag).Add(cost)
+	}
+}
+
+func status(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, ErrBudgetExceeded):
+		return "rejected"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,185 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jessewkun/gocommon/llm"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider 返回固定用量的 Provider
+type fakeProvider struct {
+	usage llm.Usage
+	err   error
+	calls int
+}
+
+func (f *fakeProvider) Name() string { return "usage-fake" }
+
+func (f *fakeProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &llm.ChatResponse{Content: "ok", Usage: f.usage}, nil
+}
+
+func (f *fakeProvider) ChatStream(ctx context.Context, req *llm.ChatRequest, callback func(chunk string) error) (*llm.ChatResponse, error) {
+	return f.Chat(ctx, req)
+}
+
+func newTestClient(t *testing.T, f *fakeProvider) *llm.Client {
+	name := fmt.Sprintf("usage-test-%s", t.Name())
+	llm.Register(name, func(config interface{}) (llm.Provider, error) { return f, nil })
+	client, err := llm.NewClient(name, nil)
+	require.NoError(t, err)
+	return client
+}
+
+func TestCost(t *testing.T) {
+	SetPrices([]Price{
+		{Model: "gpt-4o", Input: 2.5, Output: 10},
+		{Provider: "openrouter", Model: "gpt-4o", Input: 3, Output: 12},
+	})
+	defer SetPrices(nil)
+
+	u := llm.Usage{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500}
+	assert.InDelta(t, 0.0075, Cost("openai", "gpt-4o", u), 1e-9)
+	assert.InDelta(t, 0.009, Cost("openrouter", "gpt-4o", u), 1e-9)
+	assert.Zero(t, Cost("openai", "unknown", u))
+}
+
+func TestRecorder(t *testing.T) {
+	SetPrices([]Price{{Model: "rec-model", Input: 1, Output: 2}})
+	defer SetPrices(nil)
+
+	f := &fakeProvider{usage: llm.Usage{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150}}
+	client := newTestClient(t, f)
+	client.Use(NewRecorder())
+
+	ctx := WithTag(context.Background(), "search")
+	_, err := client.Chat(ctx, &llm.ChatRequest{Model: "rec-model"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(RequestsTotal.WithLabelValues("usage-fake", "rec-model", "chat", "search", "success")))
+	assert.Equal(t, 100.0, testutil.ToFloat64(TokensTotal.WithLabelValues("usage-fake", "rec-model", "search", "prompt")))
+	assert.Equal(t, 50.0, testutil.ToFloat64(TokensTotal.WithLabelValues("usage-fake", "rec-model", "search", "completion")))
+	assert.InDelta(t, 0.0002, testutil.ToFloat64(CostTotal.WithLabelValues("usage-fake", "rec-model", "search")), 1e-12)
+
+	f.err = errors.New("boom")
+	_, err = client.Chat(context.Background(), &llm.ChatRequest{Model: "rec-model"})
+	assert.Error(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(RequestsTotal.WithLabelValues("usage-fake", "rec-model", "chat", DefaultTag, "error")))
+}
+
+func TestBudgetGuard(t *testing.T) {
+	old := Cfg.Budget
+	Cfg.Budget = &BudgetConfig{DailyTokens: 200, Limits: []BudgetLimit{{Key: "vip", DailyTokens: 0}}}
+	buildIndex(Cfg)
+	defer func() {
+		Cfg.Budget = old
+		buildIndex(Cfg)
+	}()
+
+	f := &fakeProvider{usage: llm.Usage{TotalTokens: 150}}
+	client := newTestClient(t, f)
+	store := NewMemoryStore()
+	guard := NewBudgetGuard(store)
+	guard.now = func() time.Time { return time.Date(2025, 3, 1, 10, 0, 0, 0, time.Local) }
+	client.Use(NewRecorder(), guard)
+
+	ctx := WithBudgetKey(context.Background(), "tenant-1")
+	req := &llm.ChatRequest{Model: "budget-model"}
+	_, err := client.Chat(ctx, req)
+	require.NoError(t, err)
+	_, err = client.Chat(ctx, req)
+	require.NoError(t, err)
+
+	_, err = client.Chat(ctx, req)
+	require.ErrorIs(t, err, ErrBudgetExceeded)
+	var budgetErr *BudgetExceededError
+	require.True(t, errors.As(err, &budgetErr))
+	assert.Equal(t, BudgetExceededError{Key: "tenant-1", Date: "20250301", Limit: 200, Used: 300}, *budgetErr)
+	assert.Equal(t, 2, f.calls)
+	assert.Equal(t, 1.0, testutil.ToFloat64(RequestsTotal.WithLabelValues("usage-fake", "budget-model", "chat", DefaultTag, "rejected")))
+
+	// 第二天重新计数
+	guard.now = func() time.Time { return time.Date(2025, 3, 2, 0, 0, 1, 0, time.Local) }
+	_, err = client.Chat(ctx, req)
+	assert.NoError(t, err)
+
+	// 单独配置为不限制的 Key 与未设置 Key 的调用不受配额影响
+	for i := 0; i < 3; i++ {
+		_, err = client.Chat(WithBudgetKey(context.Background(), "vip"), req)
+		assert.NoError(t, err)
+		_, err = client.Chat(context.Background(), req)
+		assert.NoError(t, err)
+	}
+	used, err := guard.Used(context.Background(), "vip")
+	require.NoError(t, err)
+	assert.Equal(t, int64(450), used)
+}
+
+func TestConfig_Reload(t *testing.T) {
+	old := *Cfg
+	defer func() {
+		indexMux.Lock()
+		*Cfg = old
+		indexMux.Unlock()
+		buildIndex(Cfg)
+		SetPrices(nil)
+	}()
+
+	SetPrices([]Price{{Model: "custom-model", Input: 1, Output: 1}, {Model: "shared-model", Input: 9, Output: 9}})
+	v := viper.New()
+	v.Set("llm_usage.prices", []map[string]interface{}{
+		{"model": "config-model", "input": 2, "output": 4},
+		{"model": "shared-model", "input": 3, "output": 6},
+	})
+	v.Set("llm_usage.budget.daily_tokens", 100)
+
+	// 热更新与读取并发执行
+	guard := NewBudgetGuard(NewMemoryStore())
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				LookupPrice("openai", "config-model")
+				dailyLimit("tenant")
+				guard.storeKey("tenant", "20250301")
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		require.NoError(t, Cfg.Reload(v))
+	}
+	wg.Wait()
+
+	// SetPrices 的价格在热更新后依然生效，同一模型以 SetPrices 为准
+	p, ok := LookupPrice("openai", "custom-model")
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, p.Input)
+	p, ok = LookupPrice("openai", "config-model")
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, p.Input)
+	p, _ = LookupPrice("openai", "shared-model")
+	assert.Equal(t, 9.0, p.Input)
+	assert.Equal(t, int64(100), dailyLimit("tenant"))
+
+	// 清除 SetPrices 后使用配置文件中的价格
+	SetPrices(nil)
+	p, _ = LookupPrice("openai", "shared-model")
+	assert.Equal(t, 3.0, p.Input)
+	_, ok = LookupPrice("openai", "custom-model")
+	assert.False(t, ok)
+}
@@ -0,0 +1,37 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jessewkun/gocommon/cron"
+	"github.com/jessewkun/gocommon/middleware"
+	"github.com/jessewkun/gocommon/response"
+	"github.com/spf13/cast"
+)
+
+// RegisterCronRoutes 注册定时任务状态路由，与 pprof 相同仅允许本地访问
+//
+//	GET /debug/cron/tasks       所有任务的状态
+//	GET /debug/cron/tasks/:key  单个任务的状态与最近的执行记录，limit 参数控制记录条数
+func RegisterCronRoutes(r *gin.Engine, m *cron.Manager) {
+	cronGroup := r.Group("/debug/cron")
+	cronGroup.Use(middleware.RateLimiter(pprofRateLimitConfig))
+	{
+		cronGroup.GET("/tasks", func(c *gin.Context) {
+			response.Success(c, m.Status())
+		})
+		cronGroup.GET("/tasks/:key", func(c *gin.Context) {
+			key := c.Param("key")
+			status, ok := m.TaskStatus(key)
+			if !ok {
+				response.NotfoundError(c)
+				return
+			}
+			history, err := m.History(c.Request.Context(), key, cast.ToInt(c.Query("limit")))
+			if err != nil {
+				response.Error(c, err)
+				return
+			}
+			response.Success(c, gin.H{"status": status, "history": history})
+		})
+	}
+}